@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcutil/bech32"
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/urfave/cli"
+)
+
+const ADDRESS_PREFIX = "tc"
+
+// The protobuf header of an Ed25519 public key, the key type and the key's length,
+// the address keeps only the key after it.
+var ed25519PublicKeyHeader = []byte{0x08, 0x01, 0x12, 0x20}
+
+// Address encodes a public key with bech32 so typos are found by the checksum.
+func Address(pub []byte) (string, error) {
+	if len(pub) != len(ed25519PublicKeyHeader)+32 || !bytes.HasPrefix(pub, ed25519PublicKeyHeader) {
+		return "", errors.New("The public key is not an Ed25519 key.")
+	}
+	data, err := bech32.ConvertBits(pub[len(ed25519PublicKeyHeader):], 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return bech32.Encode(ADDRESS_PREFIX, data)
+}
+
+func AddressPublicKey(address string) ([]byte, error) {
+	hrp, data, err := bech32.Decode(address)
+	if err != nil {
+		return nil, errors.New("The address " + address + " is not correct: " + err.Error())
+	}
+	if hrp != ADDRESS_PREFIX {
+		return nil, errors.New("The address " + address + " is not a theftcoin address.")
+	}
+	key, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("The address " + address + " is not correct.")
+	}
+	return append(append([]byte{}, ed25519PublicKeyHeader...), key...), nil
+}
+
+func isAddress(account string) bool {
+	return strings.HasPrefix(strings.ToLower(account), ADDRESS_PREFIX+"1")
+}
+
+// resolveAccount returns the public key of a contact's name, an address or a hex public key.
+func resolveAccount(account string) ([]byte, error) {
+	contacts, err := readContacts()
+	if err != nil {
+		return nil, err
+	}
+	if address, ok := contacts[account]; ok {
+		account = address
+	}
+	if isAddress(account) {
+		return AddressPublicKey(account)
+	}
+	pub, err := hex.DecodeString(account)
+	if err != nil {
+		return nil, errors.New("The account " + account + " is not a contact, an address or a hex public key.")
+	}
+	_, err = crypto.UnmarshalPublicKey(pub)
+	if err != nil {
+		return nil, errors.New("The public key " + account + " is not correct.")
+	}
+	return pub, nil
+}
+
+// showAccount prints the address of a public key, or the hex for the keys that have no address.
+func showAccount(pub []byte) string {
+	address, err := Address(pub)
+	if err != nil {
+		return hex.EncodeToString(pub)
+	}
+	return address
+}
+
+func contactsFile() string {
+	return filepath.Join(filepath.Dir(Conf.ConfigFile), "contacts.json")
+}
+
+func readContacts() (map[string]string, error) {
+	contacts := map[string]string{}
+	b, err := ioutil.ReadFile(contactsFile())
+	if os.IsNotExist(err) {
+		return contacts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &contacts)
+	if err != nil {
+		return nil, errors.New("The contacts' file is not correct: " + err.Error())
+	}
+	return contacts, nil
+}
+
+func writeContacts(contacts map[string]string) error {
+	err := os.MkdirAll(filepath.Dir(contactsFile()), 0700)
+	if err != nil {
+		return err
+	}
+	b, _ := json.MarshalIndent(contacts, "", "  ")
+	return ioutil.WriteFile(contactsFile(), b, 0600)
+}
+
+type Contact struct {
+	Name      string
+	Address   string
+	PublicKey string
+}
+
+var contactsAddCommand = cli.Command{
+	Name:      "add",
+	Usage:     "add a contact with an address, a stealth address or a hex public key",
+	ArgsUsage: "<name> <account>",
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 2 {
+			return newCommandError(CodeTypeClientError, "Error: the name and the account are missing")
+		}
+		name := c.Args().Get(0)
+		if isAddress(name) {
+			return newCommandError(CodeTypeClientError, "Error: the name can not be an address")
+		}
+		_, err := hex.DecodeString(name)
+		if err == nil {
+			return newCommandError(CodeTypeClientError, "Error: the name can not be hex")
+		}
+
+		var pub []byte
+		address := c.Args().Get(1)
+		if isStealthAddress(address) {
+			_, _, err = stealthAddressKeys(address)
+		} else {
+			pub, err = resolveAccount(address)
+			if err == nil {
+				address, err = Address(pub)
+			}
+		}
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		contacts, err := readContacts()
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		contacts[name] = address
+		err = writeContacts(contacts)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		printOutput(Contact{Name: name, Address: address, PublicKey: hex.EncodeToString(pub)}, "The contact "+name+" was added")
+		return nil
+	},
+}
+
+var contactsRemoveCommand = cli.Command{
+	Name:      "remove",
+	Usage:     "remove a contact",
+	ArgsUsage: "<name>",
+	Action: func(c *cli.Context) error {
+		name := c.Args().First()
+		contacts, err := readContacts()
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		if _, ok := contacts[name]; !ok {
+			return newCommandError(CodeTypeClientError, "Error: the contact "+name+" does not exist")
+		}
+		delete(contacts, name)
+		err = writeContacts(contacts)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		printOutput(Contact{Name: name}, "The contact "+name+" was removed")
+		return nil
+	},
+}
+
+var contactsListCommand = cli.Command{
+	Name:  "list",
+	Usage: "list the contacts",
+	Action: func(c *cli.Context) error {
+		contacts, err := readContacts()
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		names := []string{}
+		for name := range contacts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		list := []Contact{}
+		lines := []string{}
+		for _, name := range names {
+			pub, _ := AddressPublicKey(contacts[name])
+			list = append(list, Contact{Name: name, Address: contacts[name], PublicKey: hex.EncodeToString(pub)})
+			lines = append(lines, name+"\t"+contacts[name])
+		}
+		printOutput(list, strings.Join(lines, "\n"))
+		return nil
+	},
+}
+
+var ContactsCommand = cli.Command{
+	Name:  "contacts",
+	Usage: "keep the addresses of other accounts by name",
+	Subcommands: []cli.Command{
+		contactsAddCommand,
+		contactsRemoveCommand,
+		contactsListCommand,
+	},
+}
+
+var AddressCommand = cli.Command{
+	Name: "address",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+	},
+	Usage:     "show the address of a key or of a public key",
+	ArgsUsage: "[public key]",
+	Action: func(c *cli.Context) error {
+		var pub []byte
+		var err error
+		if c.NArg() > 0 {
+			pub, err = resolveAccount(c.Args().First())
+		} else {
+			key := keyFile(c)
+			if len(key) == 0 {
+				return newCommandError(CodeTypeClientError, "Error: the key is missing")
+			}
+			pub, err = filePublicKey(key)
+		}
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		address, err := Address(pub)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		printOutput(Contact{Address: address, PublicKey: hex.EncodeToString(pub)}, address)
+		return nil
+	},
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/urfave/cli"
+)
+
+// The same hash as the server's for an entry of the audit log.
+func (ae *AuditEntry) ComputeHash() []byte {
+	e := *ae
+	e.Hash = nil
+	b, _ := json.Marshal(e)
+	h := sha256.Sum256(b)
+	return h[:]
+}
+
+func Audit(from crypto.PrivKey) (*AuditResponse, uint32, error) {
+	b, err := newQueryRequest(from, nil)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	resp, code, err := queryPath(AUDIT_QUERY_PATH, b)
+	if err != nil {
+		return nil, code, err
+	}
+	ar := AuditResponse{}
+	err = json.Unmarshal(resp.Value, &ar)
+	if err != nil {
+		return nil, CodeTypeEncodingError, errors.New("The audit response is not json.")
+	}
+	return &ar, CodeTypeOK, nil
+}
+
+func AuditHeadOfNode() (*AuditHead, uint32, error) {
+	resp, code, err := queryPath(AUDIT_HEAD_QUERY_PATH, nil)
+	if err != nil {
+		return nil, code, err
+	}
+	head := AuditHead{}
+	err = json.Unmarshal(resp.Value, &head)
+	if err != nil {
+		return nil, CodeTypeEncodingError, errors.New("The audit head is not json.")
+	}
+	return &head, CodeTypeOK, nil
+}
+
+func AnchorAudit(from crypto.PrivKey, head AuditHead) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr := newDeliveryRequest(pubB, AUDIT_ANCHOR_ACTION, 0)
+	dr.Data.AuditAnchor = &AuditAnchor{Log: head.Log, Index: head.Index, Hash: head.Hash}
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+type auditAccess struct {
+	Index    int64
+	Watcher  string
+	Path     string
+	Time     time.Time
+	Nonce    string
+	Verified bool
+	Anchored bool
+}
+
+type auditOutput struct {
+	Accesses   []auditAccess
+	Head       int64
+	Anchored   int64
+	Mismatches int
+}
+
+var auditListCommand = cli.Command{
+	Name: "list",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+	},
+	Usage: "list the watchers' queries of the account in the node's audit log",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		ar, code, err := Audit(privk)
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+
+		ao := auditOutput{Accesses: []auditAccess{}, Head: ar.Head.Index, Anchored: ar.Anchored, Mismatches: len(ar.Mismatches)}
+		lines := []string{}
+		for _, entry := range ar.Entries {
+			access := auditAccess{
+				Index:    entry.Index,
+				Watcher:  showAccount(entry.Watcher),
+				Path:     entry.Path,
+				Time:     entry.Time,
+				Nonce:    entry.Nonce,
+				Verified: bytes.Equal(entry.Hash, entry.ComputeHash()),
+				Anchored: entry.Index <= ar.Anchored,
+			}
+			ao.Accesses = append(ao.Accesses, access)
+			line := strconv.FormatInt(access.Index, 10) + "\t" + access.Time.Format(time.RFC3339) + "\t" + access.Watcher + "\t" + access.Path
+			if !access.Verified {
+				line += "\tthe hash is not correct"
+			} else if access.Anchored {
+				line += "\tanchored"
+			}
+			lines = append(lines, line)
+		}
+		for _, anchor := range ar.Mismatches {
+			lines = append(lines, "The anchor of the entry "+strconv.FormatInt(anchor.Index, 10)+" at the height "+
+				strconv.FormatInt(anchor.Height, 10)+" does not match the log: "+hex.EncodeToString(anchor.Hash))
+		}
+		printOutput(ao, strings.Join(lines, "\n"))
+		if ao.Mismatches > 0 {
+			return newResultError(CodeTypeUnauthorized, "Error: the audit log does not match its anchors", ao)
+		}
+		return nil
+	},
+}
+
+var auditAnchorCommand = cli.Command{
+	Name: "anchor",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.DurationFlag{
+			Name:  "every",
+			Usage: "anchor the head again after the duration, for example 10m, by default only once",
+		},
+	},
+	Usage: "keep the hash of the node's audit log in the chain",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		var anchored int64 = -1
+		for {
+			head, code, err := AuditHeadOfNode()
+			if err != nil {
+				return newCommandError(code, "Error:"+err.Error())
+			}
+			if len(head.Log) == 0 {
+				return newCommandError(CodeTypeClientError, "Error: the node's audit log does not have an id")
+			}
+			if head.Index > anchored {
+				dres, code, err := AnchorAudit(privk, *head)
+				if err != nil {
+					return newResultError(code, "Error:"+err.Error(), dres)
+				}
+				anchored = head.Index
+				printOutput(dres, "The entry "+strconv.FormatInt(head.Index, 10)+" was anchored")
+			}
+			if c.Duration("every") <= 0 {
+				return nil
+			}
+			time.Sleep(c.Duration("every"))
+		}
+	},
+}
+
+var AuditCommand = cli.Command{
+	Name:  "audit",
+	Usage: "see the watchers' queries of the account and anchor the node's audit log",
+	Subcommands: []cli.Command{
+		auditListCommand,
+		auditAnchorCommand,
+	},
+}
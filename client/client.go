@@ -5,8 +5,11 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/tendermint/abci/types"
 )
@@ -18,16 +21,24 @@ type jsonRpcRequest struct {
 	Id      string      `json:"id"`      //"id": "dontcare"
 }
 
+type jsonRpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data"`
+}
+
 type jsonRpcResponseForDelivery struct {
-	Method  string     `json:"method"`  //"method": "broadcast_tx_sync",
-	Version string     `json:"jsonrpc"` //"jsonrpc": "2.0",
-	Result  deliveryTx `json:"result"`  //"result": ,
-	Id      string     `json:"id"`      //"id": "dontcare"
+	Method  string        `json:"method"`  //"method": "broadcast_tx_sync",
+	Version string        `json:"jsonrpc"` //"jsonrpc": "2.0",
+	Result  deliveryTx    `json:"result"`  //"result": ,
+	Error   *jsonRpcError `json:"error"`   //"error": ,
+	Id      string        `json:"id"`      //"id": "dontcare"
 }
 
 type deliveryTx struct {
+	CheckTx    types.ResponseCheckTx   `json:"check_tx"`
 	DeliveryTx types.ResponseDeliverTx `json:"deliver_tx"`
-	Height     int                     `json:"height"`
+	Height     int64                   `json:"height"`
 	Hash       string                  `json:"hash"`
 }
 
@@ -35,6 +46,7 @@ type jsonRpcResponseForQuery struct {
 	Method  string        `json:"method"`  //"method": "broadcast_tx_sync",
 	Version string        `json:"jsonrpc"` //"jsonrpc": "2.0",
 	Result  responseQuery `json:"result"`  //"result": ,
+	Error   *jsonRpcError `json:"error"`   //"error": ,
 	Id      string        `json:"id"`      //"id": "dontcare"
 }
 
@@ -42,6 +54,65 @@ type responseQuery struct {
 	Response types.ResponseQuery `json:"response"`
 }
 
+type jsonRpcResponseForStatus struct {
+	Method  string        `json:"method"`  //"method": "status",
+	Version string        `json:"jsonrpc"` //"jsonrpc": "2.0",
+	Result  status        `json:"result"`  //"result": ,
+	Error   *jsonRpcError `json:"error"`   //"error": ,
+	Id      string        `json:"id"`      //"id": "dontcare"
+}
+
+type nodeInfo struct {
+	Network string `json:"network"`
+}
+
+type status struct {
+	NodeInfo          nodeInfo `json:"node_info"`
+	LatestBlockHeight int64    `json:"latest_block_height"`
+}
+
+type jsonRpcResponseForBlock struct {
+	Method  string        `json:"method"`  //"method": "block",
+	Version string        `json:"jsonrpc"` //"jsonrpc": "2.0",
+	Result  blockResult   `json:"result"`  //"result": ,
+	Error   *jsonRpcError `json:"error"`   //"error": ,
+	Id      string        `json:"id"`      //"id": "dontcare"
+}
+
+type blockHeader struct {
+	Height int64     `json:"height"`
+	Time   time.Time `json:"time"`
+}
+
+type blockData struct {
+	Txs [][]byte `json:"txs"`
+}
+
+type block struct {
+	Header blockHeader `json:"header"`
+	Data   blockData   `json:"data"`
+}
+
+type blockResult struct {
+	Block block `json:"block"`
+}
+
+type jsonRpcResponseForTxSearch struct {
+	Method  string        `json:"method"`  //"method": "tx_search",
+	Version string        `json:"jsonrpc"` //"jsonrpc": "2.0",
+	Result  []resultTx    `json:"result"`  //"result": ,
+	Error   *jsonRpcError `json:"error"`   //"error": ,
+	Id      string        `json:"id"`      //"id": "dontcare"
+}
+
+type resultTx struct {
+	Hash     string                  `json:"hash"`
+	Height   int64                   `json:"height"`
+	Index    uint32                  `json:"index"`
+	TxResult types.ResponseDeliverTx `json:"tx_result"`
+	Tx       []byte                  `json:"tx"`
+}
+
 func newJsonRpcRequest(method string, js interface{}) jsonRpcRequest {
 	jr := jsonRpcRequest{}
 	jr.Method = method
@@ -55,11 +126,25 @@ type Tx struct {
 	Tx string `json:"tx"`
 }
 
+type BlockHeight struct {
+	Height int64 `json:"height"`
+}
+
+type TxSearch struct {
+	Query string `json:"query"`
+	Prove bool   `json:"prove"`
+}
+
 type AbciQuery struct {
+	Path string `json:"path"`
 	Data string `json:"data"`
 }
 
-func RpcBroadcastCommit(deliveryB []byte) (*types.ResponseDeliverTx, error) {
+func (e *jsonRpcError) error() error {
+	return errors.New(strings.TrimSpace(e.Message + " " + e.Data))
+}
+
+func RpcBroadcastCommit(deliveryB []byte) (*deliveryTx, error) {
 	tx := Tx{}
 	tx.Tx = base64.StdEncoding.EncodeToString(deliveryB)
 	jr := newJsonRpcRequest("broadcast_tx_commit", tx)
@@ -68,15 +153,23 @@ func RpcBroadcastCommit(deliveryB []byte) (*types.ResponseDeliverTx, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 	bresp, _ := ioutil.ReadAll(resp.Body)
 	jresp := jsonRpcResponseForDelivery{}
-	json.Unmarshal(bresp, &jresp)
+	err = json.Unmarshal(bresp, &jresp)
+	if err != nil {
+		return nil, errors.New("The node's response is not json.")
+	}
+	if jresp.Error != nil {
+		return nil, jresp.Error.error()
+	}
 
-	return &jresp.Result.DeliveryTx, nil
+	return &jresp.Result, nil
 }
 
-func RpcQuery(b []byte) (*types.ResponseQuery, error) {
+func RpcQuery(path string, b []byte) (*types.ResponseQuery, error) {
 	aq := AbciQuery{}
+	aq.Path = path
 	aq.Data = hex.EncodeToString(b)
 	jr := newJsonRpcRequest("abci_query", aq)
 	bout, _ := json.Marshal(jr)
@@ -84,8 +177,75 @@ func RpcQuery(b []byte) (*types.ResponseQuery, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 	bresp, _ := ioutil.ReadAll(resp.Body)
 	jresp := jsonRpcResponseForQuery{}
-	json.Unmarshal(bresp, &jresp)
+	err = json.Unmarshal(bresp, &jresp)
+	if err != nil {
+		return nil, errors.New("The node's response is not json.")
+	}
+	if jresp.Error != nil {
+		return nil, jresp.Error.error()
+	}
 	return &jresp.Result.Response, nil
 }
+
+func RpcStatus() (*status, error) {
+	jr := newJsonRpcRequest("status", struct{}{})
+	bout, _ := json.Marshal(jr)
+	resp, err := http.Post(Conf.NodeDaemon, "text/plain", bytes.NewBuffer(bout))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	bresp, _ := ioutil.ReadAll(resp.Body)
+	jresp := jsonRpcResponseForStatus{}
+	err = json.Unmarshal(bresp, &jresp)
+	if err != nil {
+		return nil, errors.New("The node's response is not json.")
+	}
+	if jresp.Error != nil {
+		return nil, jresp.Error.error()
+	}
+	return &jresp.Result, nil
+}
+
+func RpcBlock(height int64) (*block, error) {
+	jr := newJsonRpcRequest("block", BlockHeight{Height: height})
+	bout, _ := json.Marshal(jr)
+	resp, err := http.Post(Conf.NodeDaemon, "text/plain", bytes.NewBuffer(bout))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	bresp, _ := ioutil.ReadAll(resp.Body)
+	jresp := jsonRpcResponseForBlock{}
+	err = json.Unmarshal(bresp, &jresp)
+	if err != nil {
+		return nil, errors.New("The node's response is not json.")
+	}
+	if jresp.Error != nil {
+		return nil, jresp.Error.error()
+	}
+	return &jresp.Result.Block, nil
+}
+
+func RpcTxSearch(query string) ([]resultTx, error) {
+	jr := newJsonRpcRequest("tx_search", TxSearch{Query: query})
+	bout, _ := json.Marshal(jr)
+	resp, err := http.Post(Conf.NodeDaemon, "text/plain", bytes.NewBuffer(bout))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	bresp, _ := ioutil.ReadAll(resp.Body)
+	jresp := jsonRpcResponseForTxSearch{}
+	err = json.Unmarshal(bresp, &jresp)
+	if err != nil {
+		return nil, errors.New("The node's response is not json.")
+	}
+	if jresp.Error != nil {
+		return nil, jresp.Error.error()
+	}
+	return jresp.Result, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/urfave/cli"
+)
+
+func ComplianceOf(account []byte) (*ComplianceResponse, uint32, error) {
+	resp, code, err := queryPath(COMPLIANCE_QUERY_PATH, account)
+	if err != nil {
+		return nil, code, err
+	}
+	cr := ComplianceResponse{}
+	err = json.Unmarshal(resp.Value, &cr)
+	if err != nil {
+		return nil, CodeTypeEncodingError, errors.New("The compliance's response is not json.")
+	}
+	return &cr, CodeTypeOK, nil
+}
+
+// Comply sends the authority's freeze, unfreeze or seizure of the account, the seizure pays the coins to the receiver.
+func Comply(from crypto.PrivKey, action ActionStruct, account []byte, reason string, to []byte, coins float64) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr := newDeliveryRequest(pubB, action, coins)
+	if to != nil {
+		dr.Data.To = &to
+	}
+	dr.Data.Compliance = &Compliance{Account: account, Reason: reason}
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+func complianceFlags(flags ...cli.Flag) []cli.Flag {
+	return append([]cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the authority's key in json file",
+		},
+		cli.StringFlag{
+			Name:  "account",
+			Usage: "the contact, address or public key of the account",
+		},
+		cli.StringFlag{
+			Name:  "reason",
+			Usage: "the reference of the order, like the case's number",
+		},
+	}, flags...)
+}
+
+func complianceAction(c *cli.Context, action ActionStruct) error {
+	key := keyFile(c)
+	if len(key) == 0 {
+		return newCommandError(CodeTypeClientError, "Error: the key is missing")
+	}
+	if len(c.String("account")) == 0 {
+		return newCommandError(CodeTypeClientError, "Error: the account is missing")
+	}
+	reason := c.String("reason")
+	if len(reason) == 0 {
+		return newCommandError(CodeTypeClientError, "Error: the reason is missing")
+	}
+	account, err := resolveAccount(c.String("account"))
+	if err != nil {
+		return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+	}
+	var to []byte
+	coins := float64(0)
+	if action == SEIZE_ACTION {
+		if len(c.String("receiver")) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the receiver is missing")
+		}
+		coins = c.Float64("coins")
+		if coins <= 0 {
+			return newCommandError(CodeTypeClientError, "Error: the coins are not allowed to be 0 or less")
+		}
+		to, err = resolveAccount(c.String("receiver"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+	}
+	privk, err := fileKey(key)
+	if err != nil {
+		return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+	}
+	dres, code, err := Comply(privk, action, account, reason, to, coins)
+	if err != nil {
+		return newResultError(code, "Error:"+err.Error(), dres)
+	}
+	printOutput(dres, "The "+string(action)+" was successful")
+	return nil
+}
+
+var complianceFreezeCommand = cli.Command{
+	Name:  "freeze",
+	Flags: complianceFlags(),
+	Usage: "stop the account from sending and receiving coins, as a compliance authority",
+	Action: func(c *cli.Context) error {
+		return complianceAction(c, FREEZE_ACTION)
+	},
+}
+
+var complianceUnfreezeCommand = cli.Command{
+	Name:  "unfreeze",
+	Flags: complianceFlags(),
+	Usage: "let the frozen account send and receive coins again, as a compliance authority",
+	Action: func(c *cli.Context) error {
+		return complianceAction(c, UNFREEZE_ACTION)
+	},
+}
+
+var complianceSeizeCommand = cli.Command{
+	Name: "seize",
+	Flags: complianceFlags(
+		cli.StringFlag{
+			Name:  "receiver",
+			Usage: "the contact, address or public key of the account that the court order designates",
+		},
+		cli.Float64Flag{
+			Name:  "coins",
+			Usage: "the number of coins to seize",
+		},
+	),
+	Usage: "move the account's coins to the designated account by a court order, as a compliance authority",
+	Action: func(c *cli.Context) error {
+		return complianceAction(c, SEIZE_ACTION)
+	},
+}
+
+type complianceOutput struct {
+	Account  string
+	Frozen   bool
+	Reason   string `json:",omitempty"`
+	Seizures []seizureOutput
+}
+
+type seizureOutput struct {
+	Height    int64
+	To        string
+	Coins     float64
+	Authority string
+	Reason    string
+}
+
+var complianceShowCommand = cli.Command{
+	Name: "show",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "account",
+			Usage: "the contact, address or public key of the account",
+		},
+	},
+	Usage: "show the freeze and the seizures of an account",
+	Action: func(c *cli.Context) error {
+		if len(c.String("account")) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the account is missing")
+		}
+		account, err := resolveAccount(c.String("account"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		cr, code, err := ComplianceOf(account)
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		co := complianceOutput{Account: showAccount(account), Seizures: []seizureOutput{}}
+		text := "Account: " + co.Account
+		if cr.Frozen != nil {
+			co.Frozen = true
+			co.Reason = cr.Frozen.Reason
+			text += "\nFrozen at " + strconv.FormatInt(cr.Frozen.Height, 10) + ": " + co.Reason
+		}
+		for _, sj := range cr.Seizures {
+			so := seizureOutput{Height: sj.Height, To: showAccount(sj.To), Coins: sj.Coins, Authority: showAccount(sj.Authority), Reason: sj.Reason}
+			co.Seizures = append(co.Seizures, so)
+			text += "\nSeized at " + strconv.FormatInt(so.Height, 10) + ": " + strconv.FormatFloat(so.Coins, 'f', -1, 64) +
+				" coins to " + so.To + " for " + so.Reason
+		}
+		printOutput(co, text)
+		return nil
+	},
+}
+
+var ComplianceCommand = cli.Command{
+	Name:  "compliance",
+	Usage: "freeze, unfreeze and seize accounts as a compliance authority of the server's -authorities flag",
+	Subcommands: []cli.Command{
+		complianceFreezeCommand,
+		complianceUnfreezeCommand,
+		complianceSeizeCommand,
+		complianceShowCommand,
+	},
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/urfave/cli"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/anon"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// The same numbers as the server's, the commitments hide integer units of a coin.
+const (
+	CONFIDENTIAL_UNITS          = 100
+	CONFIDENTIAL_BITS           = 40
+	CONFIDENTIAL_REMAINDER_BITS = 7
+	CONFIDENTIAL_MAX_REMAINDER  = 99
+)
+
+var pedersenH = suite.Point().Pick(suite.XOF([]byte("theftcoin-pedersen-h")))
+
+func commit(v int64, r kyber.Scalar) kyber.Point {
+	return suite.Point().Add(suite.Point().Mul(r, nil), suite.Point().Mul(suite.Scalar().SetInt64(v), pedersenH))
+}
+
+func commitBytes(v int64, r kyber.Scalar) []byte {
+	b, _ := commit(v, r).MarshalBinary()
+	return b
+}
+
+// addCommitments returns the sum of the commitments, an empty commitment is zero.
+func addCommitments(a, b []byte) ([]byte, error) {
+	if len(a) == 0 {
+		return b, nil
+	}
+	if len(b) == 0 {
+		return a, nil
+	}
+	pa := suite.Point()
+	pb := suite.Point()
+	if pa.UnmarshalBinary(a) != nil || pb.UnmarshalBinary(b) != nil {
+		return nil, errors.New("The commitment is not a point of the curve.")
+	}
+	return suite.Point().Add(pa, pb).MarshalBinary()
+}
+
+func randomScalar() kyber.Scalar {
+	return suite.Scalar().Pick(suite.RandomStream())
+}
+
+func ConfidentialUnits(coins float64) (int64, error) {
+	units := math.Floor(coins*CONFIDENTIAL_UNITS + 0.5)
+	if math.Abs(units-coins*CONFIDENTIAL_UNITS) > 1e-6 {
+		return 0, errors.New("The coins have more than " + strconv.Itoa(int(math.Log10(CONFIDENTIAL_UNITS))) + " decimals.")
+	}
+	if units <= 0 || units >= 1<<CONFIDENTIAL_BITS {
+		return 0, errors.New("The coins are out of the confidential range.")
+	}
+	return int64(units), nil
+}
+
+func unitsCoins(units int64) float64 {
+	return float64(units) / CONFIDENTIAL_UNITS
+}
+
+func RangeProofMessage(bit []byte) []byte {
+	return append([]byte("theftcoin-range:"), bit...)
+}
+
+// proveRange commits to every bit of the value with a ring signature that the bit is 0 or 1,
+// the blindings of the bits sum to the blinding of the value.
+func proveRange(v int64, r kyber.Scalar, bits int) (*RangeProof, error) {
+	if v < 0 || v >= int64(1)<<uint(bits) {
+		return nil, errors.New("The value is out of the range of " + strconv.Itoa(bits) + " bits.")
+	}
+	blindings := make([]kyber.Scalar, bits)
+	sum := suite.Scalar().Zero()
+	for i := 1; i < bits; i++ {
+		blindings[i] = randomScalar()
+		sum.Add(sum, suite.Scalar().Mul(suite.Scalar().SetInt64(int64(1)<<uint(i)), blindings[i]))
+	}
+	blindings[0] = suite.Scalar().Sub(r, sum)
+
+	proof := &RangeProof{}
+	for i := 0; i < bits; i++ {
+		bit := int((v >> uint(i)) & 1)
+		c := commit(int64(bit), blindings[i])
+		cb, err := c.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		set := anon.Set{c, suite.Point().Sub(c, pedersenH)}
+		proof.Bits = append(proof.Bits, cb)
+		proof.Signatures = append(proof.Signatures, anon.Sign(suite, RangeProofMessage(cb), set, nil, bit, blindings[i]))
+	}
+	return proof, nil
+}
+
+type noteOpening struct {
+	Value    int64
+	Blinding []byte
+}
+
+func noteKey(r kyber.Point, shared kyber.Point) *[32]byte {
+	rb, _ := r.MarshalBinary()
+	sb, _ := shared.MarshalBinary()
+	key := sha256.Sum256(append(rb, sb...))
+	return &key
+}
+
+// sealNote encrypts the opening of a commitment to the public key,
+// it is the ephemeral point, the nonce and the secretbox.
+func sealNote(pub []byte, v int64, r kyber.Scalar) ([]byte, error) {
+	p, err := publicKeyPoint(pub)
+	if err != nil {
+		return nil, err
+	}
+	k := randomScalar()
+	ephemeral := suite.Point().Mul(k, nil)
+	key := noteKey(ephemeral, suite.Point().Mul(k, p))
+
+	rb, _ := r.MarshalBinary()
+	msg, _ := json.Marshal(noteOpening{Value: v, Blinding: rb})
+	var nonce [24]byte
+	_, err = rand.Read(nonce[:])
+	if err != nil {
+		return nil, err
+	}
+	note, _ := ephemeral.MarshalBinary()
+	note = append(note, nonce[:]...)
+	return secretbox.Seal(note, msg, &nonce, key), nil
+}
+
+func openNote(x kyber.Scalar, note []byte) (int64, kyber.Scalar, error) {
+	size := suite.PointLen()
+	if len(note) < size+24 {
+		return 0, nil, errors.New("The note is too short.")
+	}
+	ephemeral := suite.Point()
+	err := ephemeral.UnmarshalBinary(note[:size])
+	if err != nil {
+		return 0, nil, errors.New("The note is not correct.")
+	}
+	var nonce [24]byte
+	copy(nonce[:], note[size:size+24])
+	msg, ok := secretbox.Open(nil, note[size+24:], &nonce, noteKey(ephemeral, suite.Point().Mul(x, ephemeral)))
+	if !ok {
+		return 0, nil, errors.New("The note is not for this key.")
+	}
+	no := noteOpening{}
+	err = json.Unmarshal(msg, &no)
+	if err != nil {
+		return 0, nil, errors.New("The note is not correct.")
+	}
+	r := suite.Scalar()
+	err = r.UnmarshalBinary(no.Blinding)
+	if err != nil {
+		return 0, nil, errors.New("The note is not correct.")
+	}
+	return no.Value, r, nil
+}
+
+type ConfidentialBalance struct {
+	Coins float64
+	Notes int
+}
+
+// confidentialOpening opens the confidential balance of the key with its notes.
+func confidentialOpening(privk crypto.PrivKey) (int64, kyber.Scalar, uint32, error) {
+	pubB, err := privk.GetPublic().Bytes()
+	if err != nil {
+		return 0, nil, CodeTypeClientError, err
+	}
+	resp, code, err := queryPath(CONFIDENTIAL_QUERY_PATH, pubB)
+	if err != nil {
+		return 0, nil, code, err
+	}
+	cr := ConfidentialResponse{}
+	err = json.Unmarshal(resp.Value, &cr)
+	if err != nil {
+		return 0, nil, CodeTypeEncodingError, errors.New("The confidential response is not json.")
+	}
+
+	x, err := privateKeyScalar(privk)
+	if err != nil {
+		return 0, nil, CodeTypeClientError, err
+	}
+	var v int64
+	r := suite.Scalar().Zero()
+	for _, note := range cr.Notes {
+		nv, nr, err := openNote(x, note)
+		if err != nil {
+			return 0, nil, CodeTypeClientError, err
+		}
+		v += nv
+		r.Add(r, nr)
+	}
+
+	balance := suite.Point().Null()
+	if len(cr.Balance) > 0 {
+		err = balance.UnmarshalBinary(cr.Balance)
+		if err != nil {
+			return 0, nil, CodeTypeEncodingError, errors.New("The balance is not a commitment.")
+		}
+	}
+	if !balance.Equal(commit(v, r)) {
+		return 0, nil, CodeTypeClientError, errors.New("The notes do not open the confidential balance.")
+	}
+	return v, r, CodeTypeOK, nil
+}
+
+func ConfidentialDeposit(from crypto.PrivKey, coins float64) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	units, err := ConfidentialUnits(coins)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr := newDeliveryRequest(pubB, CONFIDENTIAL_DEPOSIT_ACTION, coins)
+	note, err := sealNote(pubB, units, suite.Scalar().Zero())
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr.Data.Confidential = &Confidential{SenderNote: note}
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+func ConfidentialWithdraw(from crypto.PrivKey, coins float64) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	units, err := ConfidentialUnits(coins)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	v, r, code, err := confidentialOpening(from)
+	if err != nil {
+		return nil, code, err
+	}
+	if units > v {
+		return nil, CodeTypeClientError, errors.New("You dont have enough confidential money.")
+	}
+
+	conf := Confidential{}
+	conf.BalanceProof, err = proveRange(v-units, r, CONFIDENTIAL_BITS)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	conf.SenderNote, err = sealNote(pubB, v-units, r)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr := newDeliveryRequest(pubB, CONFIDENTIAL_WITHDRAW_ACTION, coins)
+	dr.Data.Confidential = &conf
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+// confidentialSendData commits to the amount and to its tax rounded down,
+// and proves the ranges and the tax without showing the numbers.
+func confidentialSendData(pubB []byte, to []byte, tr TaxResponse, v int64, r kyber.Scalar, amount int64) (*Confidential, error) {
+	if amount > v {
+		return nil, errors.New("You dont have enough confidential money.")
+	}
+	percentage := int64(tr.Percentage)
+	tax := percentage * amount / 100
+	remainder := percentage*amount - 100*tax
+	rA := randomScalar()
+	rT := randomScalar()
+	remainderR := suite.Scalar().Sub(suite.Scalar().Mul(suite.Scalar().SetInt64(percentage), rA), suite.Scalar().Mul(suite.Scalar().SetInt64(100), rT))
+
+	var err error
+	conf := &Confidential{}
+	conf.Amount = commitBytes(amount, rA)
+	conf.Tax = commitBytes(tax, rT)
+	conf.AmountProof, err = proveRange(amount, rA, CONFIDENTIAL_BITS)
+	if err != nil {
+		return nil, err
+	}
+	conf.TaxProof, err = proveRange(tax, rT, CONFIDENTIAL_BITS)
+	if err != nil {
+		return nil, err
+	}
+	low, err := proveRange(remainder, remainderR, CONFIDENTIAL_REMAINDER_BITS)
+	if err != nil {
+		return nil, err
+	}
+	high, err := proveRange(CONFIDENTIAL_MAX_REMAINDER-remainder, suite.Scalar().Neg(remainderR), CONFIDENTIAL_REMAINDER_BITS)
+	if err != nil {
+		return nil, err
+	}
+	conf.RemainderProofs = []RangeProof{*low, *high}
+	conf.BalanceProof, err = proveRange(v-amount, suite.Scalar().Sub(r, rA), CONFIDENTIAL_BITS)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.SenderNote, err = sealNote(pubB, v-amount, suite.Scalar().Sub(r, rA))
+	if err != nil {
+		return nil, err
+	}
+	conf.ReceiverNote, err = sealNote(to, amount-tax, suite.Scalar().Sub(rA, rT))
+	if err != nil {
+		return nil, err
+	}
+	conf.TaxNote, err = sealNote(tr.Receiver, tax, rT)
+	if err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+func ConfidentialSend(from crypto.PrivKey, to []byte, tr TaxResponse, coins float64) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	amount, err := ConfidentialUnits(coins)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	v, r, code, err := confidentialOpening(from)
+	if err != nil {
+		return nil, code, err
+	}
+	conf, err := confidentialSendData(pubB, to, tr, v, r, amount)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+
+	dr := newSendRequest(pubB, to, tr.Hash, 0)
+	dr.Data.Action = CONFIDENTIAL_SEND_ACTION
+	dr.Data.Confidential = conf
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+var confidentialBalanceCommand = cli.Command{
+	Name: "balance",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+	},
+	Usage: "open the confidential balance with the notes of the key",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		v, _, code, err := confidentialOpening(privk)
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		cb := ConfidentialBalance{Coins: unitsCoins(v)}
+		printOutput(cb, "Confidential coins: ", cb.Coins)
+		return nil
+	},
+}
+
+func confidentialCoinsCommand(name string, usage string, action func(crypto.PrivKey, float64) (*DeliveryResult, uint32, error)) cli.Command {
+	return cli.Command{
+		Name: name,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "key",
+				Usage: "the filename that contains the key in json file",
+			},
+			cli.Float64Flag{
+				Name:  "coins",
+				Usage: "the number of coins, with two decimals at most",
+			},
+		},
+		Usage: usage,
+		Action: func(c *cli.Context) error {
+			key := keyFile(c)
+			if len(key) == 0 {
+				return newCommandError(CodeTypeClientError, "Error: the key is missing")
+			}
+			coins := c.Float64("coins")
+			if coins <= 0 {
+				return newCommandError(CodeTypeClientError, "Error: the coins are not allowed to be 0 or less")
+			}
+			privk, err := fileKey(key)
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			dres, code, err := action(privk, coins)
+			if err != nil {
+				return newResultError(code, "Error:"+err.Error(), dres)
+			}
+			printOutput(dres, "The "+name+" was successful")
+			return nil
+		},
+	}
+}
+
+var confidentialSendCommand = cli.Command{
+	Name: "send",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.StringFlag{
+			Name:  "receiver",
+			Usage: "the receiver's contact, address or public key",
+		},
+		cli.Float64Flag{
+			Name:  "coins",
+			Usage: "the number of coins, with two decimals at most",
+		},
+		cli.BoolFlag{
+			Name:  "yes",
+			Usage: "send without asking for confirmation",
+		},
+	},
+	Usage: "send confidential coins, the amount and the tax are hidden in the block",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		receiver := c.String("receiver")
+		if len(receiver) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the receiver is missing")
+		}
+		coins := c.Float64("coins")
+		if coins <= 0 {
+			return newCommandError(CodeTypeClientError, "Error: the coins are not allowed to be 0 or less")
+		}
+		amount, err := ConfidentialUnits(coins)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		tr, code, err := Tax()
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		taxCoins := unitsCoins(int64(tr.Percentage) * amount / 100)
+		printText("Tax: ", taxCoins, "("+strconv.Itoa(tr.Percentage)+"% rounded down to "+showAccount(tr.Receiver)+")")
+		printText("The receiver gets: ", unitsCoins(amount)-taxCoins)
+		if !c.Bool("yes") {
+			ok, err := confirm("Do you want to send " + strconv.FormatFloat(coins, 'f', -1, 64) + " confidential coins?")
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			if !ok {
+				return newCommandError(CodeTypeClientError, "Error: the send was cancelled")
+			}
+		}
+
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		to, err := resolveAccount(receiver)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		sr := SendResult{Tax: taxCoins, ReceiverCoins: unitsCoins(amount) - taxCoins}
+		sr.Result, code, err = ConfidentialSend(privk, to, *tr, coins)
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), sr)
+		}
+		printOutput(sr, "The send was successful")
+		return nil
+	},
+}
+
+var ConfidentialCommand = cli.Command{
+	Name:  "confidential",
+	Usage: "keep coins in a confidential balance and send them without showing the amount",
+	Subcommands: []cli.Command{
+		confidentialBalanceCommand,
+		confidentialCoinsCommand("deposit", "move coins from the balance to the confidential balance", ConfidentialDeposit),
+		confidentialCoinsCommand("withdraw", "move coins from the confidential balance to the balance", ConfidentialWithdraw),
+		confidentialSendCommand,
+	},
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/urfave/cli"
+)
+
+const (
+	DEFAULT_PROFILE = "default"
+
+	ENV_CONFIG   = "THEFTCOIN_CONFIG"
+	ENV_PROFILE  = "THEFTCOIN_PROFILE"
+	ENV_NODE     = "THEFTCOIN_NODE"
+	ENV_CHAIN_ID = "THEFTCOIN_CHAIN_ID"
+	ENV_KEY      = "THEFTCOIN_KEY"
+	ENV_OUTPUT   = "THEFTCOIN_OUTPUT"
+)
+
+type Profile struct {
+	Node    string `toml:"node"`
+	ChainId string `toml:"chain_id"`
+	Key     string `toml:"key"`
+	Output  string `toml:"output"`
+}
+
+type ConfigFile struct {
+	Profile  string             `toml:"profile"`
+	Profiles map[string]Profile `toml:"profiles"`
+}
+
+var ConfigFlag = cli.StringFlag{
+	Name:  "config",
+	Usage: "the configuration file, by default ~/.theftcoin/config.toml",
+}
+
+var ProfileFlag = cli.StringFlag{
+	Name:  "profile, p",
+	Usage: "the profile of the configuration file",
+}
+
+var NodeFlag = cli.StringFlag{
+	Name:  "node",
+	Usage: "the URL of the node's RPC",
+}
+
+func defaultConfigFile() string {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "config.toml"
+	}
+	return filepath.Join(home, ".theftcoin", "config.toml")
+}
+
+func firstNotEmpty(values ...string) string {
+	for _, v := range values {
+		if len(v) > 0 {
+			return v
+		}
+	}
+	return ""
+}
+
+func readConfigFile(filename string) (*ConfigFile, error) {
+	cf := &ConfigFile{}
+	_, err := os.Stat(filename)
+	if err == nil {
+		_, err = toml.DecodeFile(filename, cf)
+		if err != nil {
+			return nil, errors.New("The configuration file is not correct: " + err.Error())
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if cf.Profiles == nil {
+		cf.Profiles = map[string]Profile{}
+	}
+	return cf, nil
+}
+
+func writeConfigFile(filename string, cf *ConfigFile) error {
+	err := os.MkdirAll(filepath.Dir(filename), 0700)
+	if err != nil {
+		return err
+	}
+	buf := bytes.Buffer{}
+	err = toml.NewEncoder(&buf).Encode(cf)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0600)
+}
+
+func (c *configuration) submitProfile(p Profile) {
+	c.NodeDaemon = firstNotEmpty(p.Node, c.NodeDaemon)
+	c.ChainId = firstNotEmpty(p.ChainId, c.ChainId)
+	c.Key = firstNotEmpty(p.Key, c.Key)
+	c.Output = firstNotEmpty(p.Output, c.Output)
+}
+
+// submitConfiguration loads the profile of the configuration file,
+// the environment overrides the profile and the flags override the environment.
+func submitConfiguration(c *cli.Context) error {
+	Conf.ConfigFile = firstNotEmpty(c.GlobalString("config"), os.Getenv(ENV_CONFIG), defaultConfigFile())
+	cf, err := readConfigFile(Conf.ConfigFile)
+	if err != nil {
+		return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+	}
+
+	Conf.Profile = firstNotEmpty(c.GlobalString("profile"), os.Getenv(ENV_PROFILE), cf.Profile, DEFAULT_PROFILE)
+	p, ok := cf.Profiles[Conf.Profile]
+	if !ok && Conf.Profile != DEFAULT_PROFILE && c.Args().First() != ConfigCommand.Name {
+		return newCommandError(CodeTypeClientError, "Error: the profile "+Conf.Profile+" does not exist")
+	}
+	Conf.submitProfile(p)
+	Conf.submitProfile(Profile{
+		Node:    os.Getenv(ENV_NODE),
+		ChainId: os.Getenv(ENV_CHAIN_ID),
+		Key:     os.Getenv(ENV_KEY),
+		Output:  os.Getenv(ENV_OUTPUT),
+	})
+	Conf.submitProfile(Profile{
+		Node:   c.GlobalString("node"),
+		Output: c.GlobalString("output"),
+	})
+
+	if !validOutput(Conf.Output) {
+		output := Conf.Output
+		Conf.Output = OUTPUT_TEXT
+		return newCommandError(CodeTypeClientError, "Error: the output "+output+" should be text or json")
+	}
+	return nil
+}
+
+type configOutput struct {
+	ConfigFile string
+	Profile    string
+	Node       string
+	ChainId    string
+	Key        string
+	Output     string
+}
+
+var configShowCommand = cli.Command{
+	Name:  "show",
+	Usage: "show the settings of the profile after the environment and the flags",
+	Action: func(c *cli.Context) error {
+		co := configOutput{
+			ConfigFile: Conf.ConfigFile,
+			Profile:    Conf.Profile,
+			Node:       Conf.NodeDaemon,
+			ChainId:    Conf.ChainId,
+			Key:        Conf.Key,
+			Output:     Conf.Output,
+		}
+		printOutput(co, "Config file: "+co.ConfigFile+
+			"\nProfile: "+co.Profile+
+			"\nNode: "+co.Node+
+			"\nChain ID: "+co.ChainId+
+			"\nKey: "+co.Key+
+			"\nOutput: "+co.Output)
+		return nil
+	},
+}
+
+var configSetCommand = cli.Command{
+	Name:      "set",
+	Usage:     "set a setting of the profile: node, chain_id, key or output",
+	ArgsUsage: "<setting> <value>",
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 2 {
+			return newCommandError(CodeTypeClientError, "Error: the setting and the value are missing")
+		}
+		setting := c.Args().Get(0)
+		value := c.Args().Get(1)
+
+		cf, err := readConfigFile(Conf.ConfigFile)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		p := cf.Profiles[Conf.Profile]
+		switch setting {
+		case "node":
+			p.Node = value
+		case "chain_id":
+			p.ChainId = value
+		case "key":
+			p.Key = value
+		case "output":
+			if !validOutput(value) {
+				return newCommandError(CodeTypeClientError, "Error: the output should be text or json")
+			}
+			p.Output = value
+		default:
+			return newCommandError(CodeTypeClientError, "Error: the setting should be node, chain_id, key or output")
+		}
+		cf.Profiles[Conf.Profile] = p
+
+		err = writeConfigFile(Conf.ConfigFile, cf)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		printOutput(p, "The set was successful")
+		return nil
+	},
+}
+
+var configUseCommand = cli.Command{
+	Name:      "use",
+	Usage:     "use a profile by default",
+	ArgsUsage: "<profile>",
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return newCommandError(CodeTypeClientError, "Error: the profile is missing")
+		}
+		profile := c.Args().First()
+
+		cf, err := readConfigFile(Conf.ConfigFile)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		cf.Profile = profile
+		cf.Profiles[profile] = cf.Profiles[profile]
+
+		err = writeConfigFile(Conf.ConfigFile, cf)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		printOutput(cf.Profiles[profile], "The profile "+profile+" is used by default")
+		return nil
+	},
+}
+
+var ConfigCommand = cli.Command{
+	Name:  "config",
+	Usage: "show and edit the settings of the client",
+	Subcommands: []cli.Command{
+		configShowCommand,
+		configSetCommand,
+		configUseCommand,
+	},
+}
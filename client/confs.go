@@ -6,11 +6,17 @@ import (
 	"time"
 
 	crypto "github.com/libp2p/go-libp2p-crypto"
+	cmn "github.com/tendermint/tmlibs/common"
 )
 
 type configuration struct {
 	NodeDaemon     string
 	IpfsConnection string
+	Output         string
+	ChainId        string
+	Key            string
+	Profile        string
+	ConfigFile     string
 }
 
 const (
@@ -26,6 +32,8 @@ var Conf = configuration{}
 func init() {
 	Conf.NodeDaemon = "http://localhost:46657"
 	Conf.IpfsConnection = "127.0.0.1:5001"
+	Conf.Output = OUTPUT_TEXT
+	Conf.Profile = DEFAULT_PROFILE
 }
 
 type ActionStruct string
@@ -34,23 +42,207 @@ const (
 	ADD_ACTION    = ActionStruct("add")
 	REMOVE_ACTION = ActionStruct("remove")
 	SEND_ACTION   = ActionStruct("send")
+
+	CONFIDENTIAL_DEPOSIT_ACTION  = ActionStruct("confidential_deposit")
+	CONFIDENTIAL_WITHDRAW_ACTION = ActionStruct("confidential_withdraw")
+	CONFIDENTIAL_SEND_ACTION     = ActionStruct("confidential_send")
+
+	VIEW_GRANT_ACTION  = ActionStruct("view_grant")
+	VIEW_REVOKE_ACTION = ActionStruct("view_revoke")
+
+	AUDIT_ANCHOR_ACTION = ActionStruct("audit_anchor")
+
+	ESCROW_OPEN_ACTION    = ActionStruct("escrow_open")
+	ESCROW_RELEASE_ACTION = ActionStruct("escrow_release")
+	ESCROW_REFUND_ACTION  = ActionStruct("escrow_refund")
+
+	HTLC_LOCK_ACTION   = ActionStruct("htlc_lock")
+	HTLC_CLAIM_ACTION  = ActionStruct("htlc_claim")
+	HTLC_REFUND_ACTION = ActionStruct("htlc_refund")
+
+	STANDING_ORDER_ACTION         = ActionStruct("standing_order")
+	STANDING_ORDER_CANCEL_ACTION  = ActionStruct("standing_order_cancel")
+	STANDING_ORDER_PAYMENT_ACTION = ActionStruct("standing_order_payment")
+
+	FREEZE_ACTION   = ActionStruct("freeze")
+	UNFREEZE_ACTION = ActionStruct("unfreeze")
+	SEIZE_ACTION    = ActionStruct("seize")
+
+	SET_TIER_ACTION    = ActionStruct("set_tier")
+	ASSIGN_TIER_ACTION = ActionStruct("assign_tier")
+
+	ATTEST_ACTION        = ActionStruct("attest")
+	ATTEST_REVOKE_ACTION = ActionStruct("attest_revoke")
 )
 
+const (
+	TAX_QUERY_PATH = "/tax"
+
+	TAX_REPORT_QUERY_PATH = "/tax/report"
+
+	CONFIDENTIAL_QUERY_PATH = "/confidential"
+
+	HISTORY_QUERY_PATH     = "/history"
+	VIEW_GRANTS_QUERY_PATH = "/view/grants"
+
+	AUDIT_QUERY_PATH      = "/audit"
+	AUDIT_HEAD_QUERY_PATH = "/audit/head"
+
+	ESCROW_QUERY_PATH = "/escrow"
+	HTLC_QUERY_PATH   = "/htlc"
+
+	STANDING_ORDER_QUERY_PATH          = "/standing-order"
+	STANDING_ORDER_PAYMENTS_QUERY_PATH = "/standing-order/payments"
+
+	COMPLIANCE_QUERY_PATH = "/compliance"
+
+	ALLOWANCE_QUERY_PATH = "/allowance"
+
+	ATTESTATION_QUERY_PATH = "/attestation"
+)
+
+type ViewKind string
+
+const (
+	VIEW_BALANCE = ViewKind("balance")
+	VIEW_HISTORY = ViewKind("history")
+)
+
+type RangeProof struct {
+	Bits       [][]byte // commitments
+	Signatures [][]byte
+}
+
+type Confidential struct {
+	Amount          []byte // commitment of the coins of a send
+	Tax             []byte // commitment of the tax of a send
+	AmountProof     *RangeProof
+	TaxProof        *RangeProof
+	RemainderProofs []RangeProof
+	BalanceProof    *RangeProof
+	SenderNote      []byte
+	ReceiverNote    []byte
+	TaxNote         []byte
+}
+
+type ViewGrant struct {
+	Watcher   []byte // public key
+	Kind      ViewKind
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+type AuditAnchor struct {
+	Log    string
+	Index  int64
+	Hash   []byte
+	From   []byte `json:",omitempty"`
+	Height int64  `json:",omitempty"`
+}
+
+type Stealth struct {
+	Ephemeral []byte // point of the curve
+}
+
+type Memo struct {
+	Text      string   `json:",omitempty"`
+	Ephemeral []byte   `json:",omitempty"` // point of the curve
+	Keys      [][]byte `json:",omitempty"` // nonce and sealed key of every reader
+	Box       []byte   `json:",omitempty"` // nonce and sealed text
+}
+
+type Escrow struct {
+	ID      string
+	Arbiter *[]byte `json:",omitempty"` // public key
+	Timeout int64   `json:",omitempty"` // height
+}
+
+type Htlc struct {
+	Hashlock []byte
+	Timeout  int64  `json:",omitempty"` // height
+	Preimage []byte `json:",omitempty"`
+}
+
+type StandingOrder struct {
+	ID       string
+	Interval int64 `json:",omitempty"` // blocks
+	Start    int64 `json:",omitempty"` // height
+	End      int64 `json:",omitempty"` // height
+}
+
+type Compliance struct {
+	Account []byte // public key
+	Reason  string
+}
+
+type Tier struct {
+	Name      string
+	MaxSend   float64 `json:",omitempty"`
+	MaxWindow float64 `json:",omitempty"`
+	Window    int64   `json:",omitempty"` // blocks
+	Account   *[]byte `json:",omitempty"` // public key
+}
+
+type Attestation struct {
+	Account   []byte // public key
+	Level     int    `json:",omitempty"`
+	Expiry    int64  `json:",omitempty"` // height
+	Document  []byte `json:",omitempty"` // SHA-256 hash
+	Attestor  []byte `json:",omitempty"` // public key
+	Signature []byte `json:",omitempty"`
+}
+
 type DeliveryData struct {
 	From    []byte  // public key
 	To      *[]byte // public key
 	Action  ActionStruct
 	TaxHash *string
 	Coins   float64
+	Stealth *Stealth `json:",omitempty"`
+	Memo    *Memo    `json:",omitempty"`
+
+	Confidential *Confidential `json:",omitempty"`
+
+	ViewGrant *ViewGrant `json:",omitempty"`
+
+	AuditAnchor *AuditAnchor `json:",omitempty"`
+
+	Escrow *Escrow `json:",omitempty"`
+	Htlc   *Htlc   `json:",omitempty"`
+
+	StandingOrder *StandingOrder `json:",omitempty"`
+
+	Compliance *Compliance `json:",omitempty"`
+	Tier       *Tier       `json:",omitempty"`
+
+	Attestation *Attestation `json:",omitempty"`
+}
+
+// Multisig is the threshold and the members' keys of a multisig account.
+type Multisig struct {
+	Threshold int
+	Members   [][]byte // public keys
+}
+
+type MultisigSignature struct {
+	Member    []byte // public key
+	Signature []byte
 }
 
 type DeliveryRequest struct {
 	Signature []byte
 	Date      time.Time
 	Data      DeliveryData
+
+	Multisig   *Multisig           `json:",omitempty"`
+	Signatures []MultisigSignature `json:",omitempty"`
 }
 
 func (dr *DeliveryRequest) VerifySignature() (bool, error) {
+	if dr.Multisig != nil {
+		b, _ := json.Marshal(dr.Data)
+		return dr.Multisig.verify(dr.Data.From, b, dr.Signatures, dr.Multisig.Threshold)
+	}
 	pub, err := crypto.UnmarshalPublicKey(dr.Data.From)
 	if err != nil {
 		return false, errors.New("The sender's public key is not correct")
@@ -68,11 +260,17 @@ type QueryData struct {
 	Date  time.Time
 	Nonce string
 	User  *[]byte
+
+	FromHeight int64 `json:",omitempty"`
+	ToHeight   int64 `json:",omitempty"`
 }
 
 type QueryRequest struct {
 	Signature []byte
 	Data      QueryData
+
+	Multisig   *Multisig           `json:",omitempty"`
+	Signatures []MultisigSignature `json:",omitempty"`
 }
 
 func (qr *QueryRequest) VerifySignature() (bool, error) {
@@ -91,3 +289,222 @@ func (qr *QueryRequest) VerifySignature() (bool, error) {
 type QueryResponse struct {
 	Coins float64
 }
+
+type DeliveryResult struct {
+	Hash   string
+	Height int64
+	Code   uint32
+	Log    string
+}
+
+type TaxResponse struct {
+	Hash       string
+	Percentage int
+	Receiver   []byte // public key
+}
+
+type ConfidentialResponse struct {
+	Balance []byte // commitment
+	Notes   [][]byte
+}
+
+type HistoryEntry struct {
+	Height int64
+	Time   time.Time
+	Hash   []byte
+	Action ActionStruct
+	From   []byte  // public key
+	To     *[]byte // public key
+	Coins  float64
+	Memo   *Memo `json:",omitempty"`
+
+	StandingOrder *StandingOrder `json:",omitempty"`
+
+	Payer       []byte  `json:",omitempty"` // public key
+	Payee       []byte  `json:",omitempty"` // public key
+	Tax         float64 `json:",omitempty"`
+	TaxReceiver []byte  `json:",omitempty"` // public key
+}
+
+type HistoryResponse struct {
+	Entries []HistoryEntry
+}
+
+type ViewGrantsResponse struct {
+	Grants []ViewGrant
+}
+
+type AuditEntry struct {
+	Index    int64
+	Watcher  []byte // public key
+	User     []byte // public key
+	Path     string
+	Time     time.Time
+	Nonce    string
+	Previous []byte
+	Hash     []byte
+}
+
+type AuditHead struct {
+	Log   string `json:",omitempty"`
+	Index int64
+	Hash  []byte
+}
+
+type AuditResponse struct {
+	Entries    []AuditEntry
+	Head       AuditHead
+	Anchored   int64
+	Mismatches []AuditAnchor
+}
+
+type EscrowStatus string
+
+const (
+	ESCROW_OPEN     = EscrowStatus("open")
+	ESCROW_RELEASED = EscrowStatus("released")
+	ESCROW_REFUNDED = EscrowStatus("refunded")
+)
+
+type EscrowJson struct {
+	ID      string
+	Payer   []byte  // public key
+	Payee   []byte  // public key
+	Arbiter *[]byte `json:",omitempty"` // public key
+	Coins   float64
+	TaxHash string
+	Timeout int64 // height
+	Height  int64 // the height of the open
+	Status  EscrowStatus
+}
+
+type HtlcStatus string
+
+const (
+	HTLC_LOCKED   = HtlcStatus("locked")
+	HTLC_CLAIMED  = HtlcStatus("claimed")
+	HTLC_REFUNDED = HtlcStatus("refunded")
+)
+
+type HtlcJson struct {
+	Hashlock []byte
+	Sender   []byte // public key
+	Receiver []byte // public key
+	Coins    float64
+	TaxHash  string
+	Timeout  int64 // height
+	Height   int64 // the height of the lock
+	Status   HtlcStatus
+	Preimage []byte `json:",omitempty"`
+}
+
+type StandingOrderStatus string
+
+const (
+	STANDING_ORDER_ACTIVE    = StandingOrderStatus("active")
+	STANDING_ORDER_CANCELLED = StandingOrderStatus("cancelled")
+	STANDING_ORDER_FINISHED  = StandingOrderStatus("finished")
+)
+
+type StandingOrderFailure struct {
+	Height int64
+	Reason string
+}
+
+type StandingOrderJson struct {
+	ID       string
+	Owner    []byte // public key
+	Receiver []byte // public key
+	Coins    float64
+	TaxHash  string
+	Interval int64 // blocks
+	Next     int64 // height
+	End      int64 // height
+	Status   StandingOrderStatus
+	Payments int
+	Failures []StandingOrderFailure
+}
+
+// StandingOrderPayment is a payment of a standing order at the end of the block, with the tags of a transaction.
+type StandingOrderPayment struct {
+	ID     string
+	Height int64
+	Index  int
+	Tags   []cmn.KVPair
+}
+
+type StandingOrderPaymentsRequest struct {
+	FromHeight int64
+	ToHeight   int64 // zero is until the latest height
+}
+
+type StandingOrderPaymentsResponse struct {
+	Payments []StandingOrderPayment
+}
+
+type FreezeJson struct {
+	Account   []byte // public key
+	Authority []byte // public key
+	Reason    string
+	Height    int64
+}
+
+type SeizureJson struct {
+	Account   []byte // public key
+	To        []byte // public key
+	Coins     float64
+	Authority []byte // public key
+	Reason    string
+	Height    int64
+}
+
+type ComplianceResponse struct {
+	Frozen   *FreezeJson `json:",omitempty"`
+	Seizures []SeizureJson
+}
+
+type AttestationJson struct {
+	Attestation
+	Height int64
+}
+
+type AttestationResponse struct {
+	Attestation *AttestationJson `json:",omitempty"`
+	Valid       bool
+}
+
+type AllowanceResponse struct {
+	Tier      string
+	MaxSend   float64
+	MaxWindow float64
+	Window    int64 // blocks
+	Sent      float64
+	Remaining float64 // -1 without limit
+}
+
+type TaxRateJson struct {
+	TaxHash    string
+	Percentage int
+	Transfers  int
+	Volume     float64
+	Tax        float64
+
+	ConfidentialTransfers int
+	ConfidentialTax       []byte `json:",omitempty"` // the sum of the commitments of the confidential sends' tax
+}
+
+type TaxBlockJson struct {
+	Height        int64
+	Rates         []TaxRateJson
+	Own           float64
+	Measured      float64
+	BalanceBefore float64
+	BalanceAfter  float64
+}
+
+type TaxReportResponse struct {
+	FromHeight int64
+	ToHeight   int64
+	Receiver   []byte // public key
+	Blocks     []TaxBlockJson
+}
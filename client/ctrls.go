@@ -3,14 +3,25 @@ package main
 import (
 	"encoding/hex"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"io/ioutil"
+	"strconv"
 
 	crypto "github.com/libp2p/go-libp2p-crypto"
 	"github.com/urfave/cli"
 )
 
+type GenerateResult struct {
+	PublicKey string
+	Address   string
+	Filename  string
+}
+
+type SendResult struct {
+	Tax           float64
+	ReceiverCoins float64
+	Result        *DeliveryResult
+}
+
 var GenerateKeyCommand = cli.Command{
 	Name:    "generate",
 	Aliases: []string{"g"},
@@ -24,19 +35,23 @@ var GenerateKeyCommand = cli.Command{
 	Action: func(c *cli.Context) error {
 		filename := c.String("filename")
 		if len(filename) == 0 {
-			return errors.New("Error: filename is missing")
+			return newCommandError(CodeTypeClientError, "Error: filename is missing")
 		}
 		privk, _, _ := crypto.GenerateKeyPair(crypto.Ed25519, 0)
 		kj := KeyJson{}
-		b, _ := privk.GetPublic().Bytes()
-		kj.PublicKey = hex.EncodeToString(b)
+		pub, _ := privk.GetPublic().Bytes()
+		kj.PublicKey = hex.EncodeToString(pub)
 		kj.PrivateKey, _ = crypto.MarshalPrivateKey(privk)
-		b, _ = json.Marshal(kj)
-		err := ioutil.WriteFile(filename, b, 0644)
+		address, err := Address(pub)
 		if err != nil {
-			return errors.New("Error: " + err.Error())
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
 		}
-		fmt.Println("The generate was successful")
+		b, _ := json.Marshal(kj)
+		err = ioutil.WriteFile(filename, b, 0644)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error: "+err.Error())
+		}
+		printOutput(GenerateResult{PublicKey: kj.PublicKey, Address: address, Filename: filename}, "The generate was successful, the address is "+address)
 		return nil
 	},
 }
@@ -56,26 +71,26 @@ var AddCommand = cli.Command{
 	},
 	Usage: "add coins to your account as an inflator",
 	Action: func(c *cli.Context) error {
-		key := c.String("key")
+		key := keyFile(c)
 		if len(key) == 0 {
-			return errors.New("Error: the key is missing")
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
 		}
 
 		coins := c.Float64("coins")
 		if coins <= 0 {
-			return errors.New("Error: the coins are not allowed to be 0 or less")
+			return newCommandError(CodeTypeClientError, "Error: the coins are not allowed to be 0 or less")
 		}
 
 		privk, err := fileKey(key)
 		if err != nil {
-			return errors.New("Error client:" + err.Error())
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
 		}
 
-		_, err = Add(privk, coins)
+		dres, code, err := Add(privk, coins)
 		if err != nil {
-			return errors.New("Error:" + err.Error())
+			return newResultError(code, "Error:"+err.Error(), dres)
 		}
-		fmt.Println("The add was successful")
+		printOutput(dres, "The add was successful")
 		return nil
 	},
 }
@@ -95,26 +110,26 @@ var RemoveCommand = cli.Command{
 	},
 	Usage: "remove coins from your account as an inflator",
 	Action: func(c *cli.Context) error {
-		key := c.String("key")
+		key := keyFile(c)
 		if len(key) == 0 {
-			return errors.New("Error: the key is missing")
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
 		}
 
-		coins := c.Float64("input")
+		coins := c.Float64("coins")
 		if coins <= 0 {
-			return errors.New("Error: the coins are not allowed to be 0 or less")
+			return newCommandError(CodeTypeClientError, "Error: the coins are not allowed to be 0 or less")
 		}
 
 		privk, err := fileKey(key)
 		if err != nil {
-			return errors.New("Error client:" + err.Error())
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
 		}
 
-		_, err = Remove(privk, coins)
+		dres, code, err := Remove(privk, coins)
 		if err != nil {
-			return errors.New("Error:" + err.Error())
+			return newResultError(code, "Error:"+err.Error(), dres)
 		}
-		fmt.Println("The remove was successful")
+		printOutput(dres, "The remove was successful")
 		return nil
 	},
 }
@@ -129,54 +144,122 @@ var SendCommand = cli.Command{
 		},
 		cli.StringFlag{
 			Name:  "receiver",
-			Usage: "the receiver's public key",
+			Usage: "the receiver's contact, address, stealth address or public key",
 		},
 		cli.StringFlag{
 			Name:  "tax",
-			Usage: "the IPFS hash of the tax",
+			Usage: "the IPFS hash of the tax, by default the validator's active tax",
 		},
 		cli.Float64Flag{
 			Name:  "coins",
 			Usage: "the the number of coins you want to add in your account",
 		},
+		cli.StringFlag{
+			Name:  "memo",
+			Usage: "the context of the send, like the invoice",
+		},
+		cli.BoolFlag{
+			Name:  "encrypt-memo",
+			Usage: "encrypt the memo so only the sender and the receiver can read it",
+		},
+		cli.StringFlag{
+			Name:  "memo-watcher",
+			Usage: "the contact, address or public key of a watcher that can read the encrypted memo",
+		},
+		cli.BoolFlag{
+			Name:  "yes",
+			Usage: "send without asking for confirmation",
+		},
 	},
 	Usage: "send coins to another account as an inflator",
 	Action: func(c *cli.Context) error {
-		key := c.String("key")
+		key := keyFile(c)
 		if len(key) == 0 {
-			return errors.New("Error: the key is missing")
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
 		}
 
 		receiver := c.String("receiver")
 		if len(receiver) == 0 {
-			return errors.New("Error: the receiver is missing")
+			return newCommandError(CodeTypeClientError, "Error: the receiver is missing")
 		}
 
+		coins := c.Float64("coins")
+		if coins <= 0 {
+			return newCommandError(CodeTypeClientError, "Error: the coins are not allowed to be 0 or less")
+		}
+
+		tr, code, err := Tax()
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
 		taxHash := c.String("tax")
 		if len(taxHash) == 0 {
-			return errors.New("Error: The tax is not included.")
+			taxHash = tr.Hash
+		}
+		if taxHash != tr.Hash {
+			return newCommandError(CodeTypeClientError, "Error: the tax "+taxHash+" is not the active tax "+tr.Hash)
 		}
 
-		coins := c.Float64("coins")
-		if coins <= 0 {
-			return errors.New("Error: the coins are not allowed to be 0 or less")
+		taxCoins := coins * float64(tr.Percentage) / 100
+		printText("Tax: ", taxCoins, "("+strconv.Itoa(tr.Percentage)+"% to "+showAccount(tr.Receiver)+")")
+		printText("The receiver gets: ", coins-taxCoins)
+		if !c.Bool("yes") {
+			ok, err := confirm("Do you want to send " + strconv.FormatFloat(coins, 'f', -1, 64) + " coins?")
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			if !ok {
+				return newCommandError(CodeTypeClientError, "Error: the send was cancelled")
+			}
 		}
 
 		fromPrivk, err := fileKey(key)
 		if err != nil {
-			return errors.New("Error client:" + err.Error())
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
 		}
 
-		b, err := hex.DecodeString(receiver)
+		// a stealth address receives to a new one-time key
+		var b []byte
+		var stealth *Stealth
+		stealthAddress, ok, err := resolveStealthAddress(receiver)
 		if err != nil {
-			return errors.New("Error client:" + err.Error())
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		if ok {
+			b, stealth, err = newStealthPayment(stealthAddress)
+		} else {
+			b, err = resolveAccount(receiver)
+		}
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		var memo *Memo
+		if len(c.String("memo")) > 0 {
+			readers := [][]byte{}
+			if c.Bool("encrypt-memo") || len(c.String("memo-watcher")) > 0 {
+				fromB, _ := fromPrivk.GetPublic().Bytes()
+				readers = append(readers, fromB, b)
+			}
+			if len(c.String("memo-watcher")) > 0 {
+				watcherB, err := resolveAccount(c.String("memo-watcher"))
+				if err != nil {
+					return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+				}
+				readers = append(readers, watcherB)
+			}
+			memo, err = newMemo(c.String("memo"), readers)
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
 		}
 
-		_, err = Send(fromPrivk, b, taxHash, coins)
+		sr := SendResult{Tax: taxCoins, ReceiverCoins: coins - taxCoins}
+		sr.Result, code, err = Send(fromPrivk, b, taxHash, coins, stealth, memo)
 		if err != nil {
-			return errors.New("Error:" + err.Error())
+			return newResultError(code, "Error:"+err.Error(), sr)
 		}
-		fmt.Println("The send was successful")
+		printOutput(sr, "The send was successful")
 		return nil
 	},
 }
@@ -191,36 +274,36 @@ var QueryCommand = cli.Command{
 		},
 		cli.StringFlag{
 			Name:  "user",
-			Usage: "the user to check if has watcher",
+			Usage: "the user's contact, address or public key, for a watcher with the user's view grant",
 		},
 	},
-	Usage: "send coins to another account as an inflator",
+	Usage: "show the coins of the account, or of a user for a watcher",
 	Action: func(c *cli.Context) error {
-		key := c.String("key")
+		key := keyFile(c)
 		if len(key) == 0 {
-			return errors.New("Error: the key is missing")
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
 		}
 
 		var userB *[]byte
 		user := c.String("user")
 		if len(user) > 0 {
-			b, err := hex.DecodeString(user)
+			b, err := resolveAccount(user)
 			if err != nil {
-				return errors.New("Error: the user's public key is not hex")
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
 			}
 			userB = &b
 		}
 
 		privk, err := fileKey(key)
 		if err != nil {
-			return errors.New("Error client:" + err.Error())
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
 		}
 
-		qresp, _, err := Query(privk, userB)
+		qresp, code, err := Query(privk, userB)
 		if err != nil {
-			return errors.New("Error:" + err.Error())
+			return newCommandError(code, "Error:"+err.Error())
 		}
-		fmt.Println("Coins: ", qresp.Coins)
+		printOutput(qresp, "Coins: ", qresp.Coins)
 		return nil
 	},
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"errors"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/group/edwards25519"
+)
+
+var suite = edwards25519.NewBlakeSHA256Ed25519()
+
+// The protobuf header of an Ed25519 private key and the key type,
+// the key's length follows because the older keys repeat the public key.
+var ed25519PrivateKeyHeader = []byte{0x08, 0x01, 0x12}
+
+func publicKeyPoint(pub []byte) (kyber.Point, error) {
+	if len(pub) != len(ed25519PublicKeyHeader)+32 || !bytes.HasPrefix(pub, ed25519PublicKeyHeader) {
+		return nil, errors.New("The public key is not an Ed25519 key.")
+	}
+	p := suite.Point()
+	err := p.UnmarshalBinary(pub[len(ed25519PublicKeyHeader):])
+	if err != nil {
+		return nil, errors.New("The public key is not a point of the curve.")
+	}
+	return p, nil
+}
+
+// privateKeyScalar returns the scalar of an Ed25519 private key,
+// as Ed25519 derives it from the seed, or the scalar of a one-time key.
+func privateKeyScalar(privk crypto.PrivKey) (kyber.Scalar, error) {
+	if otk, ok := privk.(*oneTimeKey); ok {
+		return otk.x, nil
+	}
+	b, err := privk.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	header := len(ed25519PrivateKeyHeader) + 1
+	if len(b) < header+64 || !bytes.HasPrefix(b, ed25519PrivateKeyHeader) || int(b[header-1]) != len(b)-header {
+		return nil, errors.New("The private key is not an Ed25519 key.")
+	}
+	seed := b[header : header+32]
+	h := sha512.Sum512(seed)
+	h[0] &= 248
+	h[31] &= 127
+	h[31] |= 64
+	return suite.Scalar().SetBytes(h[:32]), nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/urfave/cli"
+)
+
+func EscrowOf(id string) (*EscrowJson, uint32, error) {
+	resp, code, err := queryPath(ESCROW_QUERY_PATH, []byte(id))
+	if err != nil {
+		return nil, code, err
+	}
+	ej := EscrowJson{}
+	err = json.Unmarshal(resp.Value, &ej)
+	if err != nil {
+		return nil, CodeTypeEncodingError, errors.New("The escrow's response is not json.")
+	}
+	return &ej, CodeTypeOK, nil
+}
+
+func OpenEscrow(from crypto.PrivKey, to []byte, taxHash string, coins float64, escrow Escrow) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr := newSendRequest(pubB, to, taxHash, coins)
+	dr.Data.Action = ESCROW_OPEN_ACTION
+	dr.Data.Escrow = &escrow
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+func CloseEscrow(from crypto.PrivKey, action ActionStruct, id string) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr := newDeliveryRequest(pubB, action, 0)
+	dr.Data.Escrow = &Escrow{ID: id}
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+type EscrowResult struct {
+	ID     string
+	Result *DeliveryResult
+}
+
+var escrowOpenCommand = cli.Command{
+	Name: "open",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.StringFlag{
+			Name:  "receiver",
+			Usage: "the receiver's contact, address or public key",
+		},
+		cli.Float64Flag{
+			Name:  "coins",
+			Usage: "the number of coins that the escrow locks",
+		},
+		cli.StringFlag{
+			Name:  "id",
+			Usage: "the escrow's ID, like the order's number, by default a random ID",
+		},
+		cli.StringFlag{
+			Name:  "arbiter",
+			Usage: "the contact, address or public key of the arbiter that can release or refund the escrow",
+		},
+		cli.Int64Flag{
+			Name:  "blocks",
+			Value: 1000,
+			Usage: "the number of blocks until the payer can refund the escrow",
+		},
+	},
+	Usage: "lock coins for the receiver until the payer or the arbiter releases them",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		receiver := c.String("receiver")
+		if len(receiver) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the receiver is missing")
+		}
+		coins := c.Float64("coins")
+		if coins <= 0 {
+			return newCommandError(CodeTypeClientError, "Error: the coins are not allowed to be 0 or less")
+		}
+		if c.Int64("blocks") <= 0 {
+			return newCommandError(CodeTypeClientError, "Error: the blocks should be more than 0")
+		}
+
+		to, err := resolveAccount(receiver)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		escrow := Escrow{ID: c.String("id")}
+		if len(escrow.ID) == 0 {
+			escrow.ID = newID()
+		}
+		if len(c.String("arbiter")) > 0 {
+			arbiter, err := resolveAccount(c.String("arbiter"))
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			escrow.Arbiter = &arbiter
+		}
+		tr, code, err := Tax()
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		st, err := RpcStatus()
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		escrow.Timeout = st.LatestBlockHeight + c.Int64("blocks")
+
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		er := EscrowResult{ID: escrow.ID}
+		er.Result, code, err = OpenEscrow(privk, to, tr.Hash, coins, escrow)
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), er)
+		}
+		printOutput(er, "The escrow "+escrow.ID+" was opened until the height "+strconv.FormatInt(escrow.Timeout, 10))
+		return nil
+	},
+}
+
+func escrowCloseCommand(name string, action ActionStruct, usage string) cli.Command {
+	return cli.Command{
+		Name: name,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "key",
+				Usage: "the filename that contains the key in json file",
+			},
+			cli.StringFlag{
+				Name:  "id",
+				Usage: "the escrow's ID",
+			},
+		},
+		Usage: usage,
+		Action: func(c *cli.Context) error {
+			key := keyFile(c)
+			if len(key) == 0 {
+				return newCommandError(CodeTypeClientError, "Error: the key is missing")
+			}
+			id := c.String("id")
+			if len(id) == 0 {
+				return newCommandError(CodeTypeClientError, "Error: the escrow's ID is missing")
+			}
+			privk, err := fileKey(key)
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			er := EscrowResult{ID: id}
+			var code uint32
+			er.Result, code, err = CloseEscrow(privk, action, id)
+			if err != nil {
+				return newResultError(code, "Error:"+err.Error(), er)
+			}
+			printOutput(er, "The "+name+" was successful")
+			return nil
+		},
+	}
+}
+
+type escrowOutput struct {
+	ID      string
+	Payer   string
+	Payee   string
+	Arbiter string `json:",omitempty"`
+	Coins   float64
+	Timeout int64
+	Height  int64
+	Status  EscrowStatus
+}
+
+var escrowShowCommand = cli.Command{
+	Name: "show",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "id",
+			Usage: "the escrow's ID",
+		},
+	},
+	Usage: "show an escrow",
+	Action: func(c *cli.Context) error {
+		id := c.String("id")
+		if len(id) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the escrow's ID is missing")
+		}
+		ej, code, err := EscrowOf(id)
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		eo := escrowOutput{ID: ej.ID, Payer: showAccount(ej.Payer), Payee: showAccount(ej.Payee),
+			Coins: ej.Coins, Timeout: ej.Timeout, Height: ej.Height, Status: ej.Status}
+		if ej.Arbiter != nil {
+			eo.Arbiter = showAccount(*ej.Arbiter)
+		}
+		text := "Escrow: " + eo.ID + " (" + string(eo.Status) + ")\n" +
+			"Payer: " + eo.Payer + "\nPayee: " + eo.Payee + "\n"
+		if len(eo.Arbiter) > 0 {
+			text += "Arbiter: " + eo.Arbiter + "\n"
+		}
+		text += "Coins: " + strconv.FormatFloat(eo.Coins, 'f', -1, 64) + "\nTimeout: " + strconv.FormatInt(eo.Timeout, 10)
+		printOutput(eo, text)
+		return nil
+	},
+}
+
+var EscrowCommand = cli.Command{
+	Name:  "escrow",
+	Usage: "pay against delivery with coins that are locked until the release",
+	Subcommands: []cli.Command{
+		escrowOpenCommand,
+		escrowCloseCommand("release", ESCROW_RELEASE_ACTION, "pay the escrow's coins to the payee, as the payer or the arbiter"),
+		escrowCloseCommand("refund", ESCROW_REFUND_ACTION, "return the escrow's coins to the payer, as the payee, the arbiter or the payer after the timeout"),
+		escrowShowCommand,
+	},
+}
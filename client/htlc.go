@@ -0,0 +1,290 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/urfave/cli"
+)
+
+func HtlcOf(hashlock []byte) (*HtlcJson, uint32, error) {
+	resp, code, err := queryPath(HTLC_QUERY_PATH, hashlock)
+	if err != nil {
+		return nil, code, err
+	}
+	hj := HtlcJson{}
+	err = json.Unmarshal(resp.Value, &hj)
+	if err != nil {
+		return nil, CodeTypeEncodingError, errors.New("The HTLC's response is not json.")
+	}
+	return &hj, CodeTypeOK, nil
+}
+
+func LockHtlc(from crypto.PrivKey, to []byte, taxHash string, coins float64, htlc Htlc) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr := newSendRequest(pubB, to, taxHash, coins)
+	dr.Data.Action = HTLC_LOCK_ACTION
+	dr.Data.Htlc = &htlc
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+func CloseHtlc(from crypto.PrivKey, action ActionStruct, htlc Htlc) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr := newDeliveryRequest(pubB, action, 0)
+	dr.Data.Htlc = &htlc
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+func decodeHashlock(s string) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != sha256.Size {
+		return nil, errors.New("The hashlock should be a SHA-256 hash in hex.")
+	}
+	return b, nil
+}
+
+type HtlcResult struct {
+	Hashlock string
+	Preimage string `json:",omitempty"`
+	Result   *DeliveryResult
+}
+
+var htlcLockCommand = cli.Command{
+	Name: "lock",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.StringFlag{
+			Name:  "receiver",
+			Usage: "the receiver's contact, address or public key",
+		},
+		cli.Float64Flag{
+			Name:  "coins",
+			Usage: "the number of coins that the HTLC locks",
+		},
+		cli.StringFlag{
+			Name:  "hashlock",
+			Usage: "the hashlock in hex of the other chain's HTLC, by default a new secret is created",
+		},
+		cli.Int64Flag{
+			Name:  "blocks",
+			Value: 1000,
+			Usage: "the number of blocks until the sender can refund the HTLC",
+		},
+	},
+	Usage: "lock coins for the receiver under the hash of a secret",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		receiver := c.String("receiver")
+		if len(receiver) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the receiver is missing")
+		}
+		coins := c.Float64("coins")
+		if coins <= 0 {
+			return newCommandError(CodeTypeClientError, "Error: the coins are not allowed to be 0 or less")
+		}
+		if c.Int64("blocks") <= 0 {
+			return newCommandError(CodeTypeClientError, "Error: the blocks should be more than 0")
+		}
+		to, err := resolveAccount(receiver)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		hr := HtlcResult{}
+		htlc := Htlc{}
+		if len(c.String("hashlock")) > 0 {
+			htlc.Hashlock, err = decodeHashlock(c.String("hashlock"))
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+		} else {
+			preimage := make([]byte, 32)
+			_, err = rand.Read(preimage)
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			h := sha256.Sum256(preimage)
+			htlc.Hashlock = h[:]
+			hr.Preimage = hex.EncodeToString(preimage)
+		}
+		hr.Hashlock = hex.EncodeToString(htlc.Hashlock)
+
+		tr, code, err := Tax()
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		st, err := RpcStatus()
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		htlc.Timeout = st.LatestBlockHeight + c.Int64("blocks")
+
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		hr.Result, code, err = LockHtlc(privk, to, tr.Hash, coins, htlc)
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), hr)
+		}
+		text := "The HTLC " + hr.Hashlock + " was locked until the height " + strconv.FormatInt(htlc.Timeout, 10)
+		if len(hr.Preimage) > 0 {
+			text += "\nKeep the secret until the claim: " + hr.Preimage
+		}
+		printOutput(hr, text)
+		return nil
+	},
+}
+
+var htlcClaimCommand = cli.Command{
+	Name: "claim",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.StringFlag{
+			Name:  "preimage",
+			Usage: "the secret in hex of the hashlock",
+		},
+	},
+	Usage: "reveal the secret and pay the HTLC's coins to its receiver",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		preimage, err := hex.DecodeString(c.String("preimage"))
+		if err != nil || len(preimage) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the preimage should be in hex")
+		}
+		h := sha256.Sum256(preimage)
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		hr := HtlcResult{Hashlock: hex.EncodeToString(h[:])}
+		var code uint32
+		hr.Result, code, err = CloseHtlc(privk, HTLC_CLAIM_ACTION, Htlc{Hashlock: h[:], Preimage: preimage})
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), hr)
+		}
+		printOutput(hr, "The claim was successful")
+		return nil
+	},
+}
+
+var htlcRefundCommand = cli.Command{
+	Name: "refund",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.StringFlag{
+			Name:  "hashlock",
+			Usage: "the HTLC's hashlock in hex",
+		},
+	},
+	Usage: "return the HTLC's coins to the sender after the timeout",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		hashlock, err := decodeHashlock(c.String("hashlock"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		hr := HtlcResult{Hashlock: hex.EncodeToString(hashlock)}
+		var code uint32
+		hr.Result, code, err = CloseHtlc(privk, HTLC_REFUND_ACTION, Htlc{Hashlock: hashlock})
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), hr)
+		}
+		printOutput(hr, "The refund was successful")
+		return nil
+	},
+}
+
+type htlcOutput struct {
+	Hashlock string
+	Sender   string
+	Receiver string
+	Coins    float64
+	Timeout  int64
+	Height   int64
+	Status   HtlcStatus
+	Preimage string `json:",omitempty"`
+}
+
+var htlcShowCommand = cli.Command{
+	Name: "show",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "hashlock",
+			Usage: "the HTLC's hashlock in hex",
+		},
+	},
+	Usage: "show an HTLC, with the secret after the claim",
+	Action: func(c *cli.Context) error {
+		hashlock, err := decodeHashlock(c.String("hashlock"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		hj, code, err := HtlcOf(hashlock)
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		ho := htlcOutput{Hashlock: hex.EncodeToString(hj.Hashlock), Sender: showAccount(hj.Sender), Receiver: showAccount(hj.Receiver),
+			Coins: hj.Coins, Timeout: hj.Timeout, Height: hj.Height, Status: hj.Status, Preimage: hex.EncodeToString(hj.Preimage)}
+		text := "HTLC: " + ho.Hashlock + " (" + string(ho.Status) + ")\n" +
+			"Sender: " + ho.Sender + "\nReceiver: " + ho.Receiver + "\n" +
+			"Coins: " + strconv.FormatFloat(ho.Coins, 'f', -1, 64) + "\nTimeout: " + strconv.FormatInt(ho.Timeout, 10)
+		if len(ho.Preimage) > 0 {
+			text += "\nPreimage: " + ho.Preimage
+		}
+		printOutput(ho, text)
+		return nil
+	},
+}
+
+var HtlcCommand = cli.Command{
+	Name:  "htlc",
+	Usage: "swap coins with other chains with hash time-locked contracts",
+	Subcommands: []cli.Command{
+		htlcLockCommand,
+		htlcClaimCommand,
+		htlcRefundCommand,
+		htlcShowCommand,
+	},
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/urfave/cli"
+)
+
+// signedBytes returns the json of the attestation without the signature.
+func (a Attestation) signedBytes() []byte {
+	a.Signature = nil
+	b, _ := json.Marshal(a)
+	return b
+}
+
+func (a *Attestation) VerifySignature() (bool, error) {
+	pub, err := crypto.UnmarshalPublicKey(a.Attestor)
+	if err != nil {
+		return false, errors.New("The attestor's public key is not correct")
+	}
+	ver, err := pub.Verify(a.signedBytes(), a.Signature)
+	if err != nil {
+		return false, errors.New("The attestation's signature format is not correct.")
+	}
+	return ver, nil
+}
+
+func AttestationOf(account []byte) (*AttestationResponse, uint32, error) {
+	resp, code, err := queryPath(ATTESTATION_QUERY_PATH, account)
+	if err != nil {
+		return nil, code, err
+	}
+	ar := AttestationResponse{}
+	err = json.Unmarshal(resp.Value, &ar)
+	if err != nil {
+		return nil, CodeTypeEncodingError, errors.New("The attestation's response is not json.")
+	}
+	return &ar, CodeTypeOK, nil
+}
+
+// Attest signs the attestation with the attestor's key and sends it, the revocation needs only the account.
+func Attest(from crypto.PrivKey, action ActionStruct, a Attestation) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	if action == ATTEST_ACTION {
+		a.Attestor = pubB
+		a.Signature, err = from.Sign(a.signedBytes())
+		if err != nil {
+			return nil, CodeTypeClientError, err
+		}
+	}
+	dr := newDeliveryRequest(pubB, action, 0)
+	dr.Data.Attestation = &a
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+var kycFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "key",
+		Usage: "the filename that contains the attestor's key in json file",
+	},
+	cli.StringFlag{
+		Name:  "account",
+		Usage: "the contact, address or public key of the account",
+	},
+}
+
+var kycAttestCommand = cli.Command{
+	Name: "attest",
+	Flags: append([]cli.Flag{
+		cli.IntFlag{
+			Name:  "level",
+			Usage: "the level of the identity's verification",
+		},
+		cli.Int64Flag{
+			Name:  "blocks",
+			Usage: "the number of blocks until the attestation expires",
+		},
+		cli.StringFlag{
+			Name:  "document",
+			Usage: "the filename of the identity's document, only its SHA-256 hash goes to the chain",
+		},
+	}, kycFlags...),
+	Usage: "attest the identity of an account, as a KYC attestor",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		if len(c.String("account")) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the account is missing")
+		}
+		if c.Int("level") < 1 {
+			return newCommandError(CodeTypeClientError, "Error: the level should be 1 or more")
+		}
+		if c.Int64("blocks") < 1 {
+			return newCommandError(CodeTypeClientError, "Error: the blocks should be 1 or more")
+		}
+		if len(c.String("document")) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the document is missing")
+		}
+		account, err := resolveAccount(c.String("account"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		doc, err := ioutil.ReadFile(c.String("document"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		hash := sha256.Sum256(doc)
+		st, err := RpcStatus()
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		a := Attestation{Account: account, Level: c.Int("level"), Expiry: st.LatestBlockHeight + c.Int64("blocks"), Document: hash[:]}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		dres, code, err := Attest(privk, ATTEST_ACTION, a)
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), dres)
+		}
+		printOutput(dres, "The account is attested with level "+strconv.Itoa(a.Level)+" until the height "+strconv.FormatInt(a.Expiry, 10))
+		return nil
+	},
+}
+
+var kycRevokeCommand = cli.Command{
+	Name:  "revoke",
+	Flags: kycFlags,
+	Usage: "revoke the attestation of an account, as a KYC attestor",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		if len(c.String("account")) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the account is missing")
+		}
+		account, err := resolveAccount(c.String("account"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		dres, code, err := Attest(privk, ATTEST_REVOKE_ACTION, Attestation{Account: account})
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), dres)
+		}
+		printOutput(dres, "The attestation was revoked")
+		return nil
+	},
+}
+
+type attestationOutput struct {
+	Account  string
+	Attested bool
+	Valid    bool
+	Level    int    `json:",omitempty"`
+	Expiry   int64  `json:",omitempty"`
+	Document string `json:",omitempty"`
+	Attestor string `json:",omitempty"`
+	Verified bool   // the attestor's signature validates the attestation
+}
+
+var kycShowCommand = cli.Command{
+	Name: "show",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "account",
+			Usage: "the contact, address or public key of the account",
+		},
+	},
+	Usage: "show the attestation of an account and verify its signature",
+	Action: func(c *cli.Context) error {
+		if len(c.String("account")) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the account is missing")
+		}
+		account, err := resolveAccount(c.String("account"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		ar, code, err := AttestationOf(account)
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		ao := attestationOutput{Account: showAccount(account)}
+		text := "Account: " + ao.Account
+		if ar.Attestation == nil {
+			printOutput(ao, text+"\nNo attestation")
+			return nil
+		}
+		aj := ar.Attestation
+		ao.Attested = true
+		ao.Valid = ar.Valid
+		ao.Level = aj.Level
+		ao.Expiry = aj.Expiry
+		ao.Document = hex.EncodeToString(aj.Document)
+		ao.Attestor = showAccount(aj.Attestor)
+		ao.Verified, _ = aj.VerifySignature()
+		text += "\nLevel: " + strconv.Itoa(ao.Level) + "\nExpiry: " + strconv.FormatInt(ao.Expiry, 10) +
+			"\nValid: " + strconv.FormatBool(ao.Valid) + "\nDocument: " + ao.Document +
+			"\nAttestor: " + ao.Attestor + "\nVerified: " + strconv.FormatBool(ao.Verified)
+		printOutput(ao, text)
+		return nil
+	},
+}
+
+var KycCommand = cli.Command{
+	Name:  "kyc",
+	Usage: "attest the identity of accounts as a KYC attestor of the server's -attestors flag",
+	Subcommands: []cli.Command{
+		kycAttestCommand,
+		kycRevokeCommand,
+		kycShowCommand,
+	},
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/urfave/cli"
+)
+
+func AllowanceOf(account []byte) (*AllowanceResponse, uint32, error) {
+	resp, code, err := queryPath(ALLOWANCE_QUERY_PATH, account)
+	if err != nil {
+		return nil, code, err
+	}
+	ar := AllowanceResponse{}
+	err = json.Unmarshal(resp.Value, &ar)
+	if err != nil {
+		return nil, CodeTypeEncodingError, errors.New("The allowance's response is not json.")
+	}
+	return &ar, CodeTypeOK, nil
+}
+
+func SetTier(from crypto.PrivKey, action ActionStruct, tier Tier) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr := newDeliveryRequest(pubB, action, 0)
+	dr.Data.Tier = &tier
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+func formatLimit(limit float64) string {
+	if limit <= 0 {
+		return "none"
+	}
+	return strconv.FormatFloat(limit, 'f', -1, 64)
+}
+
+var limitsTierCommand = cli.Command{
+	Name: "tier",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the authority's key in json file",
+		},
+		cli.StringFlag{
+			Name:  "name",
+			Usage: "the tier's name, the default tier limits the accounts without a tier",
+		},
+		cli.Float64Flag{
+			Name:  "max-send",
+			Usage: "the maximum coins of a send, 0 for no limit",
+		},
+		cli.Float64Flag{
+			Name:  "max-window",
+			Usage: "the maximum coins that are sent in the window, 0 for no limit",
+		},
+		cli.Int64Flag{
+			Name:  "window",
+			Usage: "the number of the window's blocks",
+		},
+	},
+	Usage: "set the limits of a tier, as a compliance authority",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		tier := Tier{Name: c.String("name"), MaxSend: c.Float64("max-send"), MaxWindow: c.Float64("max-window"), Window: c.Int64("window")}
+		if len(tier.Name) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the tier's name is missing")
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		dres, code, err := SetTier(privk, SET_TIER_ACTION, tier)
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), dres)
+		}
+		printOutput(dres, "The limits of the tier "+tier.Name+" were set")
+		return nil
+	},
+}
+
+var limitsAssignCommand = cli.Command{
+	Name: "assign",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the authority's key in json file",
+		},
+		cli.StringFlag{
+			Name:  "account",
+			Usage: "the contact, address or public key of the account",
+		},
+		cli.StringFlag{
+			Name:  "tier",
+			Usage: "the tier's name",
+		},
+	},
+	Usage: "assign an account to a tier, as a compliance authority",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		if len(c.String("account")) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the account is missing")
+		}
+		if len(c.String("tier")) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the tier's name is missing")
+		}
+		account, err := resolveAccount(c.String("account"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		dres, code, err := SetTier(privk, ASSIGN_TIER_ACTION, Tier{Name: c.String("tier"), Account: &account})
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), dres)
+		}
+		printOutput(dres, "The account is in the tier "+c.String("tier"))
+		return nil
+	},
+}
+
+var limitsAllowanceCommand = cli.Command{
+	Name: "allowance",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file, when there is no account",
+		},
+		cli.StringFlag{
+			Name:  "account",
+			Usage: "the contact, address or public key of the account",
+		},
+	},
+	Usage: "show the limits of an account and the coins that it can send in the window",
+	Action: func(c *cli.Context) error {
+		var account []byte
+		var err error
+		if len(c.String("account")) > 0 {
+			account, err = resolveAccount(c.String("account"))
+		} else if key := keyFile(c); len(key) > 0 {
+			account, err = filePublicKey(key)
+		} else {
+			return newCommandError(CodeTypeClientError, "Error: the key or the account is missing")
+		}
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		ar, code, err := AllowanceOf(account)
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		text := "Tier: " + ar.Tier + "\nMax send: " + formatLimit(ar.MaxSend) + "\n" +
+			"Max in " + strconv.FormatInt(ar.Window, 10) + " blocks: " + formatLimit(ar.MaxWindow) + "\n" +
+			"Sent: " + strconv.FormatFloat(ar.Sent, 'f', -1, 64) + "\nRemaining: "
+		if ar.Remaining < 0 {
+			text += "no limit"
+		} else {
+			text += strconv.FormatFloat(ar.Remaining, 'f', -1, 64)
+		}
+		printOutput(ar, text)
+		return nil
+	},
+}
+
+var LimitsCommand = cli.Command{
+	Name:  "limits",
+	Usage: "the limits of the sends per tier of accounts",
+	Subcommands: []cli.Command{
+		limitsTierCommand,
+		limitsAssignCommand,
+		limitsAllowanceCommand,
+	},
+}
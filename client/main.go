@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/urfave/cli"
@@ -9,16 +8,42 @@ import (
 
 func main() {
 	app := cli.NewApp()
+	app.Flags = []cli.Flag{
+		OutputFlag,
+		ConfigFlag,
+		ProfileFlag,
+		NodeFlag,
+	}
+	app.Before = submitConfiguration
 	app.Commands = []cli.Command{
 		GenerateKeyCommand,
 		AddCommand,
 		RemoveCommand,
 		SendCommand,
 		QueryCommand,
+		TxCommand,
+		ConfigCommand,
+		AddressCommand,
+		ContactsCommand,
+		ConfidentialCommand,
+		ViewCommand,
+		HistoryCommand,
+		AuditCommand,
+		StealthCommand,
+		EscrowCommand,
+		HtlcCommand,
+		StandingCommand,
+		MultisigCommand,
+		ComplianceCommand,
+		LimitsCommand,
+		KycCommand,
+		StatementCommand,
+		TaxReportCommand,
+		SearchCommand,
+		WatchCommand,
 	}
 	err := app.Run(os.Args)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(printError(err))
 	}
 }
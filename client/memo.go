@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"go.dedis.ch/kyber/v3"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const (
+	MEMO_MAX_SIZE    = 256 // bytes of the text
+	MEMO_MAX_READERS = 3
+)
+
+// the prime of curve25519's field
+var curve25519P, _ = new(big.Int).SetString("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed", 16)
+
+// montgomeryU returns the X25519 coordinate of an Ed25519 point, u = (1 + y) / (1 - y).
+func montgomeryU(p kyber.Point) []byte {
+	b, _ := p.MarshalBinary()
+	b[31] &= 0x7f
+	y := new(big.Int).SetBytes(reverse(b))
+	den := new(big.Int).Sub(big.NewInt(1), y)
+	den.Mod(den, curve25519P)
+	u := new(big.Int)
+	if den.Sign() != 0 {
+		u.Add(big.NewInt(1), y)
+		u.Mul(u, den.ModInverse(den, curve25519P))
+		u.Mod(u, curve25519P)
+	}
+	out := make([]byte, 32)
+	ub := u.Bytes()
+	copy(out[32-len(ub):], ub)
+	return reverse(out)
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+// memoKey is the key that seals the memo's key for a reader, from the X25519 secret of the Ed25519 keys.
+// The product is computed on the Ed25519 points, so the one-time keys without seed can read too.
+func memoKey(ephemeral []byte, x kyber.Scalar, p kyber.Point) *[32]byte {
+	h := sha256.New()
+	h.Write([]byte("theftcoin-memo"))
+	h.Write(ephemeral)
+	h.Write(montgomeryU(suite.Point().Mul(x, p)))
+	var key [32]byte
+	copy(key[:], h.Sum(nil))
+	return &key
+}
+
+func sealBox(msg []byte, key *[32]byte) ([]byte, error) {
+	var nonce [24]byte
+	_, err := rand.Read(nonce[:])
+	if err != nil {
+		return nil, err
+	}
+	return secretbox.Seal(append([]byte{}, nonce[:]...), msg, &nonce, key), nil
+}
+
+func openBox(box []byte, key *[32]byte) ([]byte, bool) {
+	if len(box) < 24+secretbox.Overhead {
+		return nil, false
+	}
+	var nonce [24]byte
+	copy(nonce[:], box[:24])
+	return secretbox.Open(nil, box[24:], &nonce, key)
+}
+
+// newMemo returns the text's memo, encrypted for the readers' public keys if there are readers.
+func newMemo(text string, readers [][]byte) (*Memo, error) {
+	if len(text) == 0 {
+		return nil, errors.New("The memo is empty.")
+	}
+	if len(text) > MEMO_MAX_SIZE {
+		return nil, errors.New("The memo can not be more than " + strconv.Itoa(MEMO_MAX_SIZE) + " bytes.")
+	}
+	if len(readers) == 0 {
+		return &Memo{Text: text}, nil
+	}
+	if len(readers) > MEMO_MAX_READERS {
+		return nil, errors.New("The memo can not have more than " + strconv.Itoa(MEMO_MAX_READERS) + " readers.")
+	}
+
+	var key [32]byte
+	_, err := rand.Read(key[:])
+	if err != nil {
+		return nil, err
+	}
+	r := randomScalar()
+	memo := &Memo{}
+	memo.Ephemeral, _ = suite.Point().Mul(r, nil).MarshalBinary()
+	for _, reader := range readers {
+		p, err := publicKeyPoint(reader)
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := sealBox(key[:], memoKey(memo.Ephemeral, r, p))
+		if err != nil {
+			return nil, err
+		}
+		memo.Keys = append(memo.Keys, sealed)
+	}
+	memo.Box, err = sealBox([]byte(text), &key)
+	if err != nil {
+		return nil, err
+	}
+	return memo, nil
+}
+
+// openMemo returns the memo's text if the key is one of the readers.
+func openMemo(privk crypto.PrivKey, memo *Memo) (string, error) {
+	if len(memo.Box) == 0 {
+		return memo.Text, nil
+	}
+	x, err := privateKeyScalar(privk)
+	if err != nil {
+		return "", err
+	}
+	ephemeral := suite.Point()
+	err = ephemeral.UnmarshalBinary(memo.Ephemeral)
+	if err != nil {
+		return "", errors.New("The memo's ephemeral key is not correct.")
+	}
+	kek := memoKey(memo.Ephemeral, x, ephemeral)
+	for _, sealed := range memo.Keys {
+		b, ok := openBox(sealed, kek)
+		if !ok || len(b) != 32 {
+			continue
+		}
+		var key [32]byte
+		copy(key[:], b)
+		text, ok := openBox(memo.Box, &key)
+		if !ok {
+			return "", errors.New("The memo is not correct.")
+		}
+		return string(text), nil
+	}
+	return "", errors.New("The memo is not for this key.")
+}
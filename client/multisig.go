@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"time"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/urfave/cli"
+)
+
+// Account returns the key of the multisig account, the same as the server derives from the threshold and the members.
+func (m *Multisig) Account() []byte {
+	h := sha256.New()
+	h.Write([]byte("theftcoin-multisig"))
+	n := make([]byte, 4)
+	binary.BigEndian.PutUint32(n, uint32(m.Threshold))
+	h.Write(n)
+	for _, member := range m.Members {
+		binary.BigEndian.PutUint32(n, uint32(len(member)))
+		h.Write(n)
+		h.Write(member)
+	}
+	return append(append([]byte{}, ed25519PublicKeyHeader...), h.Sum(nil)...)
+}
+
+// newMultisig sorts the members, so the same members have one account.
+func newMultisig(threshold int, members [][]byte) (*Multisig, error) {
+	if threshold <= 0 || threshold > len(members) {
+		return nil, errors.New("The threshold should be from 1 to the number of the members.")
+	}
+	sorted := append([][]byte{}, members...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+	for i := 1; i < len(sorted); i++ {
+		if bytes.Equal(sorted[i-1], sorted[i]) {
+			return nil, errors.New("The members should be different.")
+		}
+	}
+	return &Multisig{Threshold: threshold, Members: sorted}, nil
+}
+
+func readMultisig(filename string) (*Multisig, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	m := Multisig{}
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, errors.New("The multisig's file is not json.")
+	}
+	return &m, nil
+}
+
+func writeMultisig(filename string, m *Multisig) error {
+	b, _ := json.MarshalIndent(m, "", "  ")
+	return ioutil.WriteFile(filename, b, 0600)
+}
+
+// verify checks that the multisig is the account's and that at least the threshold of different members signed the bytes.
+func (m *Multisig) verify(account []byte, b []byte, sigs []MultisigSignature, threshold int) (bool, error) {
+	if !bytes.Equal(m.Account(), account) {
+		return false, errors.New("The multisig is not the sender's account.")
+	}
+	signed := map[string]bool{}
+	for _, sig := range sigs {
+		if !m.isMember(sig.Member) || signed[string(sig.Member)] {
+			return false, errors.New("The signatures should be of different members of the multisig.")
+		}
+		pub, err := crypto.UnmarshalPublicKey(sig.Member)
+		if err != nil {
+			return false, errors.New("The member's public key is not correct.")
+		}
+		ver, err := pub.Verify(b, sig.Signature)
+		if err != nil {
+			return false, errors.New("The signature's format is not correct.")
+		}
+		if !ver {
+			return false, nil
+		}
+		signed[string(sig.Member)] = true
+	}
+	return len(signed) >= threshold, nil
+}
+
+func (m *Multisig) isMember(pub []byte) bool {
+	for _, member := range m.Members {
+		if bytes.Equal(member, pub) {
+			return true
+		}
+	}
+	return false
+}
+
+// multisigSign adds the member's signature of the bytes, the signature replaces an older one of the member.
+func multisigSign(privk crypto.PrivKey, m *Multisig, b []byte, sigs []MultisigSignature) ([]MultisigSignature, error) {
+	pubB, err := privk.GetPublic().Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if !m.isMember(pubB) {
+		return nil, errors.New("The key is not a member of the multisig.")
+	}
+	sig, err := privk.Sign(b)
+	if err != nil {
+		return nil, err
+	}
+	signed := []MultisigSignature{}
+	for _, s := range sigs {
+		if !bytes.Equal(s.Member, pubB) {
+			signed = append(signed, s)
+		}
+	}
+	return append(signed, MultisigSignature{Member: pubB, Signature: sig}), nil
+}
+
+// SignMultisigTransaction adds the member's partial signature to the transaction.
+func SignMultisigTransaction(privk crypto.PrivKey, dr *DeliveryRequest) error {
+	if !bytes.Equal(dr.Multisig.Account(), dr.Data.From) {
+		return errors.New("The multisig is not the sender of the transaction.")
+	}
+	b, _ := json.Marshal(dr.Data)
+	sigs, err := multisigSign(privk, dr.Multisig, b, dr.Signatures)
+	if err != nil {
+		return err
+	}
+	dr.Signatures = sigs
+	dr.Date = time.Now().UTC()
+	return nil
+}
+
+// QueryMultisig reads the balance of the multisig account with the member's signature.
+func QueryMultisig(privk crypto.PrivKey, m *Multisig) (*QueryResponse, uint32, error) {
+	qr := QueryRequest{Multisig: m}
+	qr.Data.From = m.Account()
+	qr.Data.Date = time.Now().UTC()
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	qr.Data.Nonce = hex.EncodeToString(nonce)
+	b, _ := json.Marshal(qr.Data)
+	qr.Signatures, err = multisigSign(privk, m, b, nil)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	b, _ = json.Marshal(qr)
+	return query(b)
+}
+
+type multisigOutput struct {
+	Address   string
+	Threshold int
+	Members   []string
+	Filename  string `json:",omitempty"`
+}
+
+func newMultisigOutput(m *Multisig) multisigOutput {
+	mo := multisigOutput{Threshold: m.Threshold}
+	mo.Address, _ = Address(m.Account())
+	for _, member := range m.Members {
+		mo.Members = append(mo.Members, showAccount(member))
+	}
+	return mo
+}
+
+var multisigCreateCommand = cli.Command{
+	Name: "create",
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "threshold",
+			Usage: "the number of the members that sign a transaction",
+		},
+		cli.StringSliceFlag{
+			Name:  "member",
+			Usage: "a contact, address or public key of a member",
+		},
+		cli.StringFlag{
+			Name:  "out",
+			Usage: "the filename that the multisig is saved in json file, the members share it",
+		},
+	},
+	Usage: "create a multisig account of the members' keys, its address is derived from them",
+	Action: func(c *cli.Context) error {
+		out := c.String("out")
+		if len(out) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the filename of the multisig is missing")
+		}
+		members := [][]byte{}
+		for _, account := range c.StringSlice("member") {
+			pub, err := resolveAccount(account)
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			members = append(members, pub)
+		}
+		m, err := newMultisig(c.Int("threshold"), members)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		err = writeMultisig(out, m)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		mo := newMultisigOutput(m)
+		mo.Filename = out
+		printOutput(mo, "The multisig account "+mo.Address+" needs "+strconv.Itoa(m.Threshold)+" of "+strconv.Itoa(len(m.Members))+" signatures")
+		return nil
+	},
+}
+
+var multisigShowCommand = cli.Command{
+	Name: "show",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "multisig",
+			Usage: "the filename that contains the multisig in json file",
+		},
+	},
+	Usage: "show the address and the members of a multisig account",
+	Action: func(c *cli.Context) error {
+		m, err := readMultisig(c.String("multisig"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		mo := newMultisigOutput(m)
+		text := "Address: " + mo.Address + "\nThreshold: " + strconv.Itoa(mo.Threshold)
+		for _, member := range mo.Members {
+			text += "\nMember: " + member
+		}
+		printOutput(mo, text)
+		return nil
+	},
+}
+
+var multisigBalanceCommand = cli.Command{
+	Name: "balance",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the member's key in json file",
+		},
+		cli.StringFlag{
+			Name:  "multisig",
+			Usage: "the filename that contains the multisig in json file",
+		},
+	},
+	Usage: "show the coins of the multisig account, a member's signature is enough",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		m, err := readMultisig(c.String("multisig"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		qr, code, err := QueryMultisig(privk, m)
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		printOutput(qr, "Coins: "+strconv.FormatFloat(qr.Coins, 'f', -1, 64))
+		return nil
+	},
+}
+
+var MultisigCommand = cli.Command{
+	Name:  "multisig",
+	Usage: "share an account that needs the signatures of a threshold of its members",
+	Subcommands: []cli.Command{
+		multisigCreateCommand,
+		multisigShowCommand,
+		multisigBalanceCommand,
+	},
+}
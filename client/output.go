@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/urfave/cli"
+)
+
+const (
+	OUTPUT_TEXT = "text"
+	OUTPUT_JSON = "json"
+)
+
+// The exit codes of the process for every code type,
+// any other error exits with 1.
+var exitCodes = map[uint32]int{
+	CodeTypeEncodingError: 11,
+	CodeTypeBadNonce:      12,
+	CodeTypeUnauthorized:  13,
+	CodeTypeClientError:   14,
+}
+
+var OutputFlag = cli.StringFlag{
+	Name:  "output, o",
+	Usage: "the output format of the commands: text or json, by default text",
+}
+
+type CommandError struct {
+	Code    uint32
+	Message string
+	Result  interface{} `json:"-"`
+}
+
+func (ce *CommandError) Error() string {
+	return ce.Message
+}
+
+func newCommandError(code uint32, message string) error {
+	return &CommandError{Code: code, Message: message}
+}
+
+// newResultError keeps the result of the node next to the error,
+// so the hash and the height are shown even if the transaction failed.
+func newResultError(code uint32, message string, result interface{}) error {
+	return &CommandError{Code: code, Message: message, Result: result}
+}
+
+type errorOutput struct {
+	Error  *CommandError
+	Result interface{} `json:",omitempty"`
+}
+
+func validOutput(output string) bool {
+	return output == OUTPUT_TEXT || output == OUTPUT_JSON
+}
+
+func printJson(v interface{}) {
+	b, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(b))
+}
+
+// printOutput prints the text for people or the value for scripts.
+func printOutput(v interface{}, text ...interface{}) {
+	if Conf.Output == OUTPUT_JSON {
+		printJson(v)
+		return
+	}
+	fmt.Println(text...)
+}
+
+// printText prints only in the text output, for information that is part of the value in json.
+func printText(text ...interface{}) {
+	if Conf.Output == OUTPUT_JSON {
+		return
+	}
+	fmt.Println(text...)
+}
+
+func printError(err error) int {
+	ce, ok := err.(*CommandError)
+	if !ok {
+		if Conf.Output == OUTPUT_JSON {
+			printJson(errorOutput{Error: &CommandError{Code: CodeTypeClientError, Message: err.Error()}})
+		} else {
+			fmt.Println(err)
+		}
+		return 1
+	}
+	if Conf.Output == OUTPUT_JSON {
+		printJson(errorOutput{Error: ce, Result: ce.Result})
+	} else {
+		fmt.Println(ce.Message)
+	}
+	exitCode, ok := exitCodes[ce.Code]
+	if !ok {
+		return 1
+	}
+	return exitCode
+}
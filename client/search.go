@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// The tags of the delivered transactions, like the server's.
+const (
+	TAG_ACTION       = "action"
+	TAG_SIGNER       = "signer"
+	TAG_FROM         = "from"
+	TAG_TO           = "to"
+	TAG_AMOUNT       = "amount"
+	TAG_TAX          = "tax"
+	TAG_TAX_RECEIVER = "tax_receiver"
+)
+
+type searchOutput struct {
+	Height int64
+	Hash   string
+	Action string
+	From   string
+	To     string `json:",omitempty"`
+	Amount string `json:",omitempty"`
+	Tax    string `json:",omitempty"`
+}
+
+func txTag(rt resultTx, key string) string {
+	for _, tag := range rt.TxResult.Tags {
+		if string(tag.Key) == key {
+			return string(tag.Value)
+		}
+	}
+	return ""
+}
+
+// showTagAccount shows the account of the tag's public key in hex.
+func showTagAccount(s string) string {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return s
+	}
+	return showAccount(b)
+}
+
+// tagSearch is the conditions of a search, the node's tx_search finds the transactions
+// and the standing orders' payments are matched in the client.
+type tagSearch struct {
+	tags      [][2]string // key and value
+	minAmount float64
+	maxAmount float64
+}
+
+// addAccount adds the condition on the tag's account, with the contact, address or public key of the account.
+func (ts *tagSearch) addAccount(key string, account string) error {
+	b, err := resolveAccount(account)
+	if err != nil {
+		return err
+	}
+	ts.tags = append(ts.tags, [2]string{key, hex.EncodeToString(b)})
+	return nil
+}
+
+func (ts tagSearch) empty() bool {
+	return len(ts.tags) == 0 && ts.minAmount <= 0 && ts.maxAmount <= 0
+}
+
+func (ts tagSearch) query() string {
+	conditions := []string{}
+	for _, tag := range ts.tags {
+		conditions = append(conditions, tag[0]+"='"+tag[1]+"'")
+	}
+	if ts.minAmount > 0 {
+		conditions = append(conditions, TAG_AMOUNT+">="+formatCoins(ts.minAmount))
+	}
+	if ts.maxAmount > 0 {
+		conditions = append(conditions, TAG_AMOUNT+"<="+formatCoins(ts.maxAmount))
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+func (ts tagSearch) match(rt resultTx) bool {
+	for _, tag := range ts.tags {
+		if txTag(rt, tag[0]) != tag[1] {
+			return false
+		}
+	}
+	if ts.minAmount > 0 || ts.maxAmount > 0 {
+		amount, err := strconv.ParseFloat(txTag(rt, TAG_AMOUNT), 64)
+		if err != nil {
+			return false
+		}
+		if ts.minAmount > 0 && amount < ts.minAmount {
+			return false
+		}
+		if ts.maxAmount > 0 && amount > ts.maxAmount {
+			return false
+		}
+	}
+	return true
+}
+
+// isPayment returns if the result is a standing order's payment, the payments have no transaction.
+func isPayment(rt resultTx) bool {
+	return len(rt.Tx) == 0
+}
+
+// sortResults orders the results by their heights, the payments of the standing orders are at the end of their block.
+func sortResults(results []resultTx) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Height != results[j].Height {
+			return results[i].Height < results[j].Height
+		}
+		if isPayment(results[i]) != isPayment(results[j]) {
+			return !isPayment(results[i])
+		}
+		return results[i].Index < results[j].Index
+	})
+}
+
+// searchResults returns the transactions and the standing orders' payments of the search.
+func searchResults(ts tagSearch) ([]resultTx, error) {
+	results, err := RpcTxSearch(ts.query())
+	if err != nil {
+		return nil, err
+	}
+	payments, err := StandingOrderPayments(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, rt := range payments {
+		if ts.match(rt) {
+			results = append(results, rt)
+		}
+	}
+	sortResults(results)
+	return results, nil
+}
+
+var SearchCommand = cli.Command{
+	Name: "search",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "action",
+			Usage: "the action of the transactions, like send",
+		},
+		cli.StringFlag{
+			Name:  "signer",
+			Usage: "the contact, address or public key of the transaction's signer",
+		},
+		cli.StringFlag{
+			Name:  "from",
+			Usage: "the contact, address or public key of the account that paid the coins",
+		},
+		cli.StringFlag{
+			Name:  "to",
+			Usage: "the contact, address or public key of the receiver",
+		},
+		cli.StringFlag{
+			Name:  "tax-receiver",
+			Usage: "the contact, address or public key of the tax receiver",
+		},
+		cli.Float64Flag{
+			Name:  "min-amount",
+			Usage: "the smallest amount of the transactions",
+		},
+		cli.Float64Flag{
+			Name:  "max-amount",
+			Usage: "the largest amount of the transactions",
+		},
+	},
+	Usage: "search the delivered transactions and the standing orders' payments by their tags",
+	Action: func(c *cli.Context) error {
+		ts := tagSearch{minAmount: c.Float64("min-amount"), maxAmount: c.Float64("max-amount")}
+		if len(c.String("action")) > 0 {
+			ts.tags = append(ts.tags, [2]string{TAG_ACTION, c.String("action")})
+		}
+		for _, flag := range []struct{ name, key string }{{"signer", TAG_SIGNER}, {"from", TAG_FROM}, {"to", TAG_TO}, {"tax-receiver", TAG_TAX_RECEIVER}} {
+			if len(c.String(flag.name)) == 0 {
+				continue
+			}
+			err := ts.addAccount(flag.key, c.String(flag.name))
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+		}
+		if ts.empty() {
+			return newCommandError(CodeTypeClientError, "Error: the search needs at least one of the tags")
+		}
+
+		results, err := searchResults(ts)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		list := []searchOutput{}
+		lines := []string{}
+		for _, rt := range results {
+			so := searchOutput{
+				Height: rt.Height,
+				Hash:   strings.ToUpper(rt.Hash),
+				Action: txTag(rt, TAG_ACTION),
+				From:   showTagAccount(txTag(rt, TAG_FROM)),
+				To:     showTagAccount(txTag(rt, TAG_TO)),
+				Amount: txTag(rt, TAG_AMOUNT),
+				Tax:    txTag(rt, TAG_TAX),
+			}
+			list = append(list, so)
+			line := strconv.FormatInt(so.Height, 10) + "\t" + so.Hash + "\t" + so.Action + "\t" + so.Amount + "\t" + so.From
+			if len(so.To) > 0 {
+				line += " -> " + so.To
+			}
+			lines = append(lines, line)
+		}
+		printOutput(list, strings.Join(lines, "\n"))
+		return nil
+	},
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/tendermint/abci/types"
+	"github.com/urfave/cli"
+)
+
+func StandingOrderOf(id string) (*StandingOrderJson, uint32, error) {
+	resp, code, err := queryPath(STANDING_ORDER_QUERY_PATH, []byte(id))
+	if err != nil {
+		return nil, code, err
+	}
+	so := StandingOrderJson{}
+	err = json.Unmarshal(resp.Value, &so)
+	if err != nil {
+		return nil, CodeTypeEncodingError, errors.New("The standing order's response is not json.")
+	}
+	return &so, CodeTypeOK, nil
+}
+
+// StandingOrderPaymentsOf returns the payments of the standing orders in the range of heights with their tags.
+func StandingOrderPaymentsOf(fromHeight, toHeight int64) ([]StandingOrderPayment, error) {
+	b, _ := json.Marshal(StandingOrderPaymentsRequest{FromHeight: fromHeight, ToHeight: toHeight})
+	resp, _, err := queryPath(STANDING_ORDER_PAYMENTS_QUERY_PATH, b)
+	if err != nil {
+		return nil, err
+	}
+	spr := StandingOrderPaymentsResponse{}
+	err = json.Unmarshal(resp.Value, &spr)
+	if err != nil {
+		return nil, errors.New("The standing orders' payments are not json.")
+	}
+	return spr.Payments, nil
+}
+
+// StandingOrderPayments returns the payments of the standing orders in the range of heights as the results of transactions.
+// The payments are not transactions, so their hash is made of their height and their index.
+func StandingOrderPayments(fromHeight, toHeight int64) ([]resultTx, error) {
+	payments, err := StandingOrderPaymentsOf(fromHeight, toHeight)
+	if err != nil {
+		return nil, err
+	}
+	results := []resultTx{}
+	for _, sp := range payments {
+		results = append(results, resultTx{
+			Hash:     "SO-" + strconv.FormatInt(sp.Height, 10) + "-" + strconv.Itoa(sp.Index),
+			Height:   sp.Height,
+			Index:    uint32(sp.Index),
+			TxResult: types.ResponseDeliverTx{Code: CodeTypeOK, Tags: sp.Tags},
+		})
+	}
+	return results, nil
+}
+
+func CreateStandingOrder(from crypto.PrivKey, to []byte, taxHash string, coins float64, so StandingOrder) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr := newSendRequest(pubB, to, taxHash, coins)
+	dr.Data.Action = STANDING_ORDER_ACTION
+	dr.Data.StandingOrder = &so
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+func CancelStandingOrder(from crypto.PrivKey, id string) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr := newDeliveryRequest(pubB, STANDING_ORDER_CANCEL_ACTION, 0)
+	dr.Data.StandingOrder = &StandingOrder{ID: id}
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+type StandingOrderResult struct {
+	ID     string
+	Result *DeliveryResult
+}
+
+var standingCreateCommand = cli.Command{
+	Name: "create",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.StringFlag{
+			Name:  "receiver",
+			Usage: "the receiver's contact, address or public key",
+		},
+		cli.Float64Flag{
+			Name:  "coins",
+			Usage: "the number of coins of every payment",
+		},
+		cli.StringFlag{
+			Name:  "id",
+			Usage: "the standing order's ID, by default a random ID",
+		},
+		cli.Int64Flag{
+			Name:  "interval",
+			Usage: "the number of blocks between the payments",
+		},
+		cli.Int64Flag{
+			Name:  "start",
+			Usage: "the height of the first payment, by default the block after the next",
+		},
+		cli.Int64Flag{
+			Name:  "payments",
+			Value: 12,
+			Usage: "the number of payments until the end of the standing order",
+		},
+	},
+	Usage: "pay the receiver on every interval of blocks, the chain executes the payments",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		receiver := c.String("receiver")
+		if len(receiver) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the receiver is missing")
+		}
+		coins := c.Float64("coins")
+		if coins <= 0 {
+			return newCommandError(CodeTypeClientError, "Error: the coins are not allowed to be 0 or less")
+		}
+		if c.Int64("interval") <= 0 {
+			return newCommandError(CodeTypeClientError, "Error: the interval should be more than 0")
+		}
+		if c.Int64("payments") <= 0 {
+			return newCommandError(CodeTypeClientError, "Error: the payments should be more than 0")
+		}
+
+		to, err := resolveAccount(receiver)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		so := StandingOrder{ID: c.String("id"), Interval: c.Int64("interval"), Start: c.Int64("start")}
+		if len(so.ID) == 0 {
+			so.ID = newID()
+		}
+		tr, code, err := Tax()
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		if so.Start == 0 {
+			st, err := RpcStatus()
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			// the order is delivered in the next block, so it starts after it
+			so.Start = st.LatestBlockHeight + 2
+		}
+		so.End = so.Start + (c.Int64("payments")-1)*so.Interval
+
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		sr := StandingOrderResult{ID: so.ID}
+		sr.Result, code, err = CreateStandingOrder(privk, to, tr.Hash, coins, so)
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), sr)
+		}
+		printOutput(sr, "The standing order "+so.ID+" pays until the height "+strconv.FormatInt(so.End, 10))
+		return nil
+	},
+}
+
+var standingCancelCommand = cli.Command{
+	Name: "cancel",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.StringFlag{
+			Name:  "id",
+			Usage: "the standing order's ID",
+		},
+	},
+	Usage: "stop the payments of the standing order, as its owner",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		id := c.String("id")
+		if len(id) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the standing order's ID is missing")
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		sr := StandingOrderResult{ID: id}
+		var code uint32
+		sr.Result, code, err = CancelStandingOrder(privk, id)
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), sr)
+		}
+		printOutput(sr, "The standing order was cancelled")
+		return nil
+	},
+}
+
+type standingOrderOutput struct {
+	ID       string
+	Owner    string
+	Receiver string
+	Coins    float64
+	Interval int64
+	Next     int64
+	End      int64
+	Status   StandingOrderStatus
+	Payments int
+	Failures []StandingOrderFailure
+}
+
+var standingShowCommand = cli.Command{
+	Name: "show",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "id",
+			Usage: "the standing order's ID",
+		},
+	},
+	Usage: "show a standing order with its last failed payments",
+	Action: func(c *cli.Context) error {
+		id := c.String("id")
+		if len(id) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the standing order's ID is missing")
+		}
+		so, code, err := StandingOrderOf(id)
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		out := standingOrderOutput{ID: so.ID, Owner: showAccount(so.Owner), Receiver: showAccount(so.Receiver), Coins: so.Coins,
+			Interval: so.Interval, Next: so.Next, End: so.End, Status: so.Status, Payments: so.Payments, Failures: so.Failures}
+		text := "Standing order: " + out.ID + " (" + string(out.Status) + ")\n" +
+			"Owner: " + out.Owner + "\nReceiver: " + out.Receiver + "\n" +
+			"Coins: " + strconv.FormatFloat(out.Coins, 'f', -1, 64) + " every " + strconv.FormatInt(out.Interval, 10) + " blocks\n" +
+			"Next: " + strconv.FormatInt(out.Next, 10) + "\nEnd: " + strconv.FormatInt(out.End, 10) + "\n" +
+			"Payments: " + strconv.Itoa(out.Payments)
+		for _, f := range out.Failures {
+			text += "\nFailed at " + strconv.FormatInt(f.Height, 10) + ": " + f.Reason
+		}
+		printOutput(out, text)
+		return nil
+	},
+}
+
+var StandingCommand = cli.Command{
+	Name:  "standing",
+	Usage: "schedule recurring payments that the chain executes",
+	Subcommands: []cli.Command{
+		standingCreateCommand,
+		standingCancelCommand,
+		standingShowCommand,
+	},
+}
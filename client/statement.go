@@ -0,0 +1,661 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/tendermint/abci/types"
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ripemd160"
+)
+
+const (
+	STATEMENT_JSON = "json"
+	STATEMENT_CSV  = "csv"
+)
+
+// StatementEntry is a payment of the account in the statement, with the hash and the height of its transaction.
+// The payments of the standing orders are in the blocks without a transaction, so they have the order's ID.
+type StatementEntry struct {
+	Height        int64
+	Time          time.Time
+	Hash          string `json:",omitempty"`
+	StandingOrder string `json:",omitempty"`
+	Action        ActionStruct
+	Counterparty  string `json:",omitempty"` // public key in hex
+	Sent          float64
+	Received      float64
+	TaxPaid       float64
+	TaxReceived   float64
+}
+
+type StatementTotals struct {
+	Sent        float64
+	Received    float64
+	TaxPaid     float64
+	TaxReceived float64
+}
+
+// Statement is the account's payments in a range of heights or dates, signed by the key that read the history.
+type Statement struct {
+	Account    string // public key in hex
+	Chain      string
+	FromHeight int64      `json:",omitempty"`
+	ToHeight   int64      `json:",omitempty"`
+	FromTime   *time.Time `json:",omitempty"`
+	ToTime     *time.Time `json:",omitempty"` // excluded
+	Generated  time.Time
+	Totals     StatementTotals
+	Entries    []StatementEntry
+	Signer     string // public key in hex
+	Signature  string `json:",omitempty"`
+}
+
+type statementRange struct {
+	FromHeight int64
+	ToHeight   int64
+	FromTime   *time.Time
+	ToTime     *time.Time
+}
+
+func (sr statementRange) contains(entry HistoryEntry) bool {
+	if sr.FromHeight > 0 && entry.Height < sr.FromHeight {
+		return false
+	}
+	if sr.ToHeight > 0 && entry.Height > sr.ToHeight {
+		return false
+	}
+	if sr.FromTime != nil && entry.Time.Before(*sr.FromTime) {
+		return false
+	}
+	if sr.ToTime != nil && !entry.Time.Before(*sr.ToTime) {
+		return false
+	}
+	return true
+}
+
+// txHash is the hash that tendermint shows for the transaction,
+// the ripemd160 of the transaction's bytes with their length.
+func txHash(tx []byte) []byte {
+	length := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(length, uint64(len(tx)))
+	h := ripemd160.New()
+	h.Write(length[:n])
+	h.Write(tx)
+	return h.Sum(nil)
+}
+
+// newStatementEntry returns the account's part of the entry's payment, false if the account did not pay or receive coins.
+func newStatementEntry(account []byte, entry HistoryEntry) (StatementEntry, bool) {
+	se := StatementEntry{Height: entry.Height, Time: entry.Time, Action: entry.Action}
+	if len(entry.Hash) > 0 {
+		se.Hash = strings.ToUpper(hex.EncodeToString(entry.Hash))
+	}
+	if entry.StandingOrder != nil {
+		se.StandingOrder = entry.StandingOrder.ID
+	}
+	if bytes.Equal(entry.Payer, account) {
+		se.Sent = entry.Coins
+		se.TaxPaid = entry.Tax
+		se.Counterparty = hex.EncodeToString(entry.Payee)
+	}
+	if bytes.Equal(entry.Payee, account) {
+		se.Received = entry.Coins - entry.Tax
+		se.Counterparty = hex.EncodeToString(entry.Payer)
+	}
+	if bytes.Equal(entry.TaxReceiver, account) {
+		se.TaxReceived = entry.Tax
+	}
+	if se.Sent == 0 && se.Received == 0 && se.TaxReceived == 0 {
+		return se, false
+	}
+	return se, true
+}
+
+func newStatement(account []byte, chain string, sr statementRange, entries []HistoryEntry) Statement {
+	st := Statement{
+		Account:    hex.EncodeToString(account),
+		Chain:      chain,
+		FromHeight: sr.FromHeight,
+		ToHeight:   sr.ToHeight,
+		FromTime:   sr.FromTime,
+		ToTime:     sr.ToTime,
+		Generated:  time.Now().UTC(),
+		Entries:    []StatementEntry{},
+	}
+	for _, entry := range entries {
+		if !sr.contains(entry) {
+			continue
+		}
+		se, ok := newStatementEntry(account, entry)
+		if !ok {
+			continue
+		}
+		st.Entries = append(st.Entries, se)
+		st.Totals.Sent += se.Sent
+		st.Totals.Received += se.Received
+		st.Totals.TaxPaid += se.TaxPaid
+		st.Totals.TaxReceived += se.TaxReceived
+	}
+	return st
+}
+
+// signedBytes returns the json of the statement without the signature.
+func (st Statement) signedBytes() []byte {
+	st.Signature = ""
+	b, _ := json.Marshal(st)
+	return b
+}
+
+func formatCoins(coins float64) string {
+	return strconv.FormatFloat(coins, 'f', -1, 64)
+}
+
+func optionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+var statementCsvHeader = []string{"height", "time", "hash", "standing_order", "action", "counterparty", "sent", "received", "tax_paid", "tax_received"}
+
+// csvBytes returns the statement as CSV without the last line of the signature.
+func (st Statement) csvBytes() []byte {
+	buf := bytes.Buffer{}
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"account", st.Account})
+	w.Write([]string{"chain", st.Chain})
+	w.Write([]string{"from_height", strconv.FormatInt(st.FromHeight, 10)})
+	w.Write([]string{"to_height", strconv.FormatInt(st.ToHeight, 10)})
+	w.Write([]string{"from_time", optionalTime(st.FromTime)})
+	w.Write([]string{"to_time", optionalTime(st.ToTime)})
+	w.Write([]string{"generated", st.Generated.Format(time.RFC3339)})
+	w.Write([]string{"signer", st.Signer})
+	w.Write(statementCsvHeader)
+	for _, se := range st.Entries {
+		w.Write([]string{strconv.FormatInt(se.Height, 10), se.Time.Format(time.RFC3339), se.Hash, se.StandingOrder, string(se.Action),
+			se.Counterparty, formatCoins(se.Sent), formatCoins(se.Received), formatCoins(se.TaxPaid), formatCoins(se.TaxReceived)})
+	}
+	w.Write([]string{"total", "", "", "", "", "", formatCoins(st.Totals.Sent), formatCoins(st.Totals.Received),
+		formatCoins(st.Totals.TaxPaid), formatCoins(st.Totals.TaxReceived)})
+	w.Flush()
+	return buf.Bytes()
+}
+
+// signStatement returns the signed statement in the format, the CSV ends with the line of the signature of the lines before it.
+func signStatement(privk crypto.PrivKey, st Statement, format string) ([]byte, error) {
+	pubB, err := privk.GetPublic().Bytes()
+	if err != nil {
+		return nil, err
+	}
+	st.Signer = hex.EncodeToString(pubB)
+	if format == STATEMENT_CSV {
+		b := st.csvBytes()
+		sig, err := privk.Sign(b)
+		if err != nil {
+			return nil, err
+		}
+		return append(b, []byte("signature,"+hex.EncodeToString(sig)+"\n")...), nil
+	}
+	sig, err := privk.Sign(st.signedBytes())
+	if err != nil {
+		return nil, err
+	}
+	st.Signature = hex.EncodeToString(sig)
+	return json.MarshalIndent(st, "", "  ")
+}
+
+// verifyStatement verifies the signature of the statement in JSON or CSV and its totals, and returns the statement.
+func verifyStatement(b []byte) (Statement, error) {
+	if len(bytes.TrimSpace(b)) > 0 && bytes.TrimSpace(b)[0] == '{' {
+		st := Statement{}
+		err := json.Unmarshal(b, &st)
+		if err != nil {
+			return st, errors.New("The statement is not json.")
+		}
+		err = verifySigner(st.Signer, st.signedBytes(), st.Signature)
+		if err != nil {
+			return st, err
+		}
+		return st, st.verifyTotals()
+	}
+
+	st := Statement{Entries: []StatementEntry{}}
+	i := bytes.LastIndex(bytes.TrimRight(b, "\n"), []byte("\n"))
+	if i < 0 || !bytes.HasPrefix(b[i+1:], []byte("signature,")) {
+		return st, errors.New("The statement does not have the signature's line.")
+	}
+	signature := strings.TrimSpace(strings.TrimPrefix(string(b[i+1:]), "signature,"))
+	r := csv.NewReader(bytes.NewReader(b[:i+1]))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return st, errors.New("The statement is not CSV: " + err.Error())
+	}
+	inEntries := false
+	for _, rec := range records {
+		switch {
+		case rec[0] == "account" && len(rec) == 2:
+			st.Account = rec[1]
+		case rec[0] == "chain" && len(rec) == 2:
+			st.Chain = rec[1]
+		case rec[0] == "signer" && len(rec) == 2:
+			st.Signer = rec[1]
+		case rec[0] == statementCsvHeader[0]:
+			inEntries = true
+		case inEntries && len(rec) == len(statementCsvHeader):
+			amounts, err := parseCsvCoins(rec[6:])
+			if err != nil {
+				return st, err
+			}
+			if rec[0] == "total" {
+				st.Totals = StatementTotals{Sent: amounts[0], Received: amounts[1], TaxPaid: amounts[2], TaxReceived: amounts[3]}
+				continue
+			}
+			se := StatementEntry{Hash: rec[2], StandingOrder: rec[3], Action: ActionStruct(rec[4]), Counterparty: rec[5],
+				Sent: amounts[0], Received: amounts[1], TaxPaid: amounts[2], TaxReceived: amounts[3]}
+			se.Height, err = strconv.ParseInt(rec[0], 10, 64)
+			if err != nil {
+				return st, errors.New("The height " + rec[0] + " of the statement is not a number.")
+			}
+			se.Time, err = time.Parse(time.RFC3339, rec[1])
+			if err != nil {
+				return st, errors.New("The time " + rec[1] + " of the statement is not RFC3339.")
+			}
+			st.Entries = append(st.Entries, se)
+		}
+	}
+	err = verifySigner(st.Signer, b[:i+1], signature)
+	if err != nil {
+		return st, err
+	}
+	return st, st.verifyTotals()
+}
+
+func parseCsvCoins(fields []string) ([]float64, error) {
+	amounts := []float64{}
+	for _, field := range fields {
+		f, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, errors.New("The coins " + field + " of the statement are not a number.")
+		}
+		amounts = append(amounts, f)
+	}
+	return amounts, nil
+}
+
+// verifyTotals checks that the totals are the sums of the entries, in the order that the statement added them.
+func (st Statement) verifyTotals() error {
+	totals := StatementTotals{}
+	for _, se := range st.Entries {
+		totals.Sent += se.Sent
+		totals.Received += se.Received
+		totals.TaxPaid += se.TaxPaid
+		totals.TaxReceived += se.TaxReceived
+	}
+	if totals != st.Totals {
+		return errors.New("The totals of the statement are not the sums of its payments.")
+	}
+	return nil
+}
+
+func verifySigner(signer string, b []byte, signature string) error {
+	pubB, err := hex.DecodeString(signer)
+	if err != nil {
+		return errors.New("The signer's public key is not hex.")
+	}
+	pub, err := crypto.UnmarshalPublicKey(pubB)
+	if err != nil {
+		return errors.New("The signer's public key is not correct.")
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return errors.New("The signature is not hex.")
+	}
+	ver, err := pub.Verify(b, sig)
+	if err != nil || !ver {
+		return errors.New("The signature does not validate the statement.")
+	}
+	return nil
+}
+
+// statementVerifier recomputes the entries of a statement from the chain,
+// the deliveries in the blocks, the escrows and the HTLCs in the node's state and the tags of the standing orders' payments.
+type statementVerifier struct {
+	blocks map[int64]*block
+	taxes  map[string]*TaxResponse
+}
+
+func (sv *statementVerifier) block(height int64) (*block, error) {
+	blk, ok := sv.blocks[height]
+	if ok {
+		return blk, nil
+	}
+	blk, err := RpcBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	sv.blocks[height] = blk
+	return blk, nil
+}
+
+// setPayment sets the payer, the payee and the tax of the hash on the entry, like the server.
+func (sv *statementVerifier) setPayment(entry *HistoryEntry, payer, payee []byte, taxHash string) error {
+	entry.Payer = payer
+	entry.Payee = payee
+	if len(taxHash) == 0 {
+		return nil
+	}
+	tr, ok := sv.taxes[taxHash]
+	if !ok {
+		var err error
+		tr, err = TaxOf(taxHash)
+		if err != nil {
+			return err
+		}
+		sv.taxes[taxHash] = tr
+	}
+	entry.Tax = entry.Coins * float64(tr.Percentage) / 100
+	entry.TaxReceiver = tr.Receiver
+	return nil
+}
+
+// delivery returns the payment of the entry's transaction in the block of the entry's height.
+func (sv *statementVerifier) delivery(se StatementEntry) (HistoryEntry, error) {
+	entry := HistoryEntry{Height: se.Height, Time: se.Time}
+	blk, err := sv.block(se.Height)
+	if err != nil {
+		return entry, err
+	}
+	var dr *DeliveryRequest
+	for _, tx := range blk.Data.Txs {
+		if strings.ToUpper(hex.EncodeToString(txHash(tx))) == se.Hash {
+			dr = &DeliveryRequest{}
+			if json.Unmarshal(tx, dr) != nil {
+				dr = nil
+			}
+			entry.Hash = txHash(tx)
+			break
+		}
+	}
+	if dr == nil {
+		return entry, errors.New("The transaction " + se.Hash + " is not in the block " + strconv.FormatInt(se.Height, 10) + ".")
+	}
+	entry.Action = dr.Data.Action
+	entry.Coins = dr.Data.Coins
+	switch dr.Data.Action {
+	case SEND_ACTION:
+		if dr.Data.To == nil || dr.Data.TaxHash == nil {
+			break
+		}
+		err = sv.setPayment(&entry, dr.Data.From, *dr.Data.To, *dr.Data.TaxHash)
+	case SEIZE_ACTION:
+		if dr.Data.To == nil || dr.Data.Compliance == nil {
+			break
+		}
+		err = sv.setPayment(&entry, dr.Data.Compliance.Account, *dr.Data.To, "")
+	case ESCROW_RELEASE_ACTION:
+		if dr.Data.Escrow == nil {
+			break
+		}
+		ej, _, escrowErr := EscrowOf(dr.Data.Escrow.ID)
+		if escrowErr != nil {
+			return entry, escrowErr
+		}
+		entry.Coins = ej.Coins
+		err = sv.setPayment(&entry, ej.Payer, ej.Payee, ej.TaxHash)
+	case HTLC_CLAIM_ACTION:
+		if dr.Data.Htlc == nil {
+			break
+		}
+		hj, _, htlcErr := HtlcOf(dr.Data.Htlc.Hashlock)
+		if htlcErr != nil {
+			return entry, htlcErr
+		}
+		entry.Coins = hj.Coins
+		err = sv.setPayment(&entry, hj.Sender, hj.Receiver, hj.TaxHash)
+	}
+	return entry, err
+}
+
+// standingOrderPayment returns the payment of the entry's standing order at the entry's height, from the tags that the node keeps for it.
+func (sv *statementVerifier) standingOrderPayment(se StatementEntry) (HistoryEntry, error) {
+	entry := HistoryEntry{Height: se.Height, Time: se.Time, Action: STANDING_ORDER_PAYMENT_ACTION, StandingOrder: &StandingOrder{ID: se.StandingOrder}}
+	payments, err := StandingOrderPaymentsOf(se.Height, se.Height)
+	if err != nil {
+		return entry, err
+	}
+	for _, sp := range payments {
+		if sp.ID != se.StandingOrder {
+			continue
+		}
+		rt := resultTx{TxResult: types.ResponseDeliverTx{Tags: sp.Tags}}
+		entry.Coins, _ = strconv.ParseFloat(txTag(rt, TAG_AMOUNT), 64)
+		entry.Tax, _ = strconv.ParseFloat(txTag(rt, TAG_TAX), 64)
+		entry.Payer, _ = hex.DecodeString(txTag(rt, TAG_FROM))
+		entry.Payee, _ = hex.DecodeString(txTag(rt, TAG_TO))
+		entry.TaxReceiver, _ = hex.DecodeString(txTag(rt, TAG_TAX_RECEIVER))
+		return entry, nil
+	}
+	return entry, errors.New("The standing order " + se.StandingOrder + " did not pay at the height " + strconv.FormatInt(se.Height, 10) + ".")
+}
+
+// verifyStatementChain recomputes every entry of the statement from the chain, and fails on the first entry that differs.
+func verifyStatementChain(st Statement) (int, error) {
+	account, err := hex.DecodeString(st.Account)
+	if err != nil {
+		return 0, errors.New("The statement's account is not hex.")
+	}
+	status, err := RpcStatus()
+	if err != nil {
+		return 0, err
+	}
+	if status.NodeInfo.Network != st.Chain {
+		return 0, errors.New("The statement is of the chain " + st.Chain + " and the node is of the chain " + status.NodeInfo.Network + ".")
+	}
+	sv := statementVerifier{blocks: map[int64]*block{}, taxes: map[string]*TaxResponse{}}
+	checked := 0
+	for _, se := range st.Entries {
+		var entry HistoryEntry
+		switch {
+		case len(se.Hash) > 0:
+			entry, err = sv.delivery(se)
+		case len(se.StandingOrder) > 0:
+			entry, err = sv.standingOrderPayment(se)
+		default:
+			err = errors.New("The payment of the height " + strconv.FormatInt(se.Height, 10) + " has neither a transaction nor a standing order.")
+		}
+		if err != nil {
+			return checked, err
+		}
+		chainSe, ok := newStatementEntry(account, entry)
+		chainSe.Time = se.Time
+		if !ok || chainSe != se {
+			name := se.Hash
+			if len(name) == 0 {
+				name = "of the standing order " + se.StandingOrder
+			}
+			return checked, errors.New("The payment " + name + " at the height " + strconv.FormatInt(se.Height, 10) + " differs from the chain.")
+		}
+		checked++
+	}
+	return checked, nil
+}
+
+type statementOutput struct {
+	File    string
+	Entries int
+	Totals  StatementTotals
+}
+
+var statementCreateCommand = cli.Command{
+	Name: "create",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file, it reads the history and signs the statement",
+		},
+		cli.StringFlag{
+			Name:  "user",
+			Usage: "the user's contact, address or public key, for a watcher with the user's history grant",
+		},
+		cli.Int64Flag{
+			Name:  "from-height",
+			Usage: "the first height of the statement",
+		},
+		cli.Int64Flag{
+			Name:  "to-height",
+			Usage: "the last height of the statement",
+		},
+		cli.StringFlag{
+			Name:  "from-date",
+			Usage: "the first date of the statement, as " + DATE_FORMAT + " or RFC3339",
+		},
+		cli.StringFlag{
+			Name:  "to-date",
+			Usage: "the date after the statement, as " + DATE_FORMAT + " or RFC3339",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: STATEMENT_JSON,
+			Usage: "the format of the statement: json or csv",
+		},
+		cli.StringFlag{
+			Name:  "file",
+			Usage: "the filename of the statement",
+		},
+	},
+	Usage: "create the signed statement of the sent and received coins and of the tax of an account",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		if len(c.String("file")) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the file is missing")
+		}
+		format := c.String("format")
+		if format != STATEMENT_JSON && format != STATEMENT_CSV {
+			return newCommandError(CodeTypeClientError, "Error: the format should be json or csv")
+		}
+		sr := statementRange{FromHeight: c.Int64("from-height"), ToHeight: c.Int64("to-height")}
+		if len(c.String("from-date")) > 0 {
+			t, err := parseDate(c.String("from-date"))
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			sr.FromTime = &t
+		}
+		if len(c.String("to-date")) > 0 {
+			t, err := parseDate(c.String("to-date"))
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			sr.ToTime = &t
+		}
+
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		account, err := privk.GetPublic().Bytes()
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		var userB *[]byte
+		if len(c.String("user")) > 0 {
+			account, err = resolveAccount(c.String("user"))
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			userB = &account
+		}
+		st, err := RpcStatus()
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		hr, code, err := History(privk, userB)
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+
+		statement := newStatement(account, st.NodeInfo.Network, sr, hr.Entries)
+		b, err := signStatement(privk, statement, format)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		err = ioutil.WriteFile(c.String("file"), b, 0644)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		so := statementOutput{File: c.String("file"), Entries: len(statement.Entries), Totals: statement.Totals}
+		printOutput(so, "The statement with "+strconv.Itoa(so.Entries)+" payments is in "+so.File+"\n"+
+			"Sent: "+formatCoins(so.Totals.Sent)+"\nReceived: "+formatCoins(so.Totals.Received)+"\n"+
+			"Tax paid: "+formatCoins(so.Totals.TaxPaid)+"\nTax received: "+formatCoins(so.Totals.TaxReceived))
+		return nil
+	},
+}
+
+type statementVerifyOutput struct {
+	Signer  string
+	Entries int
+	Checked int `json:",omitempty"` // the payments that are the same in the chain
+}
+
+var statementVerifyCommand = cli.Command{
+	Name: "verify",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "file",
+			Usage: "the filename of the statement in json or csv",
+		},
+		cli.BoolFlag{
+			Name:  "chain",
+			Usage: "check the transactions of the statement in the blocks of the node",
+		},
+	},
+	Usage: "verify the signature of a statement, and its transactions in the chain",
+	Action: func(c *cli.Context) error {
+		if len(c.String("file")) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the file is missing")
+		}
+		b, err := ioutil.ReadFile(c.String("file"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		st, err := verifyStatement(b)
+		if err != nil {
+			return newCommandError(CodeTypeUnauthorized, "Error:"+err.Error())
+		}
+		vo := statementVerifyOutput{Signer: st.Signer, Entries: len(st.Entries)}
+		text := "The statement is signed by " + st.Signer
+		if c.Bool("chain") {
+			vo.Checked, err = verifyStatementChain(st)
+			if err != nil {
+				return newCommandError(CodeTypeUnauthorized, "Error:"+err.Error())
+			}
+			text += "\nThe chain has the same " + strconv.Itoa(vo.Checked) + " payments"
+		}
+		printOutput(vo, text)
+		return nil
+	},
+}
+
+var StatementCommand = cli.Command{
+	Name:  "statement",
+	Usage: "the signed statements of the accounts for the tax filings",
+	Subcommands: []cli.Command{
+		statementCreateCommand,
+		statementVerifyCommand,
+	},
+}
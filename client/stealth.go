@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/urfave/cli"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+)
+
+// The stealth address is too long for bech32, so it is hex with a checksum.
+const STEALTH_ADDRESS_PREFIX = "tcs_"
+
+const stealthChecksumSize = 4
+
+// oneTimeKey is the key of a stealth payment. The receiver derives its scalar, so it has no Ed25519 seed,
+// and it signs with Schnorr signatures that are valid Ed25519 signatures.
+type oneTimeKey struct {
+	x kyber.Scalar
+}
+
+func unmarshalOneTimeKey(b []byte) (crypto.PrivKey, error) {
+	x := suite.Scalar()
+	err := x.UnmarshalBinary(b)
+	if err != nil {
+		return nil, errors.New("The one-time key is not correct.")
+	}
+	return &oneTimeKey{x: x}, nil
+}
+
+func (k *oneTimeKey) Bytes() ([]byte, error) {
+	return k.x.MarshalBinary()
+}
+
+func (k *oneTimeKey) Equals(o crypto.Key) bool {
+	b, _ := k.Bytes()
+	ob, err := o.Bytes()
+	return err == nil && bytes.Equal(b, ob)
+}
+
+func (k *oneTimeKey) Sign(b []byte) ([]byte, error) {
+	return schnorr.Sign(suite, k.x, b)
+}
+
+func (k *oneTimeKey) GetPublic() crypto.PubKey {
+	pub, _ := crypto.UnmarshalPublicKey(pointPublicKey(suite.Point().Mul(k.x, nil)))
+	return pub
+}
+
+func pointPublicKey(p kyber.Point) []byte {
+	b, _ := p.MarshalBinary()
+	return append(append([]byte{}, ed25519PublicKeyHeader...), b...)
+}
+
+func hashScalar(domain string, b []byte) kyber.Scalar {
+	h := sha512.Sum512(append([]byte(domain), b...))
+	return suite.Scalar().SetBytes(h[:])
+}
+
+// stealthScanScalar derives the scan key of the spend key, so the key's file is enough to scan.
+func stealthScanScalar(spend kyber.Scalar) kyber.Scalar {
+	b, _ := spend.MarshalBinary()
+	return hashScalar("theftcoin-stealth-scan", b)
+}
+
+// stealthSecret is the scalar that the sender and the receiver share for a one-time key.
+func stealthSecret(shared kyber.Point) kyber.Scalar {
+	b, _ := shared.MarshalBinary()
+	return hashScalar("theftcoin-stealth", b)
+}
+
+// StealthAddress encodes the public scan and spend keys of the key.
+func StealthAddress(privk crypto.PrivKey) (string, error) {
+	spend, err := privateKeyScalar(privk)
+	if err != nil {
+		return "", err
+	}
+	scanB, _ := suite.Point().Mul(stealthScanScalar(spend), nil).MarshalBinary()
+	spendB, _ := suite.Point().Mul(spend, nil).MarshalBinary()
+	data := append(scanB, spendB...)
+	sum := sha256.Sum256(data)
+	return STEALTH_ADDRESS_PREFIX + hex.EncodeToString(append(data, sum[:stealthChecksumSize]...)), nil
+}
+
+func isStealthAddress(account string) bool {
+	return strings.HasPrefix(strings.ToLower(account), STEALTH_ADDRESS_PREFIX)
+}
+
+func stealthAddressKeys(address string) (kyber.Point, kyber.Point, error) {
+	b, err := hex.DecodeString(address[len(STEALTH_ADDRESS_PREFIX):])
+	if err != nil || len(b) != 64+stealthChecksumSize {
+		return nil, nil, errors.New("The stealth address " + address + " is not correct.")
+	}
+	sum := sha256.Sum256(b[:64])
+	if !bytes.Equal(sum[:stealthChecksumSize], b[64:]) {
+		return nil, nil, errors.New("The checksum of the stealth address " + address + " is not correct.")
+	}
+	scan := suite.Point()
+	spend := suite.Point()
+	if scan.UnmarshalBinary(b[:32]) != nil || spend.UnmarshalBinary(b[32:64]) != nil {
+		return nil, nil, errors.New("The stealth address " + address + " is not a pair of keys.")
+	}
+	return scan, spend, nil
+}
+
+// resolveStealthAddress returns the stealth address of a contact's name or a stealth address.
+func resolveStealthAddress(account string) (string, bool, error) {
+	contacts, err := readContacts()
+	if err != nil {
+		return "", false, err
+	}
+	if address, ok := contacts[account]; ok {
+		account = address
+	}
+	return account, isStealthAddress(account), nil
+}
+
+// newStealthPayment derives a new one-time key of the stealth address,
+// the receiver finds the key with the ephemeral key of the send.
+func newStealthPayment(address string) ([]byte, *Stealth, error) {
+	scan, spend, err := stealthAddressKeys(address)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := randomScalar()
+	ephemeral, _ := suite.Point().Mul(r, nil).MarshalBinary()
+	secret := stealthSecret(suite.Point().Mul(r, scan))
+	oneTime := suite.Point().Add(suite.Point().Mul(secret, nil), spend)
+	return pointPublicKey(oneTime), &Stealth{Ephemeral: ephemeral}, nil
+}
+
+// stealthOneTimeKey returns the one-time key of the send if the send is a stealth payment to the spend key.
+func stealthOneTimeKey(spend kyber.Scalar, scan kyber.Scalar, dr DeliveryRequest) (*oneTimeKey, bool) {
+	if dr.Data.Action != SEND_ACTION || dr.Data.Stealth == nil || dr.Data.To == nil {
+		return nil, false
+	}
+	ephemeral := suite.Point()
+	if ephemeral.UnmarshalBinary(dr.Data.Stealth.Ephemeral) != nil {
+		return nil, false
+	}
+	x := suite.Scalar().Add(stealthSecret(suite.Point().Mul(scan, ephemeral)), spend)
+	if !bytes.Equal(pointPublicKey(suite.Point().Mul(x, nil)), *dr.Data.To) {
+		return nil, false
+	}
+	return &oneTimeKey{x: x}, true
+}
+
+type stealthPayment struct {
+	Height   int64
+	Time     time.Time
+	Address  string
+	Coins    float64
+	Balance  float64
+	Filename string `json:",omitempty"`
+	key      *oneTimeKey
+}
+
+// StealthScan finds the stealth payments to the key in the blocks from the height.
+func StealthScan(privk crypto.PrivKey, from int64) ([]stealthPayment, uint32, error) {
+	spend, err := privateKeyScalar(privk)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	scan := stealthScanScalar(spend)
+	err = checkChainId()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	st, err := RpcStatus()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+
+	payments := []stealthPayment{}
+	for height := from; height <= st.LatestBlockHeight; height++ {
+		blk, err := RpcBlock(height)
+		if err != nil {
+			return nil, CodeTypeClientError, err
+		}
+		for _, tx := range blk.Data.Txs {
+			dr := DeliveryRequest{}
+			if json.Unmarshal(tx, &dr) != nil {
+				continue
+			}
+			key, ok := stealthOneTimeKey(spend, scan, dr)
+			if !ok {
+				continue
+			}
+			address, _ := Address(*dr.Data.To)
+			payments = append(payments, stealthPayment{Height: height, Time: blk.Header.Time, Address: address, Coins: dr.Data.Coins, key: key})
+		}
+	}
+
+	// the balance shows the coins that the key can spend, a failed send has none
+	for i := range payments {
+		qr, code, err := Query(payments[i].key, nil)
+		if err != nil {
+			return nil, code, err
+		}
+		payments[i].Balance = qr.Coins
+	}
+	return payments, CodeTypeOK, nil
+}
+
+func writeOneTimeKey(filename string, key *oneTimeKey) error {
+	kj := KeyJson{}
+	pubB, _ := key.GetPublic().Bytes()
+	kj.PublicKey = hex.EncodeToString(pubB)
+	kj.OneTimeKey, _ = key.Bytes()
+	b, _ := json.Marshal(kj)
+	return ioutil.WriteFile(filename, b, 0600)
+}
+
+var stealthAddressCommand = cli.Command{
+	Name: "address",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+	},
+	Usage: "show the stealth address of the key, the senders derive a one-time key from it for every send",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		address, err := StealthAddress(privk)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		printOutput(struct{ StealthAddress string }{address}, address)
+		return nil
+	},
+}
+
+var stealthScanCommand = cli.Command{
+	Name: "scan",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.Int64Flag{
+			Name:  "from",
+			Value: 1,
+			Usage: "the height of the first block to scan",
+		},
+		cli.StringFlag{
+			Name:  "save",
+			Usage: "the directory that the one-time keys are saved, to spend them with the --key flag",
+		},
+	},
+	Usage: "find the stealth payments to the key in the committed transactions",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		payments, code, err := StealthScan(privk, c.Int64("from"))
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+
+		dir := c.String("save")
+		if len(dir) > 0 {
+			err = os.MkdirAll(dir, 0700)
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+		}
+		lines := []string{}
+		for i := range payments {
+			p := &payments[i]
+			if len(dir) > 0 {
+				p.Filename = filepath.Join(dir, p.Address+".json")
+				err = writeOneTimeKey(p.Filename, p.key)
+				if err != nil {
+					return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+				}
+			}
+			lines = append(lines, strconv.FormatInt(p.Height, 10)+"\t"+p.Time.Format(time.RFC3339)+"\t"+p.Address+
+				"\t"+strconv.FormatFloat(p.Coins, 'f', -1, 64)+"\t"+strconv.FormatFloat(p.Balance, 'f', -1, 64))
+		}
+		printOutput(payments, strings.Join(lines, "\n"))
+		return nil
+	},
+}
+
+var StealthCommand = cli.Command{
+	Name:  "stealth",
+	Usage: "receive coins to one-time keys of a stealth address",
+	Subcommands: []cli.Command{
+		stealthAddressCommand,
+		stealthScanCommand,
+	},
+}
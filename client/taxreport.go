@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/urfave/cli"
+)
+
+// The coins that the floats of the report can differ by.
+const TAX_REPORT_TOLERANCE = 1e-6
+
+func TaxReport(from crypto.PrivKey, fromHeight, toHeight int64) (*TaxReportResponse, uint32, error) {
+	b, err := signQueryData(from, QueryData{FromHeight: fromHeight, ToHeight: toHeight})
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	resp, code, err := queryPath(TAX_REPORT_QUERY_PATH, b)
+	if err != nil {
+		return nil, code, err
+	}
+	tr := TaxReportResponse{}
+	err = json.Unmarshal(resp.Value, &tr)
+	if err != nil {
+		return nil, CodeTypeEncodingError, errors.New("The tax report's response is not json.")
+	}
+	return &tr, CodeTypeOK, nil
+}
+
+type taxMismatch struct {
+	Height int64
+	Reason string
+}
+
+type taxReportOutput struct {
+	FromHeight int64
+	ToHeight   int64
+	Receiver   string
+	Rates      []TaxRateJson
+	Own        float64 // the coins of the tax receiver's own transactions
+	Measured   float64 // the change of the balance that the node measured in the transactions
+	Change     float64 // the change of the balance in the blocks
+	Mismatches []taxMismatch
+}
+
+func differ(a, b float64) bool {
+	return math.Abs(a-b) > TAX_REPORT_TOLERANCE
+}
+
+// reconcileTaxReport adds up the tax by its version and flags the blocks where the transactions did not change
+// the tax receiver's balance by the tax and the coins of its own transactions, where the balance changed outside the transactions,
+// or where the tax is not the percentage of the volume. The balance should not change between the blocks of the report either.
+func reconcileTaxReport(tr TaxReportResponse) taxReportOutput {
+	tro := taxReportOutput{FromHeight: tr.FromHeight, ToHeight: tr.ToHeight, Receiver: showAccount(tr.Receiver),
+		Rates: []TaxRateJson{}, Mismatches: []taxMismatch{}}
+	rates := map[string]int{}
+	for i, tb := range tr.Blocks {
+		if i > 0 && differ(tr.Blocks[i-1].BalanceAfter, tb.BalanceBefore) {
+			tro.Mismatches = append(tro.Mismatches, taxMismatch{Height: tb.Height,
+				Reason: "the balance changed by " + formatCoins(tb.BalanceBefore-tr.Blocks[i-1].BalanceAfter) + " after the height " + strconv.FormatInt(tr.Blocks[i-1].Height, 10)})
+		}
+		expected := tb.Own
+		for _, rate := range tb.Rates {
+			expected += rate.Tax
+			if differ(rate.Tax, rate.Volume*float64(rate.Percentage)/100) {
+				tro.Mismatches = append(tro.Mismatches, taxMismatch{Height: tb.Height,
+					Reason: "the tax " + formatCoins(rate.Tax) + " is not " + strconv.Itoa(rate.Percentage) + "% of " + formatCoins(rate.Volume)})
+			}
+			j, ok := rates[rate.TaxHash]
+			if !ok {
+				j = len(tro.Rates)
+				rates[rate.TaxHash] = j
+				tro.Rates = append(tro.Rates, TaxRateJson{TaxHash: rate.TaxHash, Percentage: rate.Percentage})
+			}
+			tro.Rates[j].Transfers += rate.Transfers
+			tro.Rates[j].Volume += rate.Volume
+			tro.Rates[j].Tax += rate.Tax
+			tro.Rates[j].ConfidentialTransfers += rate.ConfidentialTransfers
+			confidentialTax, err := addCommitments(tro.Rates[j].ConfidentialTax, rate.ConfidentialTax)
+			if err != nil {
+				tro.Mismatches = append(tro.Mismatches, taxMismatch{Height: tb.Height, Reason: "the confidential tax: " + err.Error()})
+			}
+			tro.Rates[j].ConfidentialTax = confidentialTax
+		}
+		if differ(tb.Measured, expected) {
+			tro.Mismatches = append(tro.Mismatches, taxMismatch{Height: tb.Height,
+				Reason: "the transactions changed the balance by " + formatCoins(tb.Measured) + " instead of " + formatCoins(expected)})
+		}
+		change := tb.BalanceAfter - tb.BalanceBefore
+		if differ(change, tb.Measured) {
+			tro.Mismatches = append(tro.Mismatches, taxMismatch{Height: tb.Height,
+				Reason: "the balance changed by " + formatCoins(change-tb.Measured) + " outside the transactions"})
+		}
+		tro.Own += tb.Own
+		tro.Measured += tb.Measured
+		tro.Change += change
+	}
+	return tro
+}
+
+var TaxReportCommand = cli.Command{
+	Name: "tax-report",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the watcher's key in json file",
+		},
+		cli.Int64Flag{
+			Name:  "from-height",
+			Usage: "the first height of the report",
+		},
+		cli.Int64Flag{
+			Name:  "to-height",
+			Usage: "the last height of the report, by default the last block",
+		},
+	},
+	Usage: "reconcile the tax of a range of blocks with the tax receiver's balance, as a watcher",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		tr, code, err := TaxReport(privk, c.Int64("from-height"), c.Int64("to-height"))
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+
+		tro := reconcileTaxReport(*tr)
+		lines := []string{"Tax receiver: " + tro.Receiver,
+			"Heights: " + strconv.FormatInt(tro.FromHeight, 10) + " - " + strconv.FormatInt(tro.ToHeight, 10)}
+		for _, rate := range tro.Rates {
+			lines = append(lines, rate.TaxHash+" ("+strconv.Itoa(rate.Percentage)+"%)\t"+strconv.Itoa(rate.Transfers)+" transfers\t"+
+				"volume "+formatCoins(rate.Volume)+"\ttax "+formatCoins(rate.Tax)+"\t"+strconv.Itoa(rate.ConfidentialTransfers)+" confidential transfers")
+		}
+		lines = append(lines, "Own transactions: "+formatCoins(tro.Own),
+			"Measured change in the transactions: "+formatCoins(tro.Measured),
+			"Balance change: "+formatCoins(tro.Change))
+		if len(tro.Mismatches) == 0 {
+			printOutput(tro, strings.Join(append(lines, "The tax reconciles with the balance"), "\n"))
+			return nil
+		}
+		for _, m := range tro.Mismatches {
+			lines = append(lines, "Mismatch at "+strconv.FormatInt(m.Height, 10)+": "+m.Reason)
+		}
+		printText(strings.Join(lines, "\n"))
+		return newResultError(CodeTypeUnauthorized, "Error: the tax does not reconcile in "+strconv.Itoa(len(tro.Mismatches))+" places", tro)
+	},
+}
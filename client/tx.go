@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+func readTx(filename string) (*DeliveryRequest, error) {
+	var b []byte
+	var err error
+	if len(filename) == 0 || filename == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeTx(strings.TrimSpace(string(b)))
+}
+
+// decodeTx accepts a delivery request as json, or as the base64 or hex
+// encoding of the json that tendermint shows for a transaction.
+// The hex is tried first, because a hex string can be base64 too but the base64 of json starts with "ey".
+func decodeTx(encoded string) (*DeliveryRequest, error) {
+	b := []byte(encoded)
+	if !strings.HasPrefix(encoded, "{") {
+		var err error
+		b, err = hex.DecodeString(encoded)
+		if err != nil {
+			b, err = base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, errors.New("The transaction is not json, base64 or hex.")
+			}
+		}
+	}
+	dr := DeliveryRequest{}
+	err := json.Unmarshal(b, &dr)
+	if err != nil {
+		return nil, errors.New("The transaction is not a delivery request: " + err.Error())
+	}
+	return &dr, nil
+}
+
+type txFileOutput struct {
+	Filename string
+}
+
+func writeTx(filename string, dr DeliveryRequest) error {
+	b, _ := json.MarshalIndent(dr, "", "  ")
+	if len(filename) == 0 || filename == "-" {
+		fmt.Println(string(b))
+		return nil
+	}
+	err := ioutil.WriteFile(filename, b, 0644)
+	if err != nil {
+		return err
+	}
+	printOutput(txFileOutput{Filename: filename}, "The transaction was saved in "+filename)
+	return nil
+}
+
+// hexView returns the value for the json with the bytes in hex instead of base64,
+// so the keys of every field of the data look like the rest of the client.
+func hexView(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return hexView(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return hex.EncodeToString(v.Bytes())
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = hexView(v.Index(i))
+		}
+		return list
+	case reflect.Struct:
+		if m, ok := v.Interface().(json.Marshaler); ok {
+			return m
+		}
+		fields := map[string]interface{}{}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if len(f.PkgPath) > 0 {
+				continue
+			}
+			tag := strings.Split(f.Tag.Get("json"), ",")
+			if tag[0] == "-" {
+				continue
+			}
+			name := f.Name
+			if len(tag[0]) > 0 {
+				name = tag[0]
+			}
+			if len(tag) > 1 && tag[1] == "omitempty" && v.Field(i).IsZero() {
+				continue
+			}
+			fields[name] = hexView(v.Field(i))
+		}
+		return fields
+	}
+	return v.Interface()
+}
+
+type decodedTx struct {
+	Signature  string
+	Signed     bool
+	Signatures int `json:",omitempty"` // of the multisig's members
+	Threshold  int `json:",omitempty"`
+	Date       time.Time
+	Data       interface{} // all the fields of the delivery's data, with the bytes in hex
+}
+
+func newDecodedTx(dr DeliveryRequest) decodedTx {
+	dt := decodedTx{}
+	dt.Signature = hex.EncodeToString(dr.Signature)
+	if len(dr.Signature) > 0 || dr.Multisig != nil {
+		dt.Signed, _ = dr.VerifySignature()
+	}
+	if dr.Multisig != nil {
+		dt.Signatures = len(dr.Signatures)
+		dt.Threshold = dr.Multisig.Threshold
+	}
+	dt.Date = dr.Date
+	dt.Data = hexView(reflect.ValueOf(dr.Data))
+	return dt
+}
+
+var txBuildCommand = cli.Command{
+	Name: "build",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file, only the public key is used",
+		},
+		cli.StringFlag{
+			Name:  "from",
+			Usage: "the sender's contact, address or public key, when there is no key file",
+		},
+		cli.StringFlag{
+			Name:  "multisig",
+			Usage: "the filename of the multisig in json file, when the sender is a multisig account",
+		},
+		cli.StringFlag{
+			Name:  "action",
+			Usage: "the action of the transaction: add, remove or send",
+		},
+		cli.StringFlag{
+			Name:  "receiver",
+			Usage: "the receiver's contact, address or public key for send",
+		},
+		cli.StringFlag{
+			Name:  "tax",
+			Usage: "the IPFS hash of the tax for send, by default the validator's active tax",
+		},
+		cli.Float64Flag{
+			Name:  "coins",
+			Usage: "the number of coins of the transaction",
+		},
+		cli.StringFlag{
+			Name:  "out",
+			Usage: "the filename that the unsigned transaction will be saved, by default it is printed",
+		},
+	},
+	Usage: "build an unsigned transaction",
+	Action: func(c *cli.Context) error {
+		var from []byte
+		var multisig *Multisig
+		var err error
+		key := keyFile(c)
+		if len(c.String("multisig")) > 0 {
+			multisig, err = readMultisig(c.String("multisig"))
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			from = multisig.Account()
+		} else if len(c.String("from")) > 0 || len(key) == 0 {
+			if len(c.String("from")) == 0 {
+				return newCommandError(CodeTypeClientError, "Error: the key or the sender's public key is missing")
+			}
+			from, err = resolveAccount(c.String("from"))
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+		} else {
+			from, err = filePublicKey(key)
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+		}
+
+		coins := c.Float64("coins")
+		if coins <= 0 {
+			return newCommandError(CodeTypeClientError, "Error: the coins are not allowed to be 0 or less")
+		}
+
+		var dr DeliveryRequest
+		switch ActionStruct(c.String("action")) {
+		case ADD_ACTION:
+			dr = newDeliveryRequest(from, ADD_ACTION, coins)
+		case REMOVE_ACTION:
+			dr = newDeliveryRequest(from, REMOVE_ACTION, coins)
+		case SEND_ACTION:
+			receiver := c.String("receiver")
+			if len(receiver) == 0 {
+				return newCommandError(CodeTypeClientError, "Error: the receiver is missing")
+			}
+			to, err := resolveAccount(receiver)
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			taxHash := c.String("tax")
+			if len(taxHash) == 0 {
+				tr, code, err := Tax()
+				if err != nil {
+					return newCommandError(code, "Error:"+err.Error())
+				}
+				taxHash = tr.Hash
+			}
+			dr = newSendRequest(from, to, taxHash, coins)
+		default:
+			return newCommandError(CodeTypeClientError, "Error: the action should be add, remove or send")
+		}
+		dr.Multisig = multisig
+
+		err = writeTx(c.String("out"), dr)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		return nil
+	},
+}
+
+var txSignCommand = cli.Command{
+	Name: "sign",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.StringFlag{
+			Name:  "in",
+			Usage: "the filename of the unsigned transaction, by default it is read from the input",
+		},
+		cli.StringFlag{
+			Name:  "out",
+			Usage: "the filename that the signed transaction will be saved, by default it is printed",
+		},
+	},
+	Usage: "sign a transaction without connecting to the node",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		dr, err := readTx(c.String("in"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		// the members of a multisig account add their signatures in turn
+		if dr.Multisig != nil {
+			err = SignMultisigTransaction(privk, dr)
+		} else {
+			err = signDelivery(privk, dr)
+		}
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		err = writeTx(c.String("out"), *dr)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		return nil
+	},
+}
+
+var txBroadcastCommand = cli.Command{
+	Name: "broadcast",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "in",
+			Usage: "the filename of the signed transaction, by default it is read from the input",
+		},
+	},
+	Usage: "submit a signed transaction",
+	Action: func(c *cli.Context) error {
+		dr, err := readTx(c.String("in"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		ver, err := dr.VerifySignature()
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		if !ver {
+			return newCommandError(CodeTypeClientError, "Error: the transaction is not signed by the sender or the multisig's threshold")
+		}
+
+		dres, code, err := Broadcast(*dr)
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), dres)
+		}
+		printOutput(dres, "The broadcast was successful")
+		return nil
+	},
+}
+
+var txDecodeCommand = cli.Command{
+	Name: "decode",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "in",
+			Usage: "the filename of the transaction, by default it is read from the input",
+		},
+	},
+	Usage: "print an encoded transaction",
+	Action: func(c *cli.Context) error {
+		var dr *DeliveryRequest
+		var err error
+		if c.NArg() > 0 {
+			dr, err = decodeTx(c.Args().First())
+		} else {
+			dr, err = readTx(c.String("in"))
+		}
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		printJson(newDecodedTx(*dr))
+		return nil
+	},
+}
+
+var TxCommand = cli.Command{
+	Name:  "tx",
+	Usage: "build, sign, broadcast and decode transactions separately",
+	Subcommands: []cli.Command{
+		txBuildCommand,
+		txSignCommand,
+		txBroadcastCommand,
+		txDecodeCommand,
+	},
+}
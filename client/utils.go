@@ -1,184 +1,303 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/ipfs/go-ipfs-api"
 	crypto "github.com/libp2p/go-libp2p-crypto"
-	"github.com/mragiadakos/theftcoin/server/confs"
-	abcicli "github.com/tendermint/abci/client"
 	"github.com/tendermint/abci/types"
+	"github.com/urfave/cli"
 )
 
 type KeyJson struct {
 	PublicKey  string // hex
 	PrivateKey []byte
+	OneTimeKey []byte `json:",omitempty"` // the scalar of a stealth payment's key
 }
 
-/*
-func deliver(b []byte) (uint32, error) {
-	resp, err := RpcBroadcastCommit(b)
+var chainIdChecked = false
+
+// checkChainId makes sure once that the node belongs to the chain of the profile.
+func checkChainId() error {
+	if len(Conf.ChainId) == 0 || chainIdChecked {
+		return nil
+	}
+	st, err := RpcStatus()
 	if err != nil {
-		return CodeTypeClientError, err
+		return err
 	}
-	if resp.Code > CodeTypeOK {
-		return resp.Code, errors.New(resp.Log)
+	if st.NodeInfo.Network != Conf.ChainId {
+		return errors.New("The node's chain " + st.NodeInfo.Network + " is not the chain " + Conf.ChainId)
 	}
-	return CodeTypeOK, nil
+	chainIdChecked = true
+	return nil
 }
 
-func query(b []byte) (*QueryResponse, uint32, error) {
-	resp, err := RpcQuery(b)
+// deliver broadcasts through the node so the transaction is committed in a block,
+// the node's response is the only place to find the hash and the height.
+func deliver(b []byte) (*DeliveryResult, uint32, error) {
+	err := checkChainId()
 	if err != nil {
 		return nil, CodeTypeClientError, err
 	}
-	if resp.Code > CodeTypeOK {
-		return nil, resp.Code, errors.New(resp.Log)
-	}
-
-	qresp := QueryResponse{}
-	json.Unmarshal(resp.Value, &qresp)
-
-	return &qresp, CodeTypeOK, nil
-}
-*/
-
-func deliver(b []byte) (uint32, error) {
-	client := abcicli.NewSocketClient(confs.Conf.AbciDaemon, false)
-	defer func() {
-		client.Stop()
-	}()
-	err := client.Start()
+	resp, err := RpcBroadcastCommit(b)
 	if err != nil {
-		return CodeTypeClientError, err
+		return nil, CodeTypeClientError, err
 	}
-	resp, err := client.DeliverTxSync(b)
-	if err != nil {
-		return CodeTypeClientError, err
+	dres := &DeliveryResult{}
+	dres.Hash = resp.Hash
+	dres.Height = resp.Height
+	dres.Code = resp.CheckTx.Code
+	dres.Log = resp.CheckTx.Log
+	if dres.Code == CodeTypeOK {
+		dres.Code = resp.DeliveryTx.Code
+		dres.Log = resp.DeliveryTx.Log
 	}
-	if resp.Code > CodeTypeOK {
-		return resp.Code, errors.New(resp.Log)
+	if dres.Code > CodeTypeOK {
+		return dres, dres.Code, errors.New(dres.Log)
 	}
-	return CodeTypeOK, nil
+	return dres, CodeTypeOK, nil
 }
 
-func query(b []byte) (*QueryResponse, uint32, error) {
-	client := abcicli.NewSocketClient(confs.Conf.AbciDaemon, false)
-	defer func() {
-		client.Stop()
-	}()
-	err := client.Start()
+func queryPath(path string, b []byte) (*types.ResponseQuery, uint32, error) {
+	err := checkChainId()
 	if err != nil {
 		return nil, CodeTypeClientError, err
 	}
-	req := types.RequestQuery{}
-	req.Data = b
-	resp, err := client.QuerySync(req)
+	resp, err := RpcQuery(path, b)
 	if err != nil {
 		return nil, CodeTypeClientError, err
 	}
 
 	if resp.Code > CodeTypeOK {
-		return nil, resp.Code, errors.New(resp.Log)
+		return nil, resp.Code, errors.New(resp.Log + serverTimeInfo(resp.Info))
+	}
+	return resp, CodeTypeOK, nil
+}
+
+// serverTimeInfo explains the server's time of a rejected query, so the user can see if the clock is wrong.
+func serverTimeInfo(info string) string {
+	serverTime, err := time.Parse(time.RFC3339Nano, info)
+	if err != nil {
+		return ""
+	}
+	diff := time.Now().UTC().Sub(serverTime).Round(time.Millisecond)
+	return " The server's time is " + serverTime.Format(time.RFC3339) + ", the local clock differs by " + diff.String() + "."
+}
+
+func query(b []byte) (*QueryResponse, uint32, error) {
+	resp, code, err := queryPath("", b)
+	if err != nil {
+		return nil, code, err
 	}
 	qresp := QueryResponse{}
 	json.Unmarshal(resp.Value, &qresp)
 	return &qresp, CodeTypeOK, nil
 }
 
-func Add(from crypto.PrivKey, coins float64) (uint32, error) {
-	var err error
-	dd := DeliveryData{}
-	dd.From, err = from.GetPublic().Bytes()
+func newDeliveryRequest(from []byte, action ActionStruct, coins float64) DeliveryRequest {
+	dr := DeliveryRequest{}
+	dr.Data.From = from
+	dr.Data.Action = action
+	dr.Data.Coins = coins
+	return dr
+}
+
+func newSendRequest(from []byte, toPublicKey []byte, taxHash string, coins float64) DeliveryRequest {
+	dr := newDeliveryRequest(from, SEND_ACTION, coins)
+	dr.Data.To = &toPublicKey
+	dr.Data.TaxHash = &taxHash
+	return dr
+}
+
+func signDelivery(privk crypto.PrivKey, dr *DeliveryRequest) error {
+	pubB, err := privk.GetPublic().Bytes()
 	if err != nil {
-		return CodeTypeClientError, err
+		return err
 	}
-	dd.Action = ADD_ACTION
-	dd.Coins = coins
-	b, _ := json.Marshal(dd)
-	dr := DeliveryRequest{}
-	dr.Signature, err = from.Sign(b)
+	if !bytes.Equal(pubB, dr.Data.From) {
+		return errors.New("The key is not the sender of the transaction.")
+	}
+	b, _ := json.Marshal(dr.Data)
+	dr.Signature, err = privk.Sign(b)
 	if err != nil {
-		return CodeTypeClientError, err
+		return err
 	}
 	dr.Date = time.Now().UTC()
-	dr.Data = dd
-	b, _ = json.Marshal(dr)
-	return deliver(b)
+	return nil
+}
 
+func Broadcast(dr DeliveryRequest) (*DeliveryResult, uint32, error) {
+	b, _ := json.Marshal(dr)
+	return deliver(b)
 }
 
-func Remove(from crypto.PrivKey, coins float64) (uint32, error) {
-	var err error
-	dd := DeliveryData{}
-	dd.From, err = from.GetPublic().Bytes()
+func Add(from crypto.PrivKey, coins float64) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
 	if err != nil {
-		return CodeTypeClientError, err
+		return nil, CodeTypeClientError, err
 	}
-	dd.Action = REMOVE_ACTION
-	dd.Coins = coins
-	b, _ := json.Marshal(dd)
-	dr := DeliveryRequest{}
-	dr.Signature, err = from.Sign(b)
+	dr := newDeliveryRequest(pubB, ADD_ACTION, coins)
+	err = signDelivery(from, &dr)
 	if err != nil {
-		return CodeTypeClientError, err
+		return nil, CodeTypeClientError, err
 	}
-	dr.Date = time.Now().UTC()
-	dr.Data = dd
-	b, _ = json.Marshal(dr)
-	return deliver(b)
+	return Broadcast(dr)
 }
 
-func Send(from crypto.PrivKey, toPublicKey []byte, taxHash string, coins float64) (uint32, error) {
-	var err error
-	dd := DeliveryData{}
-	dd.From, err = from.GetPublic().Bytes()
+func Remove(from crypto.PrivKey, coins float64) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
 	if err != nil {
-		return CodeTypeClientError, err
+		return nil, CodeTypeClientError, err
 	}
-	dd.Action = SEND_ACTION
-	dd.To = &toPublicKey
-	dd.Coins = coins
-	dd.TaxHash = &taxHash
-	b, _ := json.Marshal(dd)
-	dr := DeliveryRequest{}
-	dr.Signature, err = from.Sign(b)
-	dr.Date = time.Now().UTC()
+	dr := newDeliveryRequest(pubB, REMOVE_ACTION, coins)
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
 
+func Send(from crypto.PrivKey, toPublicKey []byte, taxHash string, coins float64, stealth *Stealth, memo *Memo) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
 	if err != nil {
-		return CodeTypeClientError, err
+		return nil, CodeTypeClientError, err
 	}
-	dr.Data = dd
-	b, _ = json.Marshal(dr)
-	return deliver(b)
+	dr := newSendRequest(pubB, toPublicKey, taxHash, coins)
+	dr.Data.Stealth = stealth
+	dr.Data.Memo = memo
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
 }
 
-func Query(from crypto.PrivKey, userAddr *[]byte) (*QueryResponse, uint32, error) {
+// newID returns a random identifier for the escrows and the standing orders.
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func newQueryRequest(from crypto.PrivKey, userAddr *[]byte) ([]byte, error) {
+	return signQueryData(from, QueryData{User: userAddr})
+}
+
+// signQueryData signs the data of the query with its date and a new nonce.
+func signQueryData(from crypto.PrivKey, data QueryData) ([]byte, error) {
 	var err error
 	q := QueryRequest{}
-	data := QueryData{}
 	data.From, err = from.GetPublic().Bytes()
 	if err != nil {
-		return nil, CodeTypeClientError, err
+		return nil, err
 	}
 
-	if userAddr != nil {
-		data.User = userAddr
-	}
 	data.Date = time.Now().UTC()
+	nonce := make([]byte, 16)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	data.Nonce = hex.EncodeToString(nonce)
 	b, _ := json.Marshal(data)
 
 	q.Data = data
 	q.Signature, err = from.Sign(b)
 	if err != nil {
-		return nil, CodeTypeClientError, err
+		return nil, err
 	}
 	b, _ = json.Marshal(q)
+	return b, nil
+}
+
+func Query(from crypto.PrivKey, userAddr *[]byte) (*QueryResponse, uint32, error) {
+	b, err := newQueryRequest(from, userAddr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
 	return query(b)
 }
 
+func History(from crypto.PrivKey, userAddr *[]byte) (*HistoryResponse, uint32, error) {
+	b, err := newQueryRequest(from, userAddr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	resp, code, err := queryPath(HISTORY_QUERY_PATH, b)
+	if err != nil {
+		return nil, code, err
+	}
+	hr := HistoryResponse{}
+	err = json.Unmarshal(resp.Value, &hr)
+	if err != nil {
+		return nil, CodeTypeEncodingError, errors.New("The history response is not json.")
+	}
+	return &hr, CodeTypeOK, nil
+}
+
+func Tax() (*TaxResponse, uint32, error) {
+	resp, code, err := queryPath(TAX_QUERY_PATH, nil)
+	if err != nil {
+		return nil, code, err
+	}
+	tr := TaxResponse{}
+	err = json.Unmarshal(resp.Value, &tr)
+	if err != nil {
+		return nil, CodeTypeEncodingError, errors.New("The tax response is not json.")
+	}
+	return &tr, CodeTypeOK, nil
+}
+
+// TaxOf returns the tax of the hash from IPFS, the tax of the older transactions too.
+func TaxOf(hash string) (*TaxResponse, error) {
+	sh := shell.NewShell(Conf.IpfsConnection)
+	b, err := sh.BlockGet(hash)
+	if err != nil {
+		return nil, err
+	}
+	tax := struct {
+		Percentage   int
+		PublicKeyHex string
+	}{}
+	err = json.Unmarshal(b, &tax)
+	if err != nil {
+		return nil, errors.New("The tax " + hash + " is not json.")
+	}
+	tr := TaxResponse{Hash: hash, Percentage: tax.Percentage}
+	tr.Receiver, err = hex.DecodeString(tax.PublicKeyHex)
+	if err != nil {
+		return nil, errors.New("The receiver of the tax " + hash + " is not hex.")
+	}
+	return &tr, nil
+}
+
+func confirm(question string) (bool, error) {
+	fmt.Fprint(os.Stderr, question+" [y/N]: ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// keyFile returns the key of the command or else the key of the profile.
+func keyFile(c *cli.Context) string {
+	return firstNotEmpty(c.String("key"), Conf.Key)
+}
+
 func fileKey(filename string) (crypto.PrivKey, error) {
 	b, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -191,9 +310,35 @@ func fileKey(filename string) (crypto.PrivKey, error) {
 		return nil, errors.New("Error: json problem with the key " + err.Error())
 	}
 
+	if len(kj.OneTimeKey) > 0 {
+		return unmarshalOneTimeKey(kj.OneTimeKey)
+	}
 	edKey, err := crypto.UnmarshalPrivateKey(kj.PrivateKey)
 	if err != nil {
 		return nil, errors.New("Error: private key decoding problem with the key " + err.Error())
 	}
 	return edKey, nil
 }
+
+func filePublicKey(filename string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New("Error: " + err.Error())
+	}
+
+	kj := KeyJson{}
+	err = json.Unmarshal(b, &kj)
+	if err != nil {
+		return nil, errors.New("Error: json problem with the key " + err.Error())
+	}
+
+	pubB, err := hex.DecodeString(kj.PublicKey)
+	if err != nil {
+		return nil, errors.New("Error: the public key is not hex " + err.Error())
+	}
+	_, err = crypto.UnmarshalPublicKey(pubB)
+	if err != nil {
+		return nil, errors.New("Error: public key decoding problem with the key " + err.Error())
+	}
+	return pubB, nil
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/urfave/cli"
+)
+
+const DATE_FORMAT = "2006-01-02"
+
+// parseDate accepts a day or a time in RFC3339.
+func parseDate(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t.UTC(), nil
+	}
+	t, err = time.Parse(DATE_FORMAT, s)
+	if err != nil {
+		return time.Time{}, errors.New("The date " + s + " should be " + DATE_FORMAT + " or RFC3339.")
+	}
+	return t.UTC(), nil
+}
+
+func ViewGrants(account []byte) (*ViewGrantsResponse, uint32, error) {
+	resp, code, err := queryPath(VIEW_GRANTS_QUERY_PATH, account)
+	if err != nil {
+		return nil, code, err
+	}
+	vgr := ViewGrantsResponse{}
+	err = json.Unmarshal(resp.Value, &vgr)
+	if err != nil {
+		return nil, CodeTypeEncodingError, errors.New("The view grants' response is not json.")
+	}
+	return &vgr, CodeTypeOK, nil
+}
+
+func broadcastViewGrant(from crypto.PrivKey, action ActionStruct, vg ViewGrant) (*DeliveryResult, uint32, error) {
+	pubB, err := from.GetPublic().Bytes()
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	dr := newDeliveryRequest(pubB, action, 0)
+	dr.Data.ViewGrant = &vg
+	err = signDelivery(from, &dr)
+	if err != nil {
+		return nil, CodeTypeClientError, err
+	}
+	return Broadcast(dr)
+}
+
+var viewGrantCommand = cli.Command{
+	Name: "grant",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.StringFlag{
+			Name:  "watcher",
+			Usage: "the watcher's contact, address or public key",
+		},
+		cli.StringFlag{
+			Name:  "kind",
+			Value: string(VIEW_BALANCE),
+			Usage: "the data that the watcher can see: balance, or history for the balance and the history",
+		},
+		cli.StringFlag{
+			Name:  "since",
+			Usage: "the date that the grant starts, by default now",
+		},
+		cli.StringFlag{
+			Name:  "until",
+			Usage: "the date that the grant ends",
+		},
+	},
+	Usage: "let a watcher see the account for a time",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		watcher := c.String("watcher")
+		if len(watcher) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the watcher is missing")
+		}
+		kind := ViewKind(c.String("kind"))
+		if kind != VIEW_BALANCE && kind != VIEW_HISTORY {
+			return newCommandError(CodeTypeClientError, "Error: the kind should be balance or history")
+		}
+		if len(c.String("until")) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the end of the grant is missing")
+		}
+
+		vg := ViewGrant{Kind: kind, NotBefore: time.Now().UTC()}
+		var err error
+		if len(c.String("since")) > 0 {
+			vg.NotBefore, err = parseDate(c.String("since"))
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+		}
+		vg.NotAfter, err = parseDate(c.String("until"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		vg.Watcher, err = resolveAccount(watcher)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		dres, code, err := broadcastViewGrant(privk, VIEW_GRANT_ACTION, vg)
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), dres)
+		}
+		printOutput(dres, "The grant was successful")
+		return nil
+	},
+}
+
+var viewRevokeCommand = cli.Command{
+	Name: "revoke",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.StringFlag{
+			Name:  "watcher",
+			Usage: "the watcher's contact, address or public key",
+		},
+	},
+	Usage: "stop a watcher from seeing the account",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+		watcher := c.String("watcher")
+		if len(watcher) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the watcher is missing")
+		}
+		watcherB, err := resolveAccount(watcher)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		dres, code, err := broadcastViewGrant(privk, VIEW_REVOKE_ACTION, ViewGrant{Watcher: watcherB})
+		if err != nil {
+			return newResultError(code, "Error:"+err.Error(), dres)
+		}
+		printOutput(dres, "The revoke was successful")
+		return nil
+	},
+}
+
+type viewGrantOutput struct {
+	Watcher   string
+	Kind      ViewKind
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+var viewListCommand = cli.Command{
+	Name: "list",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.StringFlag{
+			Name:  "user",
+			Usage: "the user's contact, address or public key, by default the key's account",
+		},
+	},
+	Usage: "list the view grants of an account",
+	Action: func(c *cli.Context) error {
+		var account []byte
+		var err error
+		if len(c.String("user")) > 0 {
+			account, err = resolveAccount(c.String("user"))
+		} else {
+			key := keyFile(c)
+			if len(key) == 0 {
+				return newCommandError(CodeTypeClientError, "Error: the key or the user is missing")
+			}
+			account, err = filePublicKey(key)
+		}
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+
+		vgr, code, err := ViewGrants(account)
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+		list := []viewGrantOutput{}
+		lines := []string{}
+		for _, vg := range vgr.Grants {
+			vo := viewGrantOutput{Watcher: showAccount(vg.Watcher), Kind: vg.Kind, NotBefore: vg.NotBefore, NotAfter: vg.NotAfter}
+			list = append(list, vo)
+			lines = append(lines, vo.Watcher+"\t"+string(vo.Kind)+"\t"+vo.NotBefore.Format(time.RFC3339)+"\t"+vo.NotAfter.Format(time.RFC3339))
+		}
+		printOutput(list, strings.Join(lines, "\n"))
+		return nil
+	},
+}
+
+var ViewCommand = cli.Command{
+	Name:  "view",
+	Usage: "grant and revoke the watchers' view of the account",
+	Subcommands: []cli.Command{
+		viewGrantCommand,
+		viewRevokeCommand,
+		viewListCommand,
+	},
+}
+
+type historyOutput struct {
+	Height int64
+	Time   time.Time
+	Hash   string
+	Action ActionStruct
+	From   string
+	To     string `json:",omitempty"`
+	Coins  float64
+	Memo   string `json:",omitempty"`
+}
+
+// newHistoryOutput shows the entry, with the memo if the key can read it.
+func newHistoryOutput(entry HistoryEntry, privk crypto.PrivKey) historyOutput {
+	ho := historyOutput{
+		Height: entry.Height,
+		Time:   entry.Time,
+		Hash:   strings.ToUpper(hex.EncodeToString(entry.Hash)),
+		Action: entry.Action,
+		From:   showAccount(entry.From),
+		Coins:  entry.Coins,
+	}
+	if entry.To != nil {
+		ho.To = showAccount(*entry.To)
+	}
+	if entry.Memo != nil {
+		text, err := openMemo(privk, entry.Memo)
+		if err != nil {
+			text = "(encrypted)"
+		}
+		ho.Memo = text
+	}
+	return ho
+}
+
+var HistoryCommand = cli.Command{
+	Name: "history",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key",
+			Usage: "the filename that contains the key in json file",
+		},
+		cli.StringFlag{
+			Name:  "user",
+			Usage: "the user's contact, address or public key, for a watcher with the user's history grant",
+		},
+	},
+	Usage: "show the transactions of the account, or of a user for a watcher",
+	Action: func(c *cli.Context) error {
+		key := keyFile(c)
+		if len(key) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the key is missing")
+		}
+
+		var userB *[]byte
+		if len(c.String("user")) > 0 {
+			b, err := resolveAccount(c.String("user"))
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			userB = &b
+		}
+
+		privk, err := fileKey(key)
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		hr, code, err := History(privk, userB)
+		if err != nil {
+			return newCommandError(code, "Error:"+err.Error())
+		}
+
+		list := []historyOutput{}
+		lines := []string{}
+		for _, entry := range hr.Entries {
+			ho := newHistoryOutput(entry, privk)
+			list = append(list, ho)
+			line := strconv.FormatInt(ho.Height, 10) + "\t" + ho.Time.Format(time.RFC3339) + "\t" + string(ho.Action) +
+				"\t" + strconv.FormatFloat(ho.Coins, 'f', -1, 64) + "\t" + ho.From
+			if len(ho.To) > 0 {
+				line += " -> " + ho.To
+			}
+			if len(ho.Memo) > 0 {
+				line += "\t" + strconv.Quote(ho.Memo)
+			}
+			lines = append(lines, line)
+		}
+		printOutput(list, strings.Join(lines, "\n"))
+		return nil
+	},
+}
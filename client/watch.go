@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tendermint/abci/types"
+	"github.com/urfave/cli"
+	"golang.org/x/net/websocket"
+)
+
+// The longest wait between the reconnections to the node.
+const WATCH_MAX_BACKOFF = 30 * time.Second
+
+type txEvent struct {
+	Height int64                   `json:"height"`
+	Tx     []byte                  `json:"tx"`
+	Result types.ResponseDeliverTx `json:"result"`
+}
+
+// txEventValue is the event of a transaction, inside the TxResult in the newer nodes, or the event of a new block.
+type txEventValue struct {
+	TxResult *txEvent `json:"TxResult"`
+	Block    *block   `json:"block"`
+	txEvent
+}
+
+type wsEventResponse struct {
+	Id     string `json:"id"`
+	Result *struct {
+		Data struct {
+			Value txEventValue `json:"value"`
+		} `json:"data"`
+	} `json:"result"`
+	Error *jsonRpcError `json:"error"`
+}
+
+type wsSubscribe struct {
+	Query string `json:"query"`
+}
+
+// websocketUrl returns the websocket of the node's RPC.
+func websocketUrl() string {
+	url := strings.TrimRight(Conf.NodeDaemon, "/")
+	url = strings.Replace(url, "https://", "wss://", 1)
+	url = strings.Replace(url, "http://", "ws://", 1)
+	return url + "/websocket"
+}
+
+type watchOutput struct {
+	Height    int64
+	Hash      string
+	Direction string // in, out or self
+	Action    string
+	From      string
+	To        string `json:",omitempty"`
+	Amount    string `json:",omitempty"`
+	Tax       string `json:",omitempty"`
+}
+
+// txWatcher shows the transactions of the account once, in the order of their heights.
+type txWatcher struct {
+	account    string // public key in hex
+	exec       string
+	lastHeight int64           // the height that the watch is showing, the heights before it are already shown
+	seen       map[string]bool // the hashes of the last height that are shown
+}
+
+func (w *txWatcher) conditions() []string {
+	return []string{TAG_TO + "='" + w.account + "'", TAG_FROM + "='" + w.account + "'"}
+}
+
+// matches returns if the transaction or the payment is to or from the account.
+func (w *txWatcher) matches(rt resultTx) bool {
+	return txTag(rt, TAG_FROM) == w.account || txTag(rt, TAG_TO) == w.account
+}
+
+func (w *txWatcher) handle(rt resultTx) {
+	height := rt.Height
+	if height < w.lastHeight {
+		return
+	}
+	hash := strings.ToUpper(rt.Hash)
+	if !isPayment(rt) {
+		hash = strings.ToUpper(hex.EncodeToString(txHash(rt.Tx)))
+	}
+	if height > w.lastHeight {
+		w.lastHeight = height
+		w.seen = map[string]bool{}
+	}
+	if w.seen[hash] {
+		return
+	}
+	w.seen[hash] = true
+
+	wo := watchOutput{Height: height, Hash: hash, Action: txTag(rt, TAG_ACTION), Amount: txTag(rt, TAG_AMOUNT), Tax: txTag(rt, TAG_TAX)}
+	from := txTag(rt, TAG_FROM)
+	to := txTag(rt, TAG_TO)
+	switch {
+	case from == w.account && to == w.account:
+		wo.Direction = "self"
+	case from == w.account:
+		wo.Direction = "out"
+	default:
+		wo.Direction = "in"
+	}
+	wo.From = showTagAccount(from)
+	wo.To = showTagAccount(to)
+	line := strconv.FormatInt(wo.Height, 10) + "\t" + wo.Hash + "\t" + wo.Direction + "\t" + wo.Action + "\t" + wo.Amount + "\t" + wo.From
+	if len(wo.To) > 0 {
+		line += " -> " + wo.To
+	}
+	printOutput(wo, line)
+	if len(w.exec) > 0 {
+		w.run(wo, from, to)
+	}
+}
+
+// run runs the shell command of the event, with the event in the environment.
+func (w *txWatcher) run(wo watchOutput, from, to string) {
+	cmd := exec.Command("sh", "-c", w.exec)
+	cmd.Env = append(os.Environ(),
+		"THEFTCOIN_TX_HEIGHT="+strconv.FormatInt(wo.Height, 10),
+		"THEFTCOIN_TX_HASH="+wo.Hash,
+		"THEFTCOIN_TX_DIRECTION="+wo.Direction,
+		"THEFTCOIN_TX_ACTION="+wo.Action,
+		"THEFTCOIN_TX_FROM="+from,
+		"THEFTCOIN_TX_TO="+to,
+		"THEFTCOIN_TX_AMOUNT="+wo.Amount,
+		"THEFTCOIN_TX_TAX="+wo.Tax,
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "The command of the transaction "+wo.Hash+" failed: "+err.Error())
+	}
+}
+
+// payments returns the standing orders' payments of the account in the range of heights.
+func (w *txWatcher) payments(fromHeight, toHeight int64) ([]resultTx, error) {
+	rts, err := StandingOrderPayments(fromHeight, toHeight)
+	if err != nil {
+		return nil, err
+	}
+	payments := []resultTx{}
+	for _, rt := range rts {
+		if w.matches(rt) {
+			payments = append(payments, rt)
+		}
+	}
+	return payments, nil
+}
+
+// catchUp shows the transactions from the last height, that were committed while the watch was not connected.
+// The last height can have transactions that were not shown before the connection failed, the seen hashes skip the shown ones.
+func (w *txWatcher) catchUp() error {
+	results := []resultTx{}
+	for _, condition := range w.conditions() {
+		rts, err := RpcTxSearch(condition + " AND tx.height>=" + strconv.FormatInt(w.lastHeight, 10))
+		if err != nil {
+			return err
+		}
+		results = append(results, rts...)
+	}
+	payments, err := w.payments(w.lastHeight, 0)
+	if err != nil {
+		return err
+	}
+	results = append(results, payments...)
+	sortResults(results)
+	for _, rt := range results {
+		w.handle(rt)
+	}
+	return nil
+}
+
+// listen subscribes to the transactions of the account and to the new blocks, and reads the events until the connection fails.
+// The standing orders' payments have no event of their own, so every new block reads the payments of its height.
+func (w *txWatcher) listen() error {
+	ws, err := websocket.Dial(websocketUrl(), "", Conf.NodeDaemon)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	for i, condition := range w.conditions() {
+		jr := newJsonRpcRequest("subscribe", wsSubscribe{Query: "tm.event='Tx' AND " + condition})
+		jr.Id = "watch-" + strconv.Itoa(i)
+		err = websocket.JSON.Send(ws, jr)
+		if err != nil {
+			return err
+		}
+	}
+	jr := newJsonRpcRequest("subscribe", wsSubscribe{Query: "tm.event='NewBlock'"})
+	jr.Id = "watch-block"
+	err = websocket.JSON.Send(ws, jr)
+	if err != nil {
+		return err
+	}
+	// the events of the subscriptions wait in the connection until the catch up ends
+	err = w.catchUp()
+	if err != nil {
+		return err
+	}
+	for {
+		resp := wsEventResponse{}
+		err = websocket.JSON.Receive(ws, &resp)
+		if err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return resp.Error.error()
+		}
+		if resp.Result == nil {
+			continue
+		}
+		if resp.Result.Data.Value.Block != nil {
+			height := resp.Result.Data.Value.Block.Header.Height
+			payments, err := w.payments(height, height)
+			if err != nil {
+				return err
+			}
+			for _, rt := range payments {
+				w.handle(rt)
+			}
+			continue
+		}
+		event := resp.Result.Data.Value.txEvent
+		if resp.Result.Data.Value.TxResult != nil {
+			event = *resp.Result.Data.Value.TxResult
+		}
+		if len(event.Tx) == 0 {
+			continue
+		}
+		w.handle(resultTx{Height: event.Height, Tx: event.Tx, TxResult: event.Result})
+	}
+}
+
+var WatchCommand = cli.Command{
+	Name: "watch",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "account",
+			Usage: "the contact, address or public key of the account",
+		},
+		cli.Int64Flag{
+			Name:  "from-height",
+			Usage: "show the transactions from the height too, by default only the new ones",
+		},
+		cli.StringFlag{
+			Name:  "exec",
+			Usage: "the shell command for every transaction, with the transaction in the THEFTCOIN_TX_* environment variables",
+		},
+	},
+	Usage: "show the transactions and the standing orders' payments to or from an account as they are committed",
+	Action: func(c *cli.Context) error {
+		if len(c.String("account")) == 0 {
+			return newCommandError(CodeTypeClientError, "Error: the account is missing")
+		}
+		account, err := resolveAccount(c.String("account"))
+		if err != nil {
+			return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+		}
+		w := txWatcher{account: hex.EncodeToString(account), exec: c.String("exec"), seen: map[string]bool{}}
+		if c.Int64("from-height") > 0 {
+			w.lastHeight = c.Int64("from-height")
+		} else {
+			st, err := RpcStatus()
+			if err != nil {
+				return newCommandError(CodeTypeClientError, "Error client:"+err.Error())
+			}
+			w.lastHeight = st.LatestBlockHeight + 1
+		}
+
+		backoff := time.Second
+		for {
+			started := time.Now()
+			err = w.listen()
+			if err == nil {
+				err = errors.New("The connection closed.")
+			}
+			// a connection that lasted starts the waiting again
+			if time.Since(started) > WATCH_MAX_BACKOFF {
+				backoff = time.Second
+			}
+			fmt.Fprintln(os.Stderr, "The watch reconnects after "+backoff.String()+" from the height "+
+				strconv.FormatInt(w.lastHeight, 10)+": "+err.Error())
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > WATCH_MAX_BACKOFF {
+				backoff = WATCH_MAX_BACKOFF
+			}
+		}
+	},
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mragiadakos/theftcoin/node"
+	"github.com/mragiadakos/theftcoin/server/ctrls"
+)
+
+// The header with the base64 of the json of a signed ctrls.QueryRequest, for the watchers' data.
+const HEADER_QUERY = "X-Theftcoin-Query"
+
+// Explorer indexes the committed blocks of a node and serves them as read only JSON.
+// The blocks, the supply and the tax are public, the accounts, the coins and the deliveries of the transactions
+// are only for their accounts and the watchers with the accounts' view grants of the history, like the chain's queries.
+type Explorer struct {
+	Node      node.Client
+	Index     *Index
+	Verifier  *ctrls.QueryVerifier
+	IsWatcher func(pub []byte) bool
+	Audit     *ctrls.AuditLog
+}
+
+// Follow indexes the blocks after the index's height until the latest block.
+func (e *Explorer) Follow() error {
+	latest, err := e.Node.LatestHeight()
+	if err != nil {
+		return err
+	}
+	for height := e.Index.Height() + 1; height <= latest; height++ {
+		b, err := e.Node.Block(height)
+		if err != nil {
+			return err
+		}
+		rts := []node.ResultTx{}
+		if len(b.Data.Txs) > 0 {
+			rts, err = e.Node.Txs(height)
+			if err != nil {
+				return err
+			}
+			if len(rts) != len(b.Data.Txs) {
+				return errors.New("The node indexed " + strconv.Itoa(len(rts)) + " of the " + strconv.Itoa(len(b.Data.Txs)) +
+					" transactions of the height " + strconv.FormatInt(height, 10) + ".")
+			}
+		}
+		// the standing orders' payments are at the end of the block and not in the node's index
+		payments, err := e.Node.StandingOrderPayments(height)
+		if err != nil {
+			return err
+		}
+		e.Index.AddBlock(*b, append(rts, payments...))
+	}
+	return nil
+}
+
+type errorResponse struct {
+	Error string
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err string) {
+	writeJson(w, status, errorResponse{Error: err})
+}
+
+// authorize accepts the signed query of one of the accounts, or of a watcher that one of the accounts granted
+// a view of its history for this time in the node's state, the watchers' reads are audited.
+func (e *Explorer) authorize(r *http.Request, accounts ...[]byte) (int, error) {
+	header := r.Header.Get(HEADER_QUERY)
+	if len(header) == 0 {
+		return http.StatusUnauthorized, errors.New("The signed query is missing.")
+	}
+	b, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("The signed query is not base64.")
+	}
+	qr := ctrls.QueryRequest{}
+	err = json.Unmarshal(b, &qr)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("The signed query is not json.")
+	}
+	_, err = e.Verifier.Verify(qr, time.Now().UTC())
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
+	for _, account := range accounts {
+		if bytes.Equal(qr.Data.From, account) {
+			return http.StatusOK, nil
+		}
+	}
+	if !e.IsWatcher(qr.Data.From) {
+		return http.StatusForbidden, errors.New("You are not a watcher.")
+	}
+	now := time.Now().UTC()
+	for _, account := range accounts {
+		grants, err := e.Node.ViewGrants(account)
+		if err != nil {
+			return http.StatusBadGateway, errors.New("The view grants can not be read from the node: " + err.Error())
+		}
+		for _, vg := range grants {
+			if !bytes.Equal(vg.Watcher, qr.Data.From) || !vg.Covers(ctrls.VIEW_HISTORY, now) {
+				continue
+			}
+			if e.Audit != nil {
+				_, err = e.Audit.Append(qr.Data.From, account, r.URL.Path, qr.Data.Nonce)
+				if err != nil {
+					return http.StatusInternalServerError, errors.New("The query can not be audited: " + err.Error())
+				}
+			}
+			return http.StatusOK, nil
+		}
+	}
+	return http.StatusForbidden, errors.New("The accounts did not grant you a view of their history for this time.")
+}
+
+func heightRange(r *http.Request) (int64, int64, error) {
+	heights := []int64{0, 0}
+	for i, name := range []string{"from-height", "to-height"} {
+		v := r.URL.Query().Get(name)
+		if len(v) == 0 {
+			continue
+		}
+		h, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || h < 0 {
+			return 0, 0, errors.New("The " + name + " is not a height.")
+		}
+		heights[i] = h
+	}
+	return heights[0], heights[1], nil
+}
+
+type statusResponse struct {
+	Height int64 // the last indexed height
+}
+
+func (e *Explorer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	get := func(path string, handler http.HandlerFunc) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				writeError(w, http.StatusMethodNotAllowed, "The explorer is read only.")
+				return
+			}
+			handler(w, r)
+		})
+	}
+	get("/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, http.StatusOK, statusResponse{Height: e.Index.Height()})
+	})
+	get("/blocks/", func(w http.ResponseWriter, r *http.Request) {
+		height, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/blocks/"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "The height is not correct.")
+			return
+		}
+		br, ok := e.Index.Block(height)
+		if !ok {
+			writeError(w, http.StatusNotFound, "The block is not indexed.")
+			return
+		}
+		writeJson(w, http.StatusOK, br)
+	})
+	get("/txs/", func(w http.ResponseWriter, r *http.Request) {
+		tr, ok := e.Index.Tx(strings.TrimPrefix(r.URL.Path, "/txs/"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "The transaction is not indexed.")
+			return
+		}
+		if len(r.Header.Get(HEADER_QUERY)) == 0 {
+			writeJson(w, http.StatusOK, tr.public())
+			return
+		}
+		status, err := e.authorize(r, tr.accounts()...)
+		if err != nil {
+			writeError(w, status, err.Error())
+			return
+		}
+		writeJson(w, http.StatusOK, tr)
+	})
+	get("/accounts/", func(w http.ResponseWriter, r *http.Request) {
+		account, err := hex.DecodeString(strings.TrimPrefix(r.URL.Path, "/accounts/"))
+		if err != nil || len(account) == 0 {
+			writeError(w, http.StatusBadRequest, "The account is not a public key in hex.")
+			return
+		}
+		status, err := e.authorize(r, account)
+		if err != nil {
+			writeError(w, status, err.Error())
+			return
+		}
+		writeJson(w, http.StatusOK, e.Index.Account(account))
+	})
+	get("/supply", func(w http.ResponseWriter, r *http.Request) {
+		from, to, err := heightRange(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJson(w, http.StatusOK, e.Index.Supply(from, to))
+	})
+	get("/tax", func(w http.ResponseWriter, r *http.Request) {
+		from, to, err := heightRange(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJson(w, http.StatusOK, e.Index.Tax(from, to))
+	})
+	return mux
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/node"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/mragiadakos/theftcoin/server/ctrls"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+type testUtils struct {
+	nonces int
+	grants map[string][]ctrls.ViewGrant // the view grants of the accounts in hex
+}
+
+func (tu *testUtils) pubBytes(pubk crypto.PubKey) []byte {
+	b, _ := pubk.Bytes()
+	return b
+}
+
+// delivery returns the transaction of the delivery with the tags that the chain gives to it.
+func (tu *testUtils) delivery(hash string, index uint32, action ctrls.ActionStruct, from, to []byte, coins, tax float64, taxReceiver []byte) node.ResultTx {
+	dr := ctrls.DeliveryRequest{}
+	dr.Data.Action = action
+	dr.Data.From = from
+	dr.Data.Coins = coins
+	tags := []cmn.KVPair{
+		{Key: []byte(ctrls.TAG_ACTION), Value: []byte(action)},
+		{Key: []byte(ctrls.TAG_FROM), Value: []byte(hex.EncodeToString(from))},
+		{Key: []byte(ctrls.TAG_AMOUNT), Value: []byte(strconv.FormatFloat(coins, 'f', -1, 64))},
+	}
+	if to != nil {
+		dr.Data.To = &to
+		taxHash := "QmTax"
+		dr.Data.TaxHash = &taxHash
+		tags = append(tags, cmn.KVPair{Key: []byte(ctrls.TAG_TO), Value: []byte(hex.EncodeToString(to))})
+	}
+	if tax > 0 {
+		tags = append(tags, cmn.KVPair{Key: []byte(ctrls.TAG_TAX), Value: []byte(strconv.FormatFloat(tax, 'f', -1, 64))})
+	}
+	if taxReceiver != nil {
+		tags = append(tags, cmn.KVPair{Key: []byte(ctrls.TAG_TAX_RECEIVER), Value: []byte(hex.EncodeToString(taxReceiver))})
+	}
+	b, _ := json.Marshal(dr)
+	rt := node.ResultTx{Hash: hash, Index: index, Tx: b}
+	rt.TxResult.Tags = tags
+	return rt
+}
+
+// payment returns the standing order's payment with the tags that the chain gives to it, the payment has no transaction.
+func (tu *testUtils) payment(height int64, index int, from, to []byte, coins, tax float64, taxReceiver []byte) node.ResultTx {
+	rt := tu.delivery(node.PaymentHash(height, index), uint32(index), ctrls.STANDING_ORDER_PAYMENT_ACTION, from, to, coins, tax, taxReceiver)
+	rt.Tx = nil
+	return rt
+}
+
+// nodeStandIn answers the status, the blocks, the tx_search of the heights, the view grants and the standing orders' payments like a node.
+func (tu *testUtils) nodeStandIn(t *testing.T, blocks map[int64][]node.ResultTx, latest int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jr := struct {
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}{}
+		b, _ := ioutil.ReadAll(r.Body)
+		assert.Nil(t, json.Unmarshal(b, &jr))
+		var result interface{}
+		switch jr.Method {
+		case "status":
+			result = node.Status{LatestBlockHeight: latest}
+		case "block":
+			bh := node.BlockHeight{}
+			json.Unmarshal(jr.Params, &bh)
+			br := node.BlockResult{}
+			br.Block.Header = node.BlockHeader{Height: bh.Height, Time: time.Unix(1500000000+bh.Height, 0).UTC()}
+			for _, rt := range blocks[bh.Height] {
+				if rt.Tx != nil {
+					br.Block.Data.Txs = append(br.Block.Data.Txs, rt.Tx)
+				}
+			}
+			result = br
+		case "tx_search":
+			ts := node.TxSearch{}
+			json.Unmarshal(jr.Params, &ts)
+			height, err := strconv.ParseInt(strings.TrimPrefix(ts.Query, "tx.height="), 10, 64)
+			assert.Nil(t, err)
+			rts := []node.ResultTx{}
+			for _, rt := range blocks[height] {
+				if rt.Tx != nil {
+					rt.Height = height
+					rts = append(rts, rt)
+				}
+			}
+			result = rts
+		case "abci_query":
+			aq := node.AbciQuery{}
+			json.Unmarshal(jr.Params, &aq)
+			var b []byte
+			switch aq.Path {
+			case ctrls.VIEW_GRANTS_QUERY_PATH:
+				b, _ = json.Marshal(ctrls.ViewGrantsResponse{Grants: tu.grants[aq.Data]})
+			case ctrls.STANDING_ORDER_PAYMENTS_QUERY_PATH:
+				data, _ := hex.DecodeString(aq.Data)
+				spr := ctrls.StandingOrderPaymentsRequest{}
+				assert.Nil(t, json.Unmarshal(data, &spr))
+				resp := ctrls.StandingOrderPaymentsResponse{Payments: []ctrls.StandingOrderPayment{}}
+				for height := spr.FromHeight; height <= spr.ToHeight; height++ {
+					for _, rt := range blocks[height] {
+						if rt.Tx == nil {
+							resp.Payments = append(resp.Payments, ctrls.StandingOrderPayment{ID: "rent", Height: height, Index: int(rt.Index), Tags: rt.TxResult.Tags})
+						}
+					}
+				}
+				b, _ = json.Marshal(resp)
+			default:
+				t.Error("The path " + aq.Path + " is not expected.")
+			}
+			result = node.ResponseQuery{Response: types.ResponseQuery{Code: ctrls.CodeTypeOK, Value: b}}
+		}
+		rb, _ := json.Marshal(result)
+		json.NewEncoder(w).Encode(node.JsonRpcResponse{Version: "2.0", Id: "dontcare", Result: rb})
+	}))
+}
+
+func (tu *testUtils) signedQuery(t *testing.T, from crypto.PrivKey) string {
+	tu.nonces++
+	qr := ctrls.QueryRequest{}
+	qr.Data.Date = time.Now().UTC()
+	qr.Data.Nonce = strconv.Itoa(tu.nonces)
+	qr.Data.From, _ = from.GetPublic().Bytes()
+	b, _ := json.Marshal(qr.Data)
+	var err error
+	qr.Signature, err = from.Sign(b)
+	assert.Nil(t, err)
+	b, _ = json.Marshal(qr)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func (tu *testUtils) get(t *testing.T, srv *httptest.Server, path string, query string, v interface{}) int {
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	if len(query) > 0 {
+		req.Header.Set(HEADER_QUERY, query)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	if v != nil {
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+func TestExplorerIndexesTheBlocks(t *testing.T) {
+	tu := testUtils{}
+	confs.Conf.WaitingRequestTime = 5
+	confs.Conf.QuerySkew = 2
+	_, inflatorPubk, _ := crypto.GenerateEd25519Key(rand.Reader)
+	alicePrivk, alicePubk, _ := crypto.GenerateEd25519Key(rand.Reader)
+	bobPrivk, bobPubk, _ := crypto.GenerateEd25519Key(rand.Reader)
+	_, watcherPubk, _ := crypto.GenerateEd25519Key(rand.Reader)
+	_, taxPubk, _ := crypto.GenerateEd25519Key(rand.Reader)
+	inflator, alice, bob, taxReceiver := tu.pubBytes(inflatorPubk), tu.pubBytes(alicePubk), tu.pubBytes(bobPubk), tu.pubBytes(taxPubk)
+
+	failed := tu.delivery("CC04", 1, ctrls.SEND_ACTION, alice, bob, 1000, 0, nil)
+	failed.TxResult.Code = ctrls.CodeTypeUnauthorized
+	failed.TxResult.Tags = nil
+	blocks := map[int64][]node.ResultTx{
+		1: {tu.delivery("CC01", 0, ctrls.ADD_ACTION, inflator, nil, 100, 0, nil)},
+		3: {tu.delivery("CC02", 0, ctrls.SEND_ACTION, inflator, alice, 50, 5, taxReceiver)},
+		4: {tu.delivery("CC03", 0, ctrls.SEND_ACTION, alice, bob, 20, 0, nil), failed,
+			tu.delivery("CC05", 2, ctrls.REMOVE_ACTION, inflator, nil, 10, 0, nil)},
+	}
+	nodeSrv := tu.nodeStandIn(t, blocks, 4)
+	defer nodeSrv.Close()
+
+	e := &Explorer{
+		Node:      node.Client{Url: nodeSrv.URL},
+		Index:     NewIndex(),
+		Verifier:  ctrls.NewQueryVerifier(),
+		IsWatcher: func(pub []byte) bool { return string(pub) == string(tu.pubBytes(watcherPubk)) },
+	}
+	assert.Nil(t, e.Follow())
+	assert.Equal(t, int64(4), e.Index.Height())
+	srv := httptest.NewServer(e.Handler())
+	defer srv.Close()
+
+	// the accounts and the coins of a transaction are only for its accounts
+	tr := TxRecord{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/txs/cc02", "", &tr))
+	assert.Equal(t, ctrls.SEND_ACTION, tr.Action)
+	assert.Equal(t, int64(3), tr.Height)
+	assert.Nil(t, tr.From)
+	assert.Nil(t, tr.To)
+	assert.Nil(t, tr.Tax)
+	assert.Nil(t, tr.Delivery)
+	assert.Equal(t, http.StatusForbidden, tu.get(t, srv, "/txs/cc02", tu.signedQuery(t, bobPrivk), nil))
+	tr = TxRecord{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/txs/cc02", tu.signedQuery(t, alicePrivk), &tr))
+	assert.Equal(t, alice, tr.To)
+	assert.NotNil(t, tr.Delivery)
+	assert.Equal(t, "QmTax", tr.Tax.TaxHash)
+	assert.Equal(t, float64(45), tr.Tax.Net)
+	assert.Equal(t, float64(10), tr.Tax.Rate)
+	assert.Equal(t, taxReceiver, tr.Tax.Receiver)
+	// the send without a tax has no tax in its tags
+	tr = TxRecord{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/txs/CC03", tu.signedQuery(t, bobPrivk), &tr))
+	assert.Equal(t, float64(20), tr.Amount)
+	assert.Nil(t, tr.Tax)
+	tr = TxRecord{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/txs/CC04", "", &tr))
+	assert.Equal(t, ctrls.CodeTypeUnauthorized, tr.Code)
+	assert.Nil(t, tr.Tax)
+	assert.Equal(t, http.StatusNotFound, tu.get(t, srv, "/txs/FF", "", nil))
+
+	br := BlockRecord{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/blocks/4", "", &br))
+	assert.Equal(t, []string{"CC03", "CC04", "CC05"}, br.Txs)
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/blocks/2", "", &br))
+	assert.Equal(t, 0, len(br.Txs))
+	assert.Equal(t, http.StatusNotFound, tu.get(t, srv, "/blocks/5", "", nil))
+
+	supply := []SupplyPoint{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/supply", "", &supply))
+	assert.Equal(t, 2, len(supply))
+	assert.Equal(t, float64(100), supply[0].Supply)
+	assert.Equal(t, int64(4), supply[1].Height)
+	assert.Equal(t, float64(90), supply[1].Supply)
+
+	tax := []TaxPoint{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/tax?from-height=4", "", &tax))
+	assert.Equal(t, 0, len(tax))
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/tax", "", &tax))
+	assert.Equal(t, 1, len(tax))
+	assert.Equal(t, float64(5), tax[0].Tax)
+}
+
+func TestExplorerShowsTheStandingOrdersPayments(t *testing.T) {
+	tu := testUtils{}
+	confs.Conf.WaitingRequestTime = 5
+	confs.Conf.QuerySkew = 2
+	alicePrivk, alicePubk, _ := crypto.GenerateEd25519Key(rand.Reader)
+	bobPrivk, bobPubk, _ := crypto.GenerateEd25519Key(rand.Reader)
+	_, taxPubk, _ := crypto.GenerateEd25519Key(rand.Reader)
+	alice, bob, taxReceiver := tu.pubBytes(alicePubk), tu.pubBytes(bobPubk), tu.pubBytes(taxPubk)
+
+	blocks := map[int64][]node.ResultTx{
+		1: {tu.payment(1, 0, alice, bob, 10, 1, taxReceiver), tu.delivery("EE01", 0, ctrls.SEND_ACTION, alice, bob, 20, 2, taxReceiver)},
+		2: {tu.payment(2, 0, alice, bob, 10, 1, taxReceiver)},
+	}
+	nodeSrv := tu.nodeStandIn(t, blocks, 2)
+	defer nodeSrv.Close()
+	e := &Explorer{
+		Node:      node.Client{Url: nodeSrv.URL},
+		Index:     NewIndex(),
+		Verifier:  ctrls.NewQueryVerifier(),
+		IsWatcher: func(pub []byte) bool { return false },
+	}
+	assert.Nil(t, e.Follow())
+	srv := httptest.NewServer(e.Handler())
+	defer srv.Close()
+
+	// the payments are at the end of their block
+	br := BlockRecord{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/blocks/1", "", &br))
+	assert.Equal(t, []string{"EE01", "SO-1-0"}, br.Txs)
+	tr := TxRecord{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/txs/SO-2-0", tu.signedQuery(t, bobPrivk), &tr))
+	assert.Equal(t, ctrls.STANDING_ORDER_PAYMENT_ACTION, tr.Action)
+	assert.Equal(t, alice, tr.From)
+	assert.Equal(t, float64(9), tr.Tax.Net)
+	assert.Nil(t, tr.Delivery)
+
+	ap := AccountPage{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/accounts/"+hex.EncodeToString(alice), tu.signedQuery(t, alicePrivk), &ap))
+	assert.Equal(t, 3, len(ap.Transactions))
+	assert.Equal(t, float64(40), ap.Sent)
+	assert.Equal(t, float64(4), ap.TaxPaid)
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/accounts/"+hex.EncodeToString(bob), tu.signedQuery(t, bobPrivk), &ap))
+	assert.Equal(t, float64(36), ap.Received)
+
+	tax := []TaxPoint{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/tax", "", &tax))
+	assert.Equal(t, 2, len(tax))
+	assert.Equal(t, 2, tax[0].Transfers)
+	assert.Equal(t, float64(3), tax[0].Tax)
+	assert.Equal(t, float64(1), tax[1].Tax)
+}
+
+func TestExplorerAccountsNeedTheSignedQuery(t *testing.T) {
+	tu := testUtils{}
+	confs.Conf.WaitingRequestTime = 5
+	confs.Conf.QuerySkew = 2
+	_, inflatorPubk, _ := crypto.GenerateEd25519Key(rand.Reader)
+	alicePrivk, alicePubk, _ := crypto.GenerateEd25519Key(rand.Reader)
+	bobPrivk, bobPubk, _ := crypto.GenerateEd25519Key(rand.Reader)
+	watcherPrivk, watcherPubk, _ := crypto.GenerateEd25519Key(rand.Reader)
+	inflator, alice, bob := tu.pubBytes(inflatorPubk), tu.pubBytes(alicePubk), tu.pubBytes(bobPubk)
+
+	blocks := map[int64][]node.ResultTx{
+		1: {tu.delivery("DD01", 0, ctrls.SEND_ACTION, inflator, alice, 50, 5, inflator)},
+		2: {tu.delivery("DD02", 0, ctrls.SEND_ACTION, alice, bob, 20, 2, inflator)},
+	}
+	nodeSrv := tu.nodeStandIn(t, blocks, 2)
+	defer nodeSrv.Close()
+	e := &Explorer{
+		Node:      node.Client{Url: nodeSrv.URL},
+		Index:     NewIndex(),
+		Verifier:  ctrls.NewQueryVerifier(),
+		IsWatcher: func(pub []byte) bool { return string(pub) == string(tu.pubBytes(watcherPubk)) },
+	}
+	assert.Nil(t, e.Follow())
+	srv := httptest.NewServer(e.Handler())
+	defer srv.Close()
+	path := "/accounts/" + hex.EncodeToString(alice)
+
+	assert.Equal(t, http.StatusUnauthorized, tu.get(t, srv, path, "", nil))
+	assert.Equal(t, http.StatusForbidden, tu.get(t, srv, path, tu.signedQuery(t, bobPrivk), nil))
+
+	ap := AccountPage{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, path, tu.signedQuery(t, alicePrivk), &ap))
+	assert.Equal(t, 2, len(ap.Transactions))
+	assert.Equal(t, float64(45), ap.Received)
+	assert.Equal(t, float64(20), ap.Sent)
+	assert.Equal(t, float64(2), ap.TaxPaid)
+
+	// the watcher needs the account's view grant of the history for this time
+	assert.Equal(t, http.StatusForbidden, tu.get(t, srv, path, tu.signedQuery(t, watcherPrivk), nil))
+	now := time.Now().UTC()
+	watcher := tu.pubBytes(watcherPubk)
+	tu.grants = map[string][]ctrls.ViewGrant{hex.EncodeToString(alice): {
+		{Watcher: watcher, Kind: ctrls.VIEW_BALANCE, NotBefore: now.Add(-time.Hour), NotAfter: now.Add(time.Hour)},
+	}}
+	assert.Equal(t, http.StatusForbidden, tu.get(t, srv, path, tu.signedQuery(t, watcherPrivk), nil))
+	tu.grants[hex.EncodeToString(alice)][0] = ctrls.ViewGrant{Watcher: watcher, Kind: ctrls.VIEW_HISTORY,
+		NotBefore: now.Add(-2 * time.Hour), NotAfter: now.Add(-time.Hour)}
+	assert.Equal(t, http.StatusForbidden, tu.get(t, srv, path, tu.signedQuery(t, watcherPrivk), nil))
+	tu.grants[hex.EncodeToString(alice)][0].NotAfter = now.Add(time.Hour)
+
+	query := tu.signedQuery(t, watcherPrivk)
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, path, query, &ap))
+	assert.Equal(t, alice, ap.Account)
+	// the nonce of the query is used
+	assert.Equal(t, http.StatusUnauthorized, tu.get(t, srv, path, query, nil))
+	// the grant of one of the accounts shows the transaction
+	tr := TxRecord{}
+	assert.Equal(t, http.StatusOK, tu.get(t, srv, "/txs/DD02", tu.signedQuery(t, watcherPrivk), &tr))
+	assert.Equal(t, bob, tr.To)
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/supply", nil)
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mragiadakos/theftcoin/node"
+	"github.com/mragiadakos/theftcoin/server/ctrls"
+)
+
+// TaxBreakdown is how the coins of a taxed transfer were split between the payee and the tax.
+type TaxBreakdown struct {
+	TaxHash  string  `json:",omitempty"` // the version of the tax in the delivery
+	Gross    float64 // the coins that the payer sent
+	Tax      float64
+	Net      float64 // the coins that the payee received
+	Rate     float64 // the tax as a percentage of the gross
+	Receiver []byte  // public key
+}
+
+type TxRecord struct {
+	Hash     string
+	Height   int64
+	Index    uint32
+	Time     time.Time
+	Code     uint32
+	Log      string `json:",omitempty"`
+	Action   ctrls.ActionStruct
+	From     []byte                 // public key
+	To       []byte                 `json:",omitempty"` // public key of the payee
+	Amount   float64                `json:",omitempty"`
+	Tax      *TaxBreakdown          `json:",omitempty"`
+	Delivery *ctrls.DeliveryRequest `json:",omitempty"` // empty when the transaction is not the json of a delivery and for the standing orders' payments
+}
+
+func (tr TxRecord) delivered() bool {
+	return tr.Code == ctrls.CodeTypeOK
+}
+
+// public returns the record without its accounts, its coins and its delivery.
+func (tr TxRecord) public() TxRecord {
+	return TxRecord{Hash: tr.Hash, Height: tr.Height, Index: tr.Index, Time: tr.Time, Code: tr.Code, Action: tr.Action}
+}
+
+// accounts returns the signer of the delivery and the accounts of the tags.
+func (tr TxRecord) accounts() [][]byte {
+	accounts := [][]byte{}
+	if tr.Delivery != nil {
+		accounts = append(accounts, tr.Delivery.Data.From)
+	}
+	for _, account := range [][]byte{tr.From, tr.To} {
+		if account != nil {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts
+}
+
+type BlockRecord struct {
+	Height int64
+	Time   time.Time
+	Txs    []string // hashes
+}
+
+// SupplyPoint is the supply after a block that the inflators added or removed coins.
+type SupplyPoint struct {
+	Height int64
+	Time   time.Time
+	Supply float64
+}
+
+// TaxPoint is the tax that the transfers of a block paid to the tax receiver.
+type TaxPoint struct {
+	Height    int64
+	Time      time.Time
+	Transfers int
+	Volume    float64
+	Tax       float64
+}
+
+type AccountPage struct {
+	Account      []byte
+	Received     float64 // the net coins of the transfers to the account
+	Sent         float64 // the coins of the account's sends and standing orders' payments
+	TaxPaid      float64 // the tax of the account's sends and standing orders' payments
+	Transactions []TxRecord
+}
+
+// Index keeps the decoded transactions of the followed blocks in the memory.
+type Index struct {
+	mu       sync.RWMutex
+	height   int64
+	blocks   map[int64]BlockRecord
+	txs      []TxRecord
+	hashes   map[string]int
+	accounts map[string][]int // hex of the public key to the transactions
+	supply   []SupplyPoint
+	tax      []TaxPoint
+}
+
+func NewIndex() *Index {
+	return &Index{blocks: map[int64]BlockRecord{}, hashes: map[string]int{}, accounts: map[string][]int{},
+		supply: []SupplyPoint{}, tax: []TaxPoint{}}
+}
+
+func (idx *Index) Height() int64 {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.height
+}
+
+func tagFloat(rt node.ResultTx, key string) float64 {
+	f, _ := strconv.ParseFloat(rt.Tag(key), 64)
+	return f
+}
+
+func tagAccount(rt node.ResultTx, key string) []byte {
+	b, _ := hex.DecodeString(rt.Tag(key))
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
+
+// decodeTx decodes the delivery of the transaction, and reads what the chain did from the tags of its result.
+func decodeTx(rt node.ResultTx, t time.Time) TxRecord {
+	tr := TxRecord{Hash: strings.ToUpper(rt.Hash), Height: rt.Height, Index: rt.Index, Time: t,
+		Code: rt.TxResult.Code, Log: rt.TxResult.Log}
+	dr := ctrls.DeliveryRequest{}
+	if json.Unmarshal(rt.Tx, &dr) == nil {
+		tr.Delivery = &dr
+		tr.Action = dr.Data.Action
+		tr.From = dr.Data.From
+		if dr.Data.To != nil {
+			tr.To = *dr.Data.To
+		}
+		tr.Amount = dr.Data.Coins
+	}
+	if !tr.delivered() {
+		return tr
+	}
+	tr.Action = ctrls.ActionStruct(rt.Tag(ctrls.TAG_ACTION))
+	tr.From = tagAccount(rt, ctrls.TAG_FROM)
+	tr.To = tagAccount(rt, ctrls.TAG_TO)
+	tr.Amount = tagFloat(rt, ctrls.TAG_AMOUNT)
+	tax := tagFloat(rt, ctrls.TAG_TAX)
+	if tax > 0 {
+		tb := &TaxBreakdown{Gross: tr.Amount, Tax: tax, Net: tr.Amount - tax, Receiver: tagAccount(rt, ctrls.TAG_TAX_RECEIVER)}
+		if tr.Amount > 0 {
+			tb.Rate = tax / tr.Amount * 100
+		}
+		if tr.Delivery != nil && tr.Delivery.Data.TaxHash != nil {
+			tb.TaxHash = *tr.Delivery.Data.TaxHash
+		}
+		tr.Tax = tb
+	}
+	return tr
+}
+
+// AddBlock indexes the transactions of the block, the blocks are added in the order of their heights.
+func (idx *Index) AddBlock(b node.Block, rts []node.ResultTx) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.height = b.Header.Height
+	if len(rts) == 0 {
+		return
+	}
+	br := BlockRecord{Height: b.Header.Height, Time: b.Header.Time, Txs: []string{}}
+	supply := float64(0)
+	if len(idx.supply) > 0 {
+		supply = idx.supply[len(idx.supply)-1].Supply
+	}
+	newSupply := supply
+	tp := TaxPoint{Height: br.Height, Time: br.Time}
+	for _, rt := range rts {
+		tr := decodeTx(rt, br.Time)
+		i := len(idx.txs)
+		idx.txs = append(idx.txs, tr)
+		idx.hashes[tr.Hash] = i
+		br.Txs = append(br.Txs, tr.Hash)
+		for _, account := range [][]byte{tr.From, tr.To} {
+			if account == nil {
+				continue
+			}
+			key := hex.EncodeToString(account)
+			list := idx.accounts[key]
+			if len(list) > 0 && list[len(list)-1] == i {
+				continue
+			}
+			idx.accounts[key] = append(list, i)
+		}
+		if !tr.delivered() {
+			continue
+		}
+		switch tr.Action {
+		case ctrls.ADD_ACTION:
+			newSupply += tr.Amount
+		case ctrls.REMOVE_ACTION:
+			newSupply -= tr.Amount
+		}
+		if tr.Tax != nil {
+			tp.Transfers++
+			tp.Volume += tr.Tax.Gross
+			tp.Tax += tr.Tax.Tax
+		}
+	}
+	idx.blocks[br.Height] = br
+	if newSupply != supply {
+		idx.supply = append(idx.supply, SupplyPoint{Height: br.Height, Time: br.Time, Supply: newSupply})
+	}
+	if tp.Transfers > 0 {
+		idx.tax = append(idx.tax, tp)
+	}
+}
+
+// Block returns the block of the height, the blocks without transactions have only their height.
+func (idx *Index) Block(height int64) (BlockRecord, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if height < 1 || height > idx.height {
+		return BlockRecord{}, false
+	}
+	br, ok := idx.blocks[height]
+	if !ok {
+		br = BlockRecord{Height: height, Txs: []string{}}
+	}
+	return br, true
+}
+
+func (idx *Index) Tx(hash string) (TxRecord, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	i, ok := idx.hashes[strings.ToUpper(hash)]
+	if !ok {
+		return TxRecord{}, false
+	}
+	return idx.txs[i], true
+}
+
+func (idx *Index) Account(account []byte) AccountPage {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	key := hex.EncodeToString(account)
+	ap := AccountPage{Account: account, Transactions: []TxRecord{}}
+	for _, i := range idx.accounts[key] {
+		tr := idx.txs[i]
+		ap.Transactions = append(ap.Transactions, tr)
+		if !tr.delivered() {
+			continue
+		}
+		if hex.EncodeToString(tr.To) == key {
+			if tr.Tax != nil {
+				ap.Received += tr.Tax.Net
+			} else {
+				ap.Received += tr.Amount
+			}
+		}
+		if (tr.Action == ctrls.SEND_ACTION || tr.Action == ctrls.STANDING_ORDER_PAYMENT_ACTION) && hex.EncodeToString(tr.From) == key {
+			ap.Sent += tr.Amount
+			if tr.Tax != nil {
+				ap.TaxPaid += tr.Tax.Tax
+			}
+		}
+	}
+	return ap
+}
+
+func (idx *Index) Supply(fromHeight, toHeight int64) []SupplyPoint {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	points := []SupplyPoint{}
+	for _, sp := range idx.supply {
+		if sp.Height >= fromHeight && (toHeight == 0 || sp.Height <= toHeight) {
+			points = append(points, sp)
+		}
+	}
+	return points
+}
+
+func (idx *Index) Tax(fromHeight, toHeight int64) []TaxPoint {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	points := []TaxPoint{}
+	for _, tp := range idx.tax {
+		if tp.Height >= fromHeight && (toHeight == 0 || tp.Height <= toHeight) {
+			points = append(points, tp)
+		}
+	}
+	return points
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/mragiadakos/theftcoin/node"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/mragiadakos/theftcoin/server/ctrls"
+	tmlog "github.com/tendermint/tmlibs/log"
+)
+
+func main() {
+	logger := tmlog.NewTMLogger(kitlog.NewSyncWriter(os.Stdout))
+	nodeUrl := flag.String("node", "http://localhost:46657", "the URL for the node's JSON RPC")
+	listen := flag.String("listen", ":8080", "the address of the explorer's HTTP API")
+	ipfsDaemon := flag.String("ipfs", "127.0.0.1:5001", "the URL for the IPFS's daemon")
+	ipfsWatchersHash := flag.String("watchers", "", "the IPFS hash with the JSON list of public keys for watchers")
+	waitSec := flag.Int("wait", 5, "the seconds for an acceptable query")
+	skewSec := flag.Int("skew", 2, "the seconds that the clients' clocks can differ from the explorer's clock in both directions")
+	auditLog := flag.String("audit", "explorer-audit.log", "the file of the hash-chained log for the watchers' reads, empty to keep it in memory")
+	interval := flag.Duration("interval", time.Second, "the wait between the checks for new blocks")
+	flag.Parse()
+
+	if len(*ipfsWatchersHash) == 0 {
+		fmt.Println("Error ", errors.New("The IPFS hash for watchers is missing"))
+		return
+	}
+	confs.Conf.IpfsConnection = *ipfsDaemon
+	confs.Conf.IpfsWatchers = *ipfsWatchersHash
+	err := confs.Conf.SubmitWatchers()
+	if err != nil {
+		fmt.Println("Error ", err.Error())
+		return
+	}
+	confs.Conf.WaitingRequestTime = *waitSec
+	confs.Conf.QuerySkew = *skewSec
+
+	al, err := ctrls.OpenAuditLog(*auditLog)
+	if err != nil {
+		fmt.Println("Error ", err)
+		return
+	}
+	defer al.Close()
+
+	e := &Explorer{
+		Node:      node.Client{Url: *nodeUrl},
+		Index:     NewIndex(),
+		Verifier:  ctrls.NewQueryVerifier(),
+		IsWatcher: func(pub []byte) bool { return confs.Conf.WatcherExists(string(pub)) },
+		Audit:     al,
+	}
+	go func() {
+		for {
+			err := e.Follow()
+			if err != nil {
+				logger.Error("The blocks could not be indexed", "height", e.Index.Height()+1, "err", err)
+			}
+			time.Sleep(*interval)
+		}
+	}()
+
+	logger.Info("Serving the explorer", "listen", *listen)
+	err = http.ListenAndServe(*listen, e.Handler())
+	if err != nil {
+		fmt.Println("Error ", err)
+	}
+}
@@ -0,0 +1,207 @@
+// Package node reads the committed blocks and the state of a tendermint's node from its JSON RPC,
+// for the notifier and the explorer.
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mragiadakos/theftcoin/server/ctrls"
+	"github.com/tendermint/abci/types"
+)
+
+type JsonRpcRequest struct {
+	Method  string      `json:"method"`
+	Version string      `json:"jsonrpc"`
+	Params  interface{} `json:"params"`
+	Id      string      `json:"id"`
+}
+
+type jsonRpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data"`
+}
+
+func (e *jsonRpcError) error() error {
+	return errors.New(strings.TrimSpace(e.Message + " " + e.Data))
+}
+
+type JsonRpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *jsonRpcError   `json:"error"`
+	Id      string          `json:"id"`
+}
+
+type Status struct {
+	LatestBlockHeight int64 `json:"latest_block_height"`
+}
+
+type TxSearch struct {
+	Query string `json:"query"`
+	Prove bool   `json:"prove"`
+}
+
+type AbciQuery struct {
+	Path string `json:"path"`
+	Data string `json:"data"`
+}
+
+type ResponseQuery struct {
+	Response types.ResponseQuery `json:"response"`
+}
+
+type BlockHeader struct {
+	Height int64     `json:"height"`
+	Time   time.Time `json:"time"`
+}
+
+type BlockData struct {
+	Txs [][]byte `json:"txs"`
+}
+
+type Block struct {
+	Header BlockHeader `json:"header"`
+	Data   BlockData   `json:"data"`
+}
+
+type BlockResult struct {
+	Block Block `json:"block"`
+}
+
+type BlockHeight struct {
+	Height int64 `json:"height"`
+}
+
+type ResultTx struct {
+	Hash     string                  `json:"hash"`
+	Height   int64                   `json:"height"`
+	Index    uint32                  `json:"index"`
+	TxResult types.ResponseDeliverTx `json:"tx_result"`
+	Tx       []byte                  `json:"tx"`
+}
+
+func (rt ResultTx) Tag(key string) string {
+	for _, tag := range rt.TxResult.Tags {
+		if string(tag.Key) == key {
+			return string(tag.Value)
+		}
+	}
+	return ""
+}
+
+// Client reads the committed blocks and the state from the JSON RPC of a tendermint's node.
+type Client struct {
+	Url string
+}
+
+func (n Client) call(method string, params interface{}, result interface{}) error {
+	jr := JsonRpcRequest{Method: method, Version: "2.0", Params: params, Id: "dontcare"}
+	bout, _ := json.Marshal(jr)
+	resp, err := http.Post(n.Url, "text/plain", bytes.NewBuffer(bout))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	bresp, _ := ioutil.ReadAll(resp.Body)
+	jresp := JsonRpcResponse{}
+	err = json.Unmarshal(bresp, &jresp)
+	if err != nil {
+		return errors.New("The node's response is not json.")
+	}
+	if jresp.Error != nil {
+		return jresp.Error.error()
+	}
+	err = json.Unmarshal(jresp.Result, result)
+	if err != nil {
+		return errors.New("The node's result of " + method + " is not correct.")
+	}
+	return nil
+}
+
+func (n Client) LatestHeight() (int64, error) {
+	st := Status{}
+	err := n.call("status", struct{}{}, &st)
+	return st.LatestBlockHeight, err
+}
+
+func (n Client) Block(height int64) (*Block, error) {
+	br := BlockResult{}
+	err := n.call("block", BlockHeight{Height: height}, &br)
+	if err != nil {
+		return nil, err
+	}
+	return &br.Block, nil
+}
+
+// Txs returns the transactions of the height in their order in the block, from the node's index of the tags.
+func (n Client) Txs(height int64) ([]ResultTx, error) {
+	rts := []ResultTx{}
+	err := n.call("tx_search", TxSearch{Query: "tx.height=" + strconv.FormatInt(height, 10)}, &rts)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(rts, func(i, j int) bool { return rts[i].Index < rts[j].Index })
+	return rts, nil
+}
+
+// ViewGrants returns the view grants that the account issued, from the node's state.
+func (n Client) ViewGrants(account []byte) ([]ctrls.ViewGrant, error) {
+	rq := ResponseQuery{}
+	err := n.call("abci_query", AbciQuery{Path: ctrls.VIEW_GRANTS_QUERY_PATH, Data: hex.EncodeToString(account)}, &rq)
+	if err != nil {
+		return nil, err
+	}
+	if rq.Response.Code != ctrls.CodeTypeOK {
+		return nil, errors.New(rq.Response.Log)
+	}
+	vgr := ctrls.ViewGrantsResponse{}
+	err = json.Unmarshal(rq.Response.Value, &vgr)
+	if err != nil {
+		return nil, errors.New("The node's view grants are not json.")
+	}
+	return vgr.Grants, nil
+}
+
+// StandingOrderPayments returns the standing orders' payments of the height as results of transactions after the height's transactions,
+// the payments have no transaction, so their hash is made of their height and their index.
+func (n Client) StandingOrderPayments(height int64) ([]ResultTx, error) {
+	b, _ := json.Marshal(ctrls.StandingOrderPaymentsRequest{FromHeight: height, ToHeight: height})
+	rq := ResponseQuery{}
+	err := n.call("abci_query", AbciQuery{Path: ctrls.STANDING_ORDER_PAYMENTS_QUERY_PATH, Data: hex.EncodeToString(b)}, &rq)
+	if err != nil {
+		return nil, err
+	}
+	if rq.Response.Code != ctrls.CodeTypeOK {
+		return nil, errors.New(rq.Response.Log)
+	}
+	spr := ctrls.StandingOrderPaymentsResponse{}
+	err = json.Unmarshal(rq.Response.Value, &spr)
+	if err != nil {
+		return nil, errors.New("The node's standing orders' payments are not json.")
+	}
+	rts := []ResultTx{}
+	for _, sp := range spr.Payments {
+		rts = append(rts, ResultTx{
+			Hash:     PaymentHash(sp.Height, sp.Index),
+			Height:   sp.Height,
+			Index:    uint32(sp.Index),
+			TxResult: types.ResponseDeliverTx{Code: ctrls.CodeTypeOK, Tags: sp.Tags},
+		})
+	}
+	return rts, nil
+}
+
+// PaymentHash is the hash of a standing order's payment, like the client's.
+func PaymentHash(height int64, index int) string {
+	return "SO-" + strconv.FormatInt(height, 10) + "-" + strconv.Itoa(index)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/mragiadakos/theftcoin/node"
+	tmlog "github.com/tendermint/tmlibs/log"
+)
+
+func main() {
+	logger := tmlog.NewTMLogger(kitlog.NewSyncWriter(os.Stdout))
+	nodeUrl := flag.String("node", "http://localhost:46657", "the URL for the node's JSON RPC")
+	subscriptions := flag.String("subscriptions", "subscriptions.json", "the file with the JSON list of the subscriptions")
+	stateFile := flag.String("state", "notifier.json", "the file that keeps the followed height and the pending notifications")
+	fromHeight := flag.Int64("from-height", 0, "the first height to follow when the state is new, by default the next block")
+	interval := flag.Duration("interval", time.Second, "the wait between the checks for new blocks")
+	maxAttempts := flag.Int("max-attempts", 20, "the attempts before a notification fails, 0 for no limit")
+	backoff := flag.Duration("backoff", time.Second, "the wait after the first failed attempt of a notification, it doubles after every other")
+	maxBackoff := flag.Duration("max-backoff", 10*time.Minute, "the longest wait between the attempts of a notification")
+	timeout := flag.Duration("timeout", 10*time.Second, "the timeout of a webhook's request")
+	flag.Parse()
+
+	subs, err := LoadSubscriptions(*subscriptions)
+	if err != nil {
+		fmt.Println("Error ", err.Error())
+		return
+	}
+	state, exists, err := LoadState(*stateFile)
+	if err != nil {
+		fmt.Println("Error ", err.Error())
+		return
+	}
+	n := &Notifier{
+		Node:          node.Client{Url: *nodeUrl},
+		Subscriptions: subs,
+		StateFile:     *stateFile,
+		State:         state,
+		Client:        &http.Client{Timeout: *timeout},
+		MaxAttempts:   *maxAttempts,
+		Backoff:       *backoff,
+		MaxBackoff:    *maxBackoff,
+		Logger:        logger,
+	}
+	if !exists {
+		if *fromHeight > 0 {
+			state.Height = *fromHeight - 1
+		} else {
+			state.Height, err = n.Node.LatestHeight()
+			if err != nil {
+				fmt.Println("Error ", err.Error())
+				return
+			}
+		}
+		err = state.Save(*stateFile)
+		if err != nil {
+			fmt.Println("Error ", err.Error())
+			return
+		}
+	}
+	logger.Info("Following the blocks", "height", state.Height+1, "subscriptions", len(subs), "pending", len(state.Pending))
+
+	for {
+		err = n.Follow()
+		if err != nil {
+			logger.Error("The blocks could not be followed", "height", n.State.Height+1, "err", err)
+		}
+		err = n.Deliver(time.Now())
+		if err != nil {
+			logger.Error("The state could not be saved", "err", err)
+		}
+		time.Sleep(*interval)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mragiadakos/theftcoin/node"
+	"github.com/mragiadakos/theftcoin/server/ctrls"
+	tmlog "github.com/tendermint/tmlibs/log"
+)
+
+// The headers of the notifications' requests.
+const (
+	HEADER_ID        = "X-Theftcoin-Notification"
+	HEADER_SIGNATURE = "X-Theftcoin-Signature"
+)
+
+// Sign returns the HMAC-SHA256 in hex of the body with the subscription's secret,
+// the webhook computes it again to check that the notification came from the notifier.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notifier follows the committed blocks and posts the transfers of the subscriptions' accounts to their webhooks.
+// The state is saved after every block and every attempt, so a restart continues from the same place.
+type Notifier struct {
+	Node          node.Client
+	Subscriptions []Subscription
+	StateFile     string
+	State         *NotifierState
+	Client        *http.Client
+	MaxAttempts   int           // the attempts before a notification fails, zero for no limit
+	Backoff       time.Duration // the wait after the first failed attempt, it doubles after every other
+	MaxBackoff    time.Duration
+	Logger        tmlog.Logger
+}
+
+func (n *Notifier) subscription(id string) (Subscription, bool) {
+	for _, s := range n.Subscriptions {
+		if s.Id == id {
+			return s, true
+		}
+	}
+	return Subscription{}, false
+}
+
+// match returns the notifications of the subscriptions that the transaction matches.
+func (n *Notifier) match(rt node.ResultTx) []Notification {
+	notifications := []Notification{}
+	if rt.TxResult.Code != 0 {
+		return notifications
+	}
+	from := rt.Tag(ctrls.TAG_FROM)
+	to := rt.Tag(ctrls.TAG_TO)
+	action := rt.Tag(ctrls.TAG_ACTION)
+	amount, _ := strconv.ParseFloat(rt.Tag(ctrls.TAG_AMOUNT), 64)
+	tax, _ := strconv.ParseFloat(rt.Tag(ctrls.TAG_TAX), 64)
+	hash := strings.ToUpper(rt.Hash)
+	for _, s := range n.Subscriptions {
+		direction := ""
+		if to == s.Account && s.Direction != DIRECTION_OUT {
+			direction = DIRECTION_IN
+		} else if from == s.Account && (s.Direction == DIRECTION_OUT || s.Direction == DIRECTION_BOTH) {
+			direction = DIRECTION_OUT
+		}
+		if len(direction) == 0 || amount < s.MinAmount {
+			continue
+		}
+		if len(s.Actions) > 0 && !contains(s.Actions, action) {
+			continue
+		}
+		notifications = append(notifications, Notification{
+			Url: s.Url,
+			Payload: Payload{
+				Id:           hash + ":" + s.Id,
+				Subscription: s.Id,
+				Account:      s.Account,
+				Direction:    direction,
+				Height:       rt.Height,
+				Hash:         hash,
+				Action:       action,
+				From:         from,
+				To:           to,
+				Amount:       amount,
+				Tax:          tax,
+			},
+		})
+	}
+	return notifications
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Follow adds the notifications of the blocks after the state's height until the latest block.
+func (n *Notifier) Follow() error {
+	latest, err := n.Node.LatestHeight()
+	if err != nil {
+		return err
+	}
+	for height := n.State.Height + 1; height <= latest; height++ {
+		b, err := n.Node.Block(height)
+		if err != nil {
+			return err
+		}
+		rts := []node.ResultTx{}
+		if len(b.Data.Txs) > 0 {
+			rts, err = n.Node.Txs(height)
+			if err != nil {
+				return err
+			}
+			if len(rts) != len(b.Data.Txs) {
+				return errors.New("The node indexed " + strconv.Itoa(len(rts)) + " of the " + strconv.Itoa(len(b.Data.Txs)) +
+					" transactions of the height " + strconv.FormatInt(height, 10) + ".")
+			}
+		}
+		// the standing orders' payments are at the end of the block and not in the node's index
+		payments, err := n.Node.StandingOrderPayments(height)
+		if err != nil {
+			return err
+		}
+		rts = append(rts, payments...)
+		for _, rt := range rts {
+			n.State.Pending = append(n.State.Pending, n.match(rt)...)
+		}
+		n.State.Height = height
+		err = n.State.Save(n.StateFile)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Notifier) backoff(attempts int) time.Duration {
+	wait := n.Backoff
+	for i := 1; i < attempts && wait < n.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > n.MaxBackoff {
+		wait = n.MaxBackoff
+	}
+	return wait
+}
+
+func (n *Notifier) post(nt Notification) error {
+	s, ok := n.subscription(nt.Payload.Subscription)
+	if !ok {
+		return errors.New("The subscription " + nt.Payload.Subscription + " does not exist anymore.")
+	}
+	body, _ := json.Marshal(nt.Payload)
+	req, err := http.NewRequest(http.MethodPost, nt.Url, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HEADER_ID, nt.Payload.Id)
+	req.Header.Set(HEADER_SIGNATURE, Sign(s.Secret, body))
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("The webhook responded " + resp.Status + ".")
+	}
+	return nil
+}
+
+// Deliver posts the pending notifications that their attempt is due, a failed attempt waits for the backoff.
+func (n *Notifier) Deliver(now time.Time) error {
+	for i := 0; i < len(n.State.Pending); {
+		nt := &n.State.Pending[i]
+		if nt.NextAttempt.After(now) {
+			i++
+			continue
+		}
+		err := n.post(*nt)
+		nt.Attempts++
+		if err == nil {
+			n.Logger.Info("Notified", "id", nt.Payload.Id, "url", nt.Url)
+			n.State.Pending = append(n.State.Pending[:i], n.State.Pending[i+1:]...)
+		} else if n.MaxAttempts > 0 && nt.Attempts >= n.MaxAttempts {
+			nt.LastError = err.Error()
+			n.Logger.Error("The notification failed", "id", nt.Payload.Id, "attempts", nt.Attempts, "err", err)
+			n.State.Failed = append(n.State.Failed, *nt)
+			n.State.Pending = append(n.State.Pending[:i], n.State.Pending[i+1:]...)
+		} else {
+			nt.LastError = err.Error()
+			nt.NextAttempt = now.Add(n.backoff(nt.Attempts))
+			i++
+		}
+		err = n.State.Save(n.StateFile)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
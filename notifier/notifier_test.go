@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mragiadakos/theftcoin/node"
+	"github.com/mragiadakos/theftcoin/server/ctrls"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+	cmn "github.com/tendermint/tmlibs/common"
+	tmlog "github.com/tendermint/tmlibs/log"
+)
+
+const (
+	alice = "0801122011aa"
+	bob   = "0801122022bb"
+)
+
+func transfer(hash string, height int64, index uint32, action, from, to, amount string) node.ResultTx {
+	rt := node.ResultTx{Hash: hash, Height: height, Index: index, Tx: []byte(hash)}
+	rt.TxResult.Tags = []cmn.KVPair{
+		{Key: []byte(ctrls.TAG_ACTION), Value: []byte(action)},
+		{Key: []byte(ctrls.TAG_FROM), Value: []byte(from)},
+		{Key: []byte(ctrls.TAG_TO), Value: []byte(to)},
+		{Key: []byte(ctrls.TAG_AMOUNT), Value: []byte(amount)},
+	}
+	return rt
+}
+
+// payment is a standing order's payment at the end of the block, it has no transaction.
+func payment(height int64, index int, from, to, amount string) node.ResultTx {
+	rt := transfer(node.PaymentHash(height, index), height, uint32(index), string(ctrls.STANDING_ORDER_PAYMENT_ACTION), from, to, amount)
+	rt.Tx = nil
+	return rt
+}
+
+// nodeStandIn answers the status, the blocks, the tx_search of the heights and the standing orders' payments like a node,
+// the blocks have the unindexed transactions too that the tx_search does not return.
+func nodeStandIn(t *testing.T, blocks map[int64][]node.ResultTx, unindexed map[int64]int, latest *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jr := struct {
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}{}
+		b, _ := ioutil.ReadAll(r.Body)
+		assert.Nil(t, json.Unmarshal(b, &jr))
+		var result interface{}
+		switch jr.Method {
+		case "status":
+			result = node.Status{LatestBlockHeight: *latest}
+		case "block":
+			bh := node.BlockHeight{}
+			assert.Nil(t, json.Unmarshal(jr.Params, &bh))
+			br := node.BlockResult{}
+			br.Block.Header = node.BlockHeader{Height: bh.Height}
+			for _, rt := range blocks[bh.Height] {
+				if rt.Tx != nil {
+					br.Block.Data.Txs = append(br.Block.Data.Txs, rt.Tx)
+				}
+			}
+			for i := 0; i < unindexed[bh.Height]; i++ {
+				br.Block.Data.Txs = append(br.Block.Data.Txs, []byte("unindexed"))
+			}
+			result = br
+		case "tx_search":
+			ts := node.TxSearch{}
+			assert.Nil(t, json.Unmarshal(jr.Params, &ts))
+			height, err := strconv.ParseInt(strings.TrimPrefix(ts.Query, "tx.height="), 10, 64)
+			assert.Nil(t, err)
+			rts := []node.ResultTx{}
+			for _, rt := range blocks[height] {
+				if rt.Tx != nil {
+					rts = append(rts, rt)
+				}
+			}
+			result = rts
+		case "abci_query":
+			aq := node.AbciQuery{}
+			assert.Nil(t, json.Unmarshal(jr.Params, &aq))
+			assert.Equal(t, ctrls.STANDING_ORDER_PAYMENTS_QUERY_PATH, aq.Path)
+			b, _ := hex.DecodeString(aq.Data)
+			spr := ctrls.StandingOrderPaymentsRequest{}
+			assert.Nil(t, json.Unmarshal(b, &spr))
+			resp := ctrls.StandingOrderPaymentsResponse{Payments: []ctrls.StandingOrderPayment{}}
+			for height := spr.FromHeight; height <= spr.ToHeight; height++ {
+				for _, rt := range blocks[height] {
+					if rt.Tx == nil {
+						resp.Payments = append(resp.Payments, ctrls.StandingOrderPayment{ID: "rent", Height: height, Index: int(rt.Index), Tags: rt.TxResult.Tags})
+					}
+				}
+			}
+			value, _ := json.Marshal(resp)
+			result = node.ResponseQuery{Response: types.ResponseQuery{Code: ctrls.CodeTypeOK, Value: value}}
+		}
+		rb, _ := json.Marshal(result)
+		json.NewEncoder(w).Encode(node.JsonRpcResponse{Version: "2.0", Id: "dontcare", Result: rb})
+	}))
+}
+
+type webhookStandIn struct {
+	mu       sync.Mutex
+	fails    int // the requests that fail before the webhook accepts them
+	requests int
+	received []Payload
+}
+
+func (wh *webhookStandIn) server(t *testing.T, secret string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wh.mu.Lock()
+		defer wh.mu.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, Sign(secret, body), r.Header.Get(HEADER_SIGNATURE))
+		wh.requests++
+		if wh.requests <= wh.fails {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		p := Payload{}
+		assert.Nil(t, json.Unmarshal(body, &p))
+		assert.Equal(t, p.Id, r.Header.Get(HEADER_ID))
+		wh.received = append(wh.received, p)
+	}))
+}
+
+func tempFile(t *testing.T, name string) (string, func()) {
+	dir, err := ioutil.TempDir("", "notifier")
+	assert.Nil(t, err)
+	return filepath.Join(dir, name), func() { os.RemoveAll(dir) }
+}
+
+func newTestNotifier(t *testing.T, nodeUrl, stateFile string, subs []Subscription) *Notifier {
+	state, _, err := LoadState(stateFile)
+	assert.Nil(t, err)
+	return &Notifier{
+		Node:          node.Client{Url: nodeUrl},
+		Subscriptions: subs,
+		StateFile:     stateFile,
+		State:         state,
+		Client:        &http.Client{Timeout: time.Second},
+		MaxAttempts:   3,
+		Backoff:       time.Minute,
+		MaxBackoff:    time.Hour,
+		Logger:        tmlog.NewNopLogger(),
+	}
+}
+
+func TestNotifierRetriesAndResumes(t *testing.T) {
+	latest := int64(2)
+	blocks := map[int64][]node.ResultTx{
+		1: {transfer("AA01", 1, 0, "send", bob, alice, "10"), transfer("AA02", 1, 1, "send", alice, bob, "5")},
+		2: {transfer("AA03", 2, 0, "send", bob, alice, "1")},
+		3: {transfer("AA04", 3, 0, "escrow_release", bob, alice, "30")},
+	}
+	nodeSrv := nodeStandIn(t, blocks, nil, &latest)
+	defer nodeSrv.Close()
+	wh := &webhookStandIn{fails: 1}
+	webhook := wh.server(t, "secret")
+	defer webhook.Close()
+
+	subs := []Subscription{{Id: "payments", Account: alice, Url: webhook.URL, Secret: "secret", MinAmount: 2}}
+	stateFile, remove := tempFile(t, "notifier.json")
+	defer remove()
+	n := newTestNotifier(t, nodeSrv.URL, stateFile, subs)
+	now := time.Now()
+
+	assert.Nil(t, n.Follow())
+	assert.Equal(t, int64(2), n.State.Height)
+	// the send from alice and the send below the minimum do not match
+	assert.Equal(t, 1, len(n.State.Pending))
+
+	// the first attempt fails and waits for the backoff
+	assert.Nil(t, n.Deliver(now))
+	assert.Equal(t, 1, len(n.State.Pending))
+	assert.Equal(t, 1, n.State.Pending[0].Attempts)
+	assert.Nil(t, n.Deliver(now.Add(time.Second)))
+	assert.Equal(t, 1, wh.requests)
+
+	// a restart continues with the saved state
+	latest = 3
+	n = newTestNotifier(t, nodeSrv.URL, stateFile, subs)
+	assert.Equal(t, int64(2), n.State.Height)
+	assert.Nil(t, n.Follow())
+	assert.Equal(t, 2, len(n.State.Pending))
+	assert.Nil(t, n.Deliver(now.Add(time.Minute)))
+	assert.Equal(t, 0, len(n.State.Pending))
+	assert.Equal(t, 2, len(wh.received))
+	assert.Equal(t, "AA01:payments", wh.received[0].Id)
+	assert.Equal(t, DIRECTION_IN, wh.received[0].Direction)
+	assert.Equal(t, float64(10), wh.received[0].Amount)
+	assert.Equal(t, "escrow_release", wh.received[1].Action)
+
+	// nothing is notified twice after another restart
+	n = newTestNotifier(t, nodeSrv.URL, stateFile, subs)
+	assert.Nil(t, n.Follow())
+	assert.Nil(t, n.Deliver(now.Add(time.Hour)))
+	assert.Equal(t, 2, len(wh.received))
+}
+
+func TestNotifierFailsAfterTheAttempts(t *testing.T) {
+	latest := int64(1)
+	blocks := map[int64][]node.ResultTx{
+		1: {transfer("BB01", 1, 0, "send", alice, bob, "10")},
+	}
+	nodeSrv := nodeStandIn(t, blocks, nil, &latest)
+	defer nodeSrv.Close()
+	wh := &webhookStandIn{fails: 10}
+	webhook := wh.server(t, "secret")
+	defer webhook.Close()
+
+	subs := []Subscription{{Id: "outgoing", Account: alice, Url: webhook.URL, Secret: "secret", Direction: DIRECTION_OUT}}
+	stateFile, remove := tempFile(t, "notifier.json")
+	defer remove()
+	n := newTestNotifier(t, nodeSrv.URL, stateFile, subs)
+	assert.Nil(t, n.Follow())
+	assert.Equal(t, 1, len(n.State.Pending))
+	assert.Equal(t, DIRECTION_OUT, n.State.Pending[0].Payload.Direction)
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, n.Deliver(now))
+		now = now.Add(time.Hour)
+	}
+	assert.Equal(t, 3, wh.requests)
+	assert.Equal(t, 0, len(n.State.Pending))
+	assert.Equal(t, 1, len(n.State.Failed))
+	assert.Equal(t, 3, n.State.Failed[0].Attempts)
+}
+
+func TestNotifierWaitsForTheIndexedTransactions(t *testing.T) {
+	latest := int64(2)
+	blocks := map[int64][]node.ResultTx{
+		1: {transfer("DD01", 1, 0, "send", bob, alice, "10")},
+		2: {transfer("DD02", 2, 0, "send", bob, alice, "20")},
+	}
+	unindexed := map[int64]int{2: 1}
+	nodeSrv := nodeStandIn(t, blocks, unindexed, &latest)
+	defer nodeSrv.Close()
+
+	subs := []Subscription{{Id: "payments", Account: alice, Url: "http://localhost", Secret: "secret"}}
+	stateFile, remove := tempFile(t, "notifier.json")
+	defer remove()
+	n := newTestNotifier(t, nodeSrv.URL, stateFile, subs)
+	// the height with a transaction that the node did not index yet stops the follow
+	assert.NotNil(t, n.Follow())
+	assert.Equal(t, int64(1), n.State.Height)
+	assert.Equal(t, 1, len(n.State.Pending))
+
+	// the next follow continues from the same height after the node indexed it
+	delete(unindexed, 2)
+	n = newTestNotifier(t, nodeSrv.URL, stateFile, subs)
+	assert.Equal(t, int64(1), n.State.Height)
+	assert.Nil(t, n.Follow())
+	assert.Equal(t, int64(2), n.State.Height)
+	assert.Equal(t, 2, len(n.State.Pending))
+}
+
+func TestNotifierPostsTheStandingOrdersPayments(t *testing.T) {
+	latest := int64(2)
+	blocks := map[int64][]node.ResultTx{
+		1: {payment(1, 0, bob, alice, "10"), transfer("EE01", 1, 0, "send", bob, alice, "5")},
+		2: {payment(2, 0, alice, bob, "10")},
+	}
+	nodeSrv := nodeStandIn(t, blocks, nil, &latest)
+	defer nodeSrv.Close()
+
+	subs := []Subscription{{Id: "payments", Account: alice, Url: "http://localhost", Secret: "secret", Direction: DIRECTION_BOTH}}
+	stateFile, remove := tempFile(t, "notifier.json")
+	defer remove()
+	n := newTestNotifier(t, nodeSrv.URL, stateFile, subs)
+	assert.Nil(t, n.Follow())
+	assert.Equal(t, int64(2), n.State.Height)
+	// the payments have no transaction and come after the transactions of their block
+	assert.Equal(t, 3, len(n.State.Pending))
+	assert.Equal(t, "EE01", n.State.Pending[0].Payload.Hash)
+	assert.Equal(t, "SO-1-0", n.State.Pending[1].Payload.Hash)
+	assert.Equal(t, string(ctrls.STANDING_ORDER_PAYMENT_ACTION), n.State.Pending[1].Payload.Action)
+	assert.Equal(t, DIRECTION_IN, n.State.Pending[1].Payload.Direction)
+	assert.Equal(t, "SO-2-0:payments", n.State.Pending[2].Payload.Id)
+	assert.Equal(t, DIRECTION_OUT, n.State.Pending[2].Payload.Direction)
+}
+
+func TestNotifierBackoff(t *testing.T) {
+	n := Notifier{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, n.backoff(1))
+	assert.Equal(t, 2*time.Second, n.backoff(2))
+	assert.Equal(t, 4*time.Second, n.backoff(3))
+	assert.Equal(t, 5*time.Second, n.backoff(4))
+	assert.Equal(t, 5*time.Second, n.backoff(40))
+}
+
+func TestLoadSubscriptions(t *testing.T) {
+	filename, remove := tempFile(t, "subscriptions.json")
+	defer remove()
+	b, _ := json.Marshal([]Subscription{{Id: "a", Account: alice, Url: "http://localhost", Secret: "s"}, {Id: "a", Account: bob, Url: "http://localhost", Secret: "s"}})
+	assert.Nil(t, ioutil.WriteFile(filename, b, 0600))
+	_, err := LoadSubscriptions(filename)
+	assert.NotNil(t, err)
+
+	b, _ = json.Marshal([]Subscription{{Id: "a", Account: "alice", Url: "http://localhost", Secret: "s"}})
+	assert.Nil(t, ioutil.WriteFile(filename, b, 0600))
+	_, err = LoadSubscriptions(filename)
+	assert.NotNil(t, err)
+
+	b, _ = json.Marshal([]Subscription{{Id: "a", Account: alice, Url: "http://localhost", Secret: "s", Direction: DIRECTION_BOTH}})
+	assert.Nil(t, ioutil.WriteFile(filename, b, 0600))
+	subs, err := LoadSubscriptions(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(subs))
+}
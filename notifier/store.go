@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	DIRECTION_IN   = "in"
+	DIRECTION_OUT  = "out"
+	DIRECTION_BOTH = "both"
+)
+
+// Subscription is a webhook for the transfers of an account.
+type Subscription struct {
+	Id        string
+	Account   string   // the public key in hex, like the tags
+	Url       string   // the URL that the notifications are posted to
+	Secret    string   // the key of the notifications' HMAC
+	Direction string   // in, out or both, by default in
+	Actions   []string // the actions of the transactions, by default all
+	MinAmount float64  `json:",omitempty"`
+}
+
+func (s Subscription) validate() error {
+	if len(s.Id) == 0 {
+		return errors.New("The subscription's id is missing.")
+	}
+	b, err := hex.DecodeString(s.Account)
+	if err != nil || len(b) == 0 {
+		return errors.New("The account of the subscription " + s.Id + " is not a public key in hex.")
+	}
+	if len(s.Url) == 0 {
+		return errors.New("The URL of the subscription " + s.Id + " is missing.")
+	}
+	if len(s.Secret) == 0 {
+		return errors.New("The secret of the subscription " + s.Id + " is missing.")
+	}
+	switch s.Direction {
+	case "", DIRECTION_IN, DIRECTION_OUT, DIRECTION_BOTH:
+	default:
+		return errors.New("The direction of the subscription " + s.Id + " is not in, out or both.")
+	}
+	return nil
+}
+
+// LoadSubscriptions reads the JSON list of the subscriptions.
+func LoadSubscriptions(filename string) ([]Subscription, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	subs := []Subscription{}
+	err = json.Unmarshal(b, &subs)
+	if err != nil {
+		return nil, errors.New("The subscriptions are not a json list.")
+	}
+	ids := map[string]bool{}
+	for _, s := range subs {
+		err = s.validate()
+		if err != nil {
+			return nil, err
+		}
+		if ids[s.Id] {
+			return nil, errors.New("The subscription " + s.Id + " is twice in the list.")
+		}
+		ids[s.Id] = true
+	}
+	return subs, nil
+}
+
+// Payload is the JSON that the webhook receives.
+type Payload struct {
+	Id           string // the same for every attempt of the notification
+	Subscription string
+	Account      string
+	Direction    string
+	Height       int64
+	Hash         string
+	Action       string
+	From         string
+	To           string  `json:",omitempty"`
+	Amount       float64 `json:",omitempty"`
+	Tax          float64 `json:",omitempty"`
+}
+
+type Notification struct {
+	Payload     Payload
+	Url         string
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string `json:",omitempty"`
+}
+
+// NotifierState is the height that the notifier followed and the notifications that wait for their delivery.
+type NotifierState struct {
+	Height  int64
+	Pending []Notification
+	Failed  []Notification // the notifications that reached the most attempts
+}
+
+// LoadState reads the state of the file, the state is new when the file does not exist.
+func LoadState(filename string) (*NotifierState, bool, error) {
+	ns := &NotifierState{Pending: []Notification{}, Failed: []Notification{}}
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return ns, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	err = json.Unmarshal(b, ns)
+	if err != nil {
+		return nil, false, errors.New("The notifier's state is not json.")
+	}
+	return ns, true, nil
+}
+
+// Save replaces the file with the state at once, so a crash keeps either the old state or the new.
+func (ns *NotifierState) Save(filename string) error {
+	b, _ := json.MarshalIndent(ns, "", "  ")
+	f, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
@@ -14,13 +14,24 @@ type configuration struct {
 	IpfsConnection     string
 	AbciDaemon         string
 	WaitingRequestTime int
+	QuerySkew          int
 	inflators          map[string]int
 	watchers           map[string]int
+	authorities        map[string]int
+	attestors          map[string]int
+	auditAnchorers     map[string]string
 	IpfsTax            string
 	IpfsInflators      string
 	IpfsWatchers       string
+	IpfsAuthorities    string
+	IpfsAttestors      string
+	IpfsKyc            string
+	IpfsAuditAnchorers string
+	KycLevels          []KycLevel
 	Tax                Tax
 	TaxReceiver        crypto.PubKey
+	AuditLog           string
+	AuditLogId         string
 }
 
 type Tax struct {
@@ -60,6 +71,52 @@ func (w *Watcher) Bytes() ([]byte, error) {
 	return hex.DecodeString(w.PublicKeyHex)
 }
 
+// Authority is a compliance authority that freezes, unfreezes and seizes accounts.
+type Authority struct {
+	PublicKeyHex string
+}
+
+func (a *Authority) SetPublic(b []byte) {
+	a.PublicKeyHex = hex.EncodeToString(b)
+}
+
+func (a *Authority) Bytes() ([]byte, error) {
+	return hex.DecodeString(a.PublicKeyHex)
+}
+
+// Attestor verifies the identity of accounts off the chain and signs their attestations.
+type Attestor struct {
+	PublicKeyHex string
+}
+
+func (a *Attestor) SetPublic(b []byte) {
+	a.PublicKeyHex = hex.EncodeToString(b)
+}
+
+func (a *Attestor) Bytes() ([]byte, error) {
+	return hex.DecodeString(a.PublicKeyHex)
+}
+
+// AuditAnchorer is the account of a node's operator that anchors the node's audit log with the log's id.
+type AuditAnchorer struct {
+	PublicKeyHex string
+	Log          string
+}
+
+func (a *AuditAnchorer) SetPublic(b []byte) {
+	a.PublicKeyHex = hex.EncodeToString(b)
+}
+
+func (a *AuditAnchorer) Bytes() ([]byte, error) {
+	return hex.DecodeString(a.PublicKeyHex)
+}
+
+// KycLevel is the level of the attestation that both accounts of a send above the coins need.
+type KycLevel struct {
+	Coins float64
+	Level int
+}
+
 func (c *configuration) InflatorExists(inlf string) bool {
 	_, ok := c.inflators[inlf]
 	return ok
@@ -70,6 +127,33 @@ func (c *configuration) WatcherExists(watcher string) bool {
 	return ok
 }
 
+func (c *configuration) AuthorityExists(authority string) bool {
+	_, ok := c.authorities[authority]
+	return ok
+}
+
+func (c *configuration) AttestorExists(attestor string) bool {
+	_, ok := c.attestors[attestor]
+	return ok
+}
+
+// RequiredKycLevel returns the highest level of the KYC levels that the coins are above, 0 for none.
+func (c *configuration) RequiredKycLevel(coins float64) int {
+	level := 0
+	for _, v := range c.KycLevels {
+		if coins > v.Coins && v.Level > level {
+			level = v.Level
+		}
+	}
+	return level
+}
+
+// AuditAnchorerExists returns true for the account that anchors the audit log of the id.
+func (c *configuration) AuditAnchorerExists(anchorer string, log string) bool {
+	l, ok := c.auditAnchorers[anchorer]
+	return ok && l == log
+}
+
 func (c *configuration) SubmitTax() error {
 	sh := shell.NewShell(c.IpfsConnection)
 	b, err := sh.BlockGet(c.IpfsTax)
@@ -178,14 +262,125 @@ func (c *configuration) SubmitWatchers() error {
 	return nil
 }
 
+func (c *configuration) SubmitAuthorities() error {
+	sh := shell.NewShell(c.IpfsConnection)
+	b, err := sh.BlockGet(c.IpfsAuthorities)
+	if err != nil {
+		return errors.New("The hash for the authorities is not correct: " + err.Error())
+	}
+	cleaned := cleanArrayJsonFromFileBytesOfIpfs(string(b))
+	authorities := []Authority{}
+	err = json.Unmarshal([]byte(cleaned), &authorities)
+	if err != nil {
+		return errors.New("The json for the authorities is not correct: " + err.Error())
+	}
+	c.authorities = map[string]int{}
+	for _, v := range authorities {
+		pubB, err := v.Bytes()
+		if err != nil {
+			return errors.New("The authority's public key " + string(v.PublicKeyHex) + " is not correct," + err.Error())
+		}
+		_, err = crypto.UnmarshalPublicKey(pubB)
+		if err != nil {
+			return errors.New("The authority's public key is not correct")
+		}
+		c.authorities[string(pubB)] = 0
+	}
+	return nil
+}
+
+func (c *configuration) SubmitAttestors() error {
+	sh := shell.NewShell(c.IpfsConnection)
+	b, err := sh.BlockGet(c.IpfsAttestors)
+	if err != nil {
+		return errors.New("The hash for the attestors is not correct: " + err.Error())
+	}
+	cleaned := cleanArrayJsonFromFileBytesOfIpfs(string(b))
+	attestors := []Attestor{}
+	err = json.Unmarshal([]byte(cleaned), &attestors)
+	if err != nil {
+		return errors.New("The json for the attestors is not correct: " + err.Error())
+	}
+	c.attestors = map[string]int{}
+	for _, v := range attestors {
+		pubB, err := v.Bytes()
+		if err != nil {
+			return errors.New("The attestor's public key " + string(v.PublicKeyHex) + " is not correct," + err.Error())
+		}
+		_, err = crypto.UnmarshalPublicKey(pubB)
+		if err != nil {
+			return errors.New("The attestor's public key is not correct")
+		}
+		c.attestors[string(pubB)] = 0
+	}
+	return nil
+}
+
+func (c *configuration) SubmitAuditAnchorers() error {
+	sh := shell.NewShell(c.IpfsConnection)
+	b, err := sh.BlockGet(c.IpfsAuditAnchorers)
+	if err != nil {
+		return errors.New("The hash for the audit anchorers is not correct: " + err.Error())
+	}
+	cleaned := cleanArrayJsonFromFileBytesOfIpfs(string(b))
+	anchorers := []AuditAnchorer{}
+	err = json.Unmarshal([]byte(cleaned), &anchorers)
+	if err != nil {
+		return errors.New("The json for the audit anchorers is not correct: " + err.Error())
+	}
+	c.auditAnchorers = map[string]string{}
+	logs := map[string]bool{}
+	for _, v := range anchorers {
+		pubB, err := v.Bytes()
+		if err != nil {
+			return errors.New("The audit anchorer's public key " + string(v.PublicKeyHex) + " is not correct," + err.Error())
+		}
+		_, err = crypto.UnmarshalPublicKey(pubB)
+		if err != nil {
+			return errors.New("The audit anchorer's public key is not correct")
+		}
+		_, exists := c.auditAnchorers[string(pubB)]
+		if exists || len(v.Log) == 0 || logs[v.Log] {
+			return errors.New("The audit anchorers should have one log each with its own id")
+		}
+		logs[v.Log] = true
+		c.auditAnchorers[string(pubB)] = v.Log
+	}
+	return nil
+}
+
+func (c *configuration) SubmitKycLevels() error {
+	sh := shell.NewShell(c.IpfsConnection)
+	b, err := sh.BlockGet(c.IpfsKyc)
+	if err != nil {
+		return errors.New("The hash for the KYC levels is not correct: " + err.Error())
+	}
+	cleaned := cleanArrayJsonFromFileBytesOfIpfs(string(b))
+	levels := []KycLevel{}
+	err = json.Unmarshal([]byte(cleaned), &levels)
+	if err != nil {
+		return errors.New("The json for the KYC levels is not correct: " + err.Error())
+	}
+	for _, v := range levels {
+		if v.Coins < 0 || v.Level < 1 {
+			return errors.New("The KYC levels should have coins of 0 or more and a level of 1 or more")
+		}
+	}
+	c.KycLevels = levels
+	return nil
+}
+
 var Conf = configuration{}
 
 func init() {
 	Conf.IpfsConnection = "127.0.0.1:5001"
 	Conf.AbciDaemon = "tcp://0.0.0.0:46658"
 	Conf.WaitingRequestTime = 5
+	Conf.QuerySkew = 2
 	Conf.inflators = map[string]int{}
 	Conf.watchers = map[string]int{}
+	Conf.authorities = map[string]int{}
+	Conf.attestors = map[string]int{}
 	Conf.Tax = Tax{}
 	Conf.IpfsTax = ""
 }
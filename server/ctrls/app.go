@@ -1,6 +1,9 @@
 package ctrls
 
 import (
+	"time"
+
+	"github.com/mragiadakos/theftcoin/server/confs"
 	"github.com/tendermint/abci/types"
 	dbm "github.com/tendermint/tmlibs/db"
 )
@@ -10,10 +13,22 @@ var _ types.Application = (*TCApplication)(nil)
 type TCApplication struct {
 	types.BaseApplication
 
-	state State
+	state  State
+	audit  *AuditLog
+	nonces *queryNonces
+
+	// the block that is delivered
+	blockHeight int64
+	blockTime   time.Time
+	blockTxs    int
+	taxBlock    TaxBlockJson
 }
 
 func NewTCApplication() *TCApplication {
 	state := loadState(dbm.NewMemDB())
-	return &TCApplication{state: state}
+	audit, err := OpenAuditLog(confs.Conf.AuditLog)
+	if err != nil {
+		panic(err)
+	}
+	return &TCApplication{state: state, audit: audit, nonces: newQueryNonces()}
 }
@@ -0,0 +1,194 @@
+package ctrls
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/tendermint/abci/types"
+)
+
+// AuditLog keeps the watchers' queries of the node, every entry has the hash of the previous one
+// so a change of an older entry breaks the hashes after it.
+type AuditLog struct {
+	mu      sync.Mutex
+	file    *os.File
+	entries []AuditEntry
+}
+
+func (ae *AuditEntry) ComputeHash() []byte {
+	e := *ae
+	e.Hash = nil
+	b, _ := json.Marshal(e)
+	h := sha256.Sum256(b)
+	return h[:]
+}
+
+// OpenAuditLog reads and checks the log of the file, an empty filename keeps the log only in the memory.
+func OpenAuditLog(filename string) (*AuditLog, error) {
+	al := &AuditLog{}
+	if len(filename) == 0 {
+		return al, nil
+	}
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry := AuditEntry{}
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			f.Close()
+			return nil, errors.New("The audit log's entry " + strconv.Itoa(len(al.entries)) + " is not json.")
+		}
+		if entry.Index != int64(len(al.entries)) || !bytes.Equal(entry.Previous, al.head().Hash) || !bytes.Equal(entry.Hash, entry.ComputeHash()) {
+			f.Close()
+			return nil, errors.New("The audit log's entry " + strconv.Itoa(len(al.entries)) + " is not correct.")
+		}
+		al.entries = append(al.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	al.file = f
+	return al, nil
+}
+
+func (al *AuditLog) Close() error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if al.file == nil {
+		return nil
+	}
+	err := al.file.Close()
+	al.file = nil
+	return err
+}
+
+func (al *AuditLog) head() AuditHead {
+	if len(al.entries) == 0 {
+		return AuditHead{Index: -1}
+	}
+	last := al.entries[len(al.entries)-1]
+	return AuditHead{Index: last.Index, Hash: last.Hash}
+}
+
+func (al *AuditLog) Head() AuditHead {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	return al.head()
+}
+
+func (al *AuditLog) Append(watcher []byte, user []byte, path string, nonce string) (AuditEntry, error) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	head := al.head()
+	entry := AuditEntry{
+		Index:    head.Index + 1,
+		Watcher:  watcher,
+		User:     user,
+		Path:     path,
+		Time:     time.Now().UTC(),
+		Nonce:    nonce,
+		Previous: head.Hash,
+	}
+	entry.Hash = entry.ComputeHash()
+	if al.file != nil {
+		b, _ := json.Marshal(entry)
+		_, err := al.file.Write(append(b, '\n'))
+		if err != nil {
+			return entry, err
+		}
+	}
+	al.entries = append(al.entries, entry)
+	return entry, nil
+}
+
+func (al *AuditLog) Entries(user []byte) []AuditEntry {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	entries := []AuditEntry{}
+	for _, entry := range al.entries {
+		if bytes.Equal(entry.User, user) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// anchored returns the highest index of the log that an anchor confirms,
+// and the anchors that do not match the log.
+func (al *AuditLog) anchored(anchors []AuditAnchor) (int64, []AuditAnchor) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	var index int64 = -1
+	mismatches := []AuditAnchor{}
+	for _, anchor := range anchors {
+		if anchor.Index >= int64(len(al.entries)) || !bytes.Equal(al.entries[anchor.Index].Hash, anchor.Hash) {
+			mismatches = append(mismatches, anchor)
+			continue
+		}
+		if anchor.Index > index {
+			index = anchor.Index
+		}
+	}
+	return index, mismatches
+}
+
+func (tca *TCApplication) validateAuditAnchor(dr DeliveryRequest) (uint32, error) {
+	anchor := dr.Data.AuditAnchor
+	if anchor == nil {
+		return CodeTypeUnauthorized, errors.New("The audit anchor is missing.")
+	}
+	if anchor.Index < 0 || len(anchor.Hash) != sha256.Size {
+		return CodeTypeUnauthorized, errors.New("The audit anchor is not correct.")
+	}
+	if !confs.Conf.AuditAnchorerExists(string(dr.Data.From), anchor.Log) {
+		return CodeTypeUnauthorized, errors.New("The account does not anchor the audit log " + anchor.Log + ".")
+	}
+	return CodeTypeOK, nil
+}
+
+func (tca *TCApplication) deliverAuditAnchor(dr DeliveryRequest) {
+	anchor := *dr.Data.AuditAnchor
+	anchor.From = dr.Data.From
+	anchor.Height = tca.blockHeight
+	tca.state.AddAuditAnchor(anchor)
+}
+
+func (tca *TCApplication) queryAuditHead() types.ResponseQuery {
+	head := tca.audit.Head()
+	head.Log = confs.Conf.AuditLogId
+	b, _ := json.Marshal(head)
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
+
+// queryAudit returns the watchers' queries of the account, only for the account.
+func (tca *TCApplication) queryAudit(qr QueryRequest) types.ResponseQuery {
+	if qr.Data.User != nil {
+		return types.ResponseQuery{Code: CodeTypeUnauthorized, Log: "Only the account can see its audit."}
+	}
+	ar := AuditResponse{}
+	ar.Entries = tca.audit.Entries(qr.Data.From)
+	ar.Head = tca.audit.Head()
+	ar.Head.Log = confs.Conf.AuditLogId
+	// the anchors of the other nodes' logs are not compared
+	anchors := []AuditAnchor{}
+	for _, anchor := range tca.state.GetAuditAnchors() {
+		if len(confs.Conf.AuditLogId) > 0 && anchor.Log == confs.Conf.AuditLogId {
+			anchors = append(anchors, anchor)
+		}
+	}
+	ar.Anchored, ar.Mismatches = tca.audit.anchored(anchors)
+	b, _ := json.Marshal(ar)
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
@@ -0,0 +1,147 @@
+package ctrls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-ipfs-api"
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+)
+
+func (tu *testUtils) ownQuery(t *testing.T, app *TCApplication, path string, from crypto.PrivKey) types.ResponseQuery {
+	qr := QueryRequest{}
+	qr.Data.Date = time.Now().UTC()
+	qr.Data.Nonce = tu.nonce()
+	qr.Data.From, _ = from.GetPublic().Bytes()
+	b, _ := json.Marshal(qr.Data)
+	var err error
+	qr.Signature, err = from.Sign(b)
+	assert.Nil(t, err)
+	b, _ = json.Marshal(qr)
+	return app.Query(types.RequestQuery{Path: path, Data: b})
+}
+
+func (tu *testUtils) addAuditAnchorers(t *testing.T, logs map[string]crypto.PubKey) {
+	anchorers := []confs.AuditAnchorer{}
+	for log, pubk := range logs {
+		b, _ := pubk.Bytes()
+		anchorers = append(anchorers, confs.AuditAnchorer{PublicKeyHex: hex.EncodeToString(b), Log: log})
+	}
+	anchB, _ := json.Marshal(anchorers)
+	sh := shell.NewShell(confs.Conf.IpfsConnection)
+	anchHash, err := sh.BlockPut(anchB)
+	assert.Nil(t, err)
+	confs.Conf.IpfsAuditAnchorers = anchHash
+	err = confs.Conf.SubmitAuditAnchorers()
+	assert.Nil(t, err)
+}
+
+func TestAuditWatcherQueriesSuccessfully(t *testing.T) {
+	tu := testUtils{}
+	dir, err := ioutil.TempDir("", "audit")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	confs.Conf.AuditLog = filepath.Join(dir, "audit.log")
+	defer func() { confs.Conf.AuditLog = "" }()
+	waiting := confs.Conf.WaitingRequestTime
+	confs.Conf.WaitingRequestTime = 5
+	defer func() { confs.Conf.WaitingRequestTime = waiting }()
+	app := NewTCApplication()
+
+	fromPrivk, fromPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	fromPubkB, _ := fromPubk.Bytes()
+	watcherPrivk, watcherPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	watcherPubkB, _ := watcherPubk.Bytes()
+	tu.addWatcher(t, watcherPubk)
+	tu.grantView(t, app, fromPrivk, watcherPubk, VIEW_HISTORY)
+
+	resp := tu.watcherQuery(t, app, "", watcherPrivk, fromPubk)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	resp = tu.watcherQuery(t, app, HISTORY_QUERY_PATH, watcherPrivk, fromPubk)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	// the watcher can not see the audit of the account
+	resp = tu.watcherQuery(t, app, AUDIT_QUERY_PATH, watcherPrivk, fromPubk)
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+
+	// the node's operator anchors the head of the log, only the log's anchorer can anchor it
+	anchorerPrivk, anchorerPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	otherPrivk, otherPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	conf := confs.Conf
+	defer func() { confs.Conf = conf }()
+	tu.addAuditAnchorers(t, map[string]crypto.PubKey{"node-1": anchorerPubk, "node-2": otherPubk})
+	confs.Conf.AuditLogId = "node-1"
+	resp = app.Query(types.RequestQuery{Path: AUDIT_HEAD_QUERY_PATH})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	head := AuditHead{}
+	json.Unmarshal(resp.Value, &head)
+	assert.Equal(t, "node-1", head.Log)
+	assert.Equal(t, int64(1), head.Index)
+	anchors := []struct {
+		from   crypto.PrivKey
+		anchor AuditAnchor
+		code   uint32
+	}{
+		{watcherPrivk, AuditAnchor{Log: "node-1", Index: head.Index, Hash: head.Hash}, CodeTypeUnauthorized},
+		{anchorerPrivk, AuditAnchor{Log: "node-2", Index: head.Index, Hash: head.Hash}, CodeTypeUnauthorized},
+		// the anchor of the other node's log is not compared with this log
+		{otherPrivk, AuditAnchor{Log: "node-2", Index: 0, Hash: make([]byte, 32)}, CodeTypeOK},
+		{anchorerPrivk, AuditAnchor{Log: "node-1", Index: head.Index, Hash: head.Hash}, CodeTypeOK},
+	}
+	for _, v := range anchors {
+		dr := tu.inflatorCoins(t, v.from, AUDIT_ANCHOR_ACTION, 0)
+		anchor := v.anchor
+		dr.Data.AuditAnchor = &anchor
+		tu.signDelivery(t, &dr, v.from)
+		b, _ := json.Marshal(dr)
+		assert.Equal(t, v.code, app.DeliverTx(b).Code)
+	}
+
+	resp = tu.ownQuery(t, app, AUDIT_QUERY_PATH, fromPrivk)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	ar := AuditResponse{}
+	err = json.Unmarshal(resp.Value, &ar)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ar.Entries))
+	assert.Equal(t, watcherPubkB, ar.Entries[0].Watcher)
+	assert.Equal(t, fromPubkB, ar.Entries[0].User)
+	assert.Equal(t, HISTORY_QUERY_PATH, ar.Entries[1].Path)
+	assert.Equal(t, ar.Entries[0].Hash, ar.Entries[1].Previous)
+	assert.Equal(t, int64(1), ar.Anchored)
+	assert.Equal(t, 0, len(ar.Mismatches))
+
+	// the log is read again from the file
+	app.audit.Close()
+	al, err := OpenAuditLog(confs.Conf.AuditLog)
+	assert.Nil(t, err)
+	assert.Equal(t, AuditHead{Index: head.Index, Hash: head.Hash}, al.Head())
+	al.Close()
+
+	// a changed entry breaks the chain
+	b, err := ioutil.ReadFile(confs.Conf.AuditLog)
+	assert.Nil(t, err)
+	lines := bytes.Split(bytes.TrimSpace(b), []byte("\n"))
+	assert.Equal(t, 2, len(lines))
+	entry := AuditEntry{}
+	json.Unmarshal(lines[0], &entry)
+	entry.Path = AUDIT_QUERY_PATH
+	lines[0], _ = json.Marshal(entry)
+	err = ioutil.WriteFile(confs.Conf.AuditLog, append(bytes.Join(lines, []byte("\n")), '\n'), 0600)
+	assert.Nil(t, err)
+	_, err = OpenAuditLog(confs.Conf.AuditLog)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,127 @@
+package ctrls
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/tendermint/abci/types"
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+const COMPLIANCE_REASON_MAX_LENGTH = 128
+
+// validateNotFrozen rejects the transactions of a frozen account and to a frozen account.
+func (tca *TCApplication) validateNotFrozen(dr DeliveryRequest) (uint32, error) {
+	if tca.state.IsFrozen(dr.Data.From) {
+		return CodeTypeUnauthorized, errors.New("The sender's account is frozen.")
+	}
+	if dr.Data.To != nil && tca.state.IsFrozen(*dr.Data.To) {
+		return CodeTypeUnauthorized, errors.New("The receiver's account is frozen.")
+	}
+	return CodeTypeOK, nil
+}
+
+// validateCompliance allows the authorities to freeze and unfreeze an account,
+// and to seize the coins of an account to the account of the court order.
+func (tca *TCApplication) validateCompliance(dr DeliveryRequest) (uint32, error) {
+	if !confs.Conf.AuthorityExists(string(dr.Data.From)) {
+		return CodeTypeUnauthorized, errors.New("You are not a compliance authority.")
+	}
+	c := dr.Data.Compliance
+	if c == nil {
+		return CodeTypeUnauthorized, errors.New("The compliance's account is missing.")
+	}
+	_, err := crypto.UnmarshalPublicKey(c.Account)
+	if err != nil {
+		return CodeTypeEncodingError, errors.New("The account's public key is not correct.")
+	}
+	if len(c.Reason) == 0 || len(c.Reason) > COMPLIANCE_REASON_MAX_LENGTH {
+		return CodeTypeUnauthorized, errors.New("The reason should have from 1 to " + strconv.Itoa(COMPLIANCE_REASON_MAX_LENGTH) + " characters.")
+	}
+
+	switch dr.Data.Action {
+	case FREEZE_ACTION:
+		if tca.state.IsFrozen(c.Account) {
+			return CodeTypeUnauthorized, errors.New("The account is already frozen.")
+		}
+	case UNFREEZE_ACTION:
+		if !tca.state.IsFrozen(c.Account) {
+			return CodeTypeUnauthorized, errors.New("The account is not frozen.")
+		}
+	case SEIZE_ACTION:
+		if dr.Data.To == nil {
+			return CodeTypeUnauthorized, errors.New("The receiver's public key is empty.")
+		}
+		_, err := crypto.UnmarshalPublicKey(*dr.Data.To)
+		if err != nil {
+			return CodeTypeEncodingError, errors.New("The receiver's public key is not correct.")
+		}
+		if bytes.Equal(*dr.Data.To, c.Account) {
+			return CodeTypeUnauthorized, errors.New("The seized coins can not go to the same account.")
+		}
+	}
+	return CodeTypeOK, nil
+}
+
+func (tca *TCApplication) deliverFreeze(dr DeliveryRequest) {
+	tca.state.SetFreeze(FreezeJson{
+		Account:   dr.Data.Compliance.Account,
+		Authority: dr.Data.From,
+		Reason:    dr.Data.Compliance.Reason,
+		Height:    tca.blockHeight,
+	})
+}
+
+func (tca *TCApplication) deliverUnfreeze(dr DeliveryRequest) {
+	tca.state.DeleteFreeze(dr.Data.Compliance.Account)
+}
+
+// deliverSeize moves the coins without tax, the account can be frozen.
+func (tca *TCApplication) deliverSeize(dr DeliveryRequest) error {
+	account, _ := crypto.UnmarshalPublicKey(dr.Data.Compliance.Account)
+	accountCj, _ := tca.state.GetCoins(account)
+	newAccountCoins := accountCj.Coins - dr.Data.Coins
+	if newAccountCoins < 0 {
+		return errors.New("The account does not have the coins to seize.")
+	}
+	tca.state.SetCoins(account, newAccountCoins)
+
+	to, _ := crypto.UnmarshalPublicKey(*dr.Data.To)
+	toCj, _ := tca.state.GetCoins(to)
+	tca.state.SetCoins(to, toCj.Coins+dr.Data.Coins)
+
+	tca.state.AddSeizure(tca.blockTxs, SeizureJson{
+		Account:   dr.Data.Compliance.Account,
+		To:        *dr.Data.To,
+		Coins:     dr.Data.Coins,
+		Authority: dr.Data.From,
+		Reason:    dr.Data.Compliance.Reason,
+		Height:    tca.blockHeight,
+	})
+	return nil
+}
+
+// complianceTags lets the indexer find the orders of an account.
+func complianceTags(dr DeliveryRequest) []cmn.KVPair {
+	return []cmn.KVPair{
+		{Key: []byte("compliance.action"), Value: []byte(dr.Data.Action)},
+		{Key: []byte("compliance.account"), Value: []byte(hex.EncodeToString(dr.Data.Compliance.Account))},
+		{Key: []byte("compliance.reason"), Value: []byte(dr.Data.Compliance.Reason)},
+	}
+}
+
+// queryCompliance returns the freeze and the seizures of the public key in the data.
+func (tca *TCApplication) queryCompliance(account []byte) types.ResponseQuery {
+	cr := ComplianceResponse{}
+	if fj, ok := tca.state.GetFreeze(account); ok {
+		cr.Frozen = &fj
+	}
+	cr.Seizures = tca.state.GetSeizures(account)
+	b, _ := json.Marshal(cr)
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
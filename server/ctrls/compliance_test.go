@@ -0,0 +1,119 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/ipfs/go-ipfs-api"
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+)
+
+func (tu *testUtils) addAuthority(t *testing.T, authority crypto.PubKey) {
+	b, _ := authority.Bytes()
+	authorities := []confs.Authority{{PublicKeyHex: hex.EncodeToString(b)}}
+	authB, _ := json.Marshal(authorities)
+	sh := shell.NewShell(confs.Conf.IpfsConnection)
+	authHash, err := sh.BlockPut(authB)
+	assert.Nil(t, err)
+	confs.Conf.IpfsAuthorities = authHash
+	err = confs.Conf.SubmitAuthorities()
+	assert.Nil(t, err)
+}
+
+func (tu *testUtils) pubBytes(pubk crypto.PubKey) []byte {
+	b, _ := pubk.Bytes()
+	return b
+}
+
+func TestComplianceFreezeStopsTheAccount(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+
+	authorityPrivk, authorityPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	conf := confs.Conf
+	defer func() { confs.Conf = conf }()
+	tu.addAuthority(t, authorityPubk)
+	accountPrivk, accountPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	otherPrivk, otherPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, accountPrivk, 100)
+
+	// only the authorities freeze
+	resp := tu.deliver(t, app, otherPrivk, DeliveryData{Action: FREEZE_ACTION, Compliance: &Compliance{Account: tu.pubBytes(accountPubk), Reason: "case-1"}})
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+	resp = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: FREEZE_ACTION, Compliance: &Compliance{Account: tu.pubBytes(accountPubk), Reason: ""}})
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+	resp = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: FREEZE_ACTION, Compliance: &Compliance{Account: tu.pubBytes(accountPubk), Reason: "case-1"}})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	resp = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: FREEZE_ACTION, Compliance: &Compliance{Account: tu.pubBytes(accountPubk), Reason: "case-1"}})
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+
+	// the frozen account can not send or receive
+	dr := tu.sendCoins(t, accountPrivk, otherPubk, taxHash, 10)
+	b, _ := json.Marshal(dr)
+	assert.Equal(t, CodeTypeUnauthorized, app.DeliverTx(b).Code)
+	tu.addCoins(t, app, otherPrivk, 100)
+	dr = tu.sendCoins(t, otherPrivk, accountPubk, taxHash, 10)
+	b, _ = json.Marshal(dr)
+	assert.Equal(t, CodeTypeUnauthorized, app.DeliverTx(b).Code)
+
+	qresp := app.Query(types.RequestQuery{Path: COMPLIANCE_QUERY_PATH, Data: tu.pubBytes(accountPubk)})
+	assert.Equal(t, CodeTypeOK, qresp.Code)
+	cr := ComplianceResponse{}
+	json.Unmarshal(qresp.Value, &cr)
+	assert.NotNil(t, cr.Frozen)
+	assert.Equal(t, "case-1", cr.Frozen.Reason)
+
+	resp = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: UNFREEZE_ACTION, Compliance: &Compliance{Account: tu.pubBytes(accountPubk), Reason: "case-1 closed"}})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	dr = tu.sendCoins(t, accountPrivk, otherPubk, taxHash, 10)
+	b, _ = json.Marshal(dr)
+	assert.Equal(t, CodeTypeOK, app.DeliverTx(b).Code)
+}
+
+func TestComplianceSeizeToTheCourtAccount(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+
+	authorityPrivk, authorityPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	conf := confs.Conf
+	defer func() { confs.Conf = conf }()
+	tu.addAuthority(t, authorityPubk)
+	accountPrivk, accountPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, courtPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	tu.addCoins(t, app, accountPrivk, 100)
+
+	resp := tu.deliver(t, app, authorityPrivk, DeliveryData{Action: FREEZE_ACTION, Compliance: &Compliance{Account: tu.pubBytes(accountPubk), Reason: "case-2"}})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	resp = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: SEIZE_ACTION, To: tu.to(courtPubk), Coins: 150, Compliance: &Compliance{Account: tu.pubBytes(accountPubk), Reason: "order-2"}})
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+	resp = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: SEIZE_ACTION, To: tu.to(courtPubk), Coins: 60, Compliance: &Compliance{Account: tu.pubBytes(accountPubk), Reason: "order-2"}})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	assert.Equal(t, "seize", tu.tag(resp, "compliance.action"))
+	assert.Equal(t, hex.EncodeToString(tu.pubBytes(accountPubk)), tu.tag(resp, "compliance.account"))
+	assert.Equal(t, "order-2", tu.tag(resp, "compliance.reason"))
+
+	// the seizure has no tax
+	cj, _ := app.state.GetCoins(accountPubk)
+	assert.Equal(t, float64(40), cj.Coins)
+	cj, _ = app.state.GetCoins(courtPubk)
+	assert.Equal(t, float64(60), cj.Coins)
+
+	seizures := app.state.GetSeizures(tu.pubBytes(accountPubk))
+	assert.Equal(t, 1, len(seizures))
+	assert.Equal(t, "order-2", seizures[0].Reason)
+	assert.Equal(t, tu.pubBytes(courtPubk), seizures[0].To)
+}
@@ -0,0 +1,233 @@
+package ctrls
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/anon"
+)
+
+const (
+	// The commitments hide integer units of a coin.
+	CONFIDENTIAL_UNITS          = 100
+	CONFIDENTIAL_BITS           = 40
+	CONFIDENTIAL_REMAINDER_BITS = 7
+	// The tax is rounded down, so the percentage of the amount is
+	// 100 times the tax and a remainder up to 99.
+	CONFIDENTIAL_MAX_REMAINDER = 99
+)
+
+// pedersenH is the second generator of the commitments,
+// it is picked from a seed so nobody knows its logarithm to the base.
+var pedersenH = suite.Point().Pick(suite.XOF([]byte("theftcoin-pedersen-h")))
+
+func unmarshalCommitment(b []byte) (kyber.Point, error) {
+	if len(b) == 0 {
+		return suite.Point().Null(), nil
+	}
+	p := suite.Point()
+	err := p.UnmarshalBinary(b)
+	if err != nil {
+		return nil, errors.New("The commitment is not a point of the curve.")
+	}
+	return p, nil
+}
+
+func valueCommitment(v int64) kyber.Point {
+	return suite.Point().Mul(suite.Scalar().SetInt64(v), pedersenH)
+}
+
+// ConfidentialUnits returns the coins in units, the coins should not have more decimals than the units.
+func ConfidentialUnits(coins float64) (int64, error) {
+	units := math.Floor(coins*CONFIDENTIAL_UNITS + 0.5)
+	if math.Abs(units-coins*CONFIDENTIAL_UNITS) > 1e-6 {
+		return 0, errors.New("The coins have more than " + strconv.Itoa(int(math.Log10(CONFIDENTIAL_UNITS))) + " decimals.")
+	}
+	if units <= 0 || units >= 1<<CONFIDENTIAL_BITS {
+		return 0, errors.New("The coins are out of the confidential range.")
+	}
+	return int64(units), nil
+}
+
+func RangeProofMessage(bit []byte) []byte {
+	return append([]byte("theftcoin-range:"), bit...)
+}
+
+// VerifyRangeProof checks that every bit hides 0 or 1, and that the bits
+// multiplied by their powers of two sum to the commitment.
+func VerifyRangeProof(c kyber.Point, proof *RangeProof, bits int) error {
+	if proof == nil {
+		return errors.New("The range proof is missing.")
+	}
+	if len(proof.Bits) != bits || len(proof.Signatures) != bits {
+		return errors.New("The range proof does not have " + strconv.Itoa(bits) + " bits.")
+	}
+	sum := suite.Point().Null()
+	for i := range proof.Bits {
+		bit, err := unmarshalCommitment(proof.Bits[i])
+		if err != nil {
+			return err
+		}
+		set := anon.Set{bit, suite.Point().Sub(bit, pedersenH)}
+		_, err = anon.Verify(suite, RangeProofMessage(proof.Bits[i]), set, nil, proof.Signatures[i])
+		if err != nil {
+			return errors.New("The bit " + strconv.Itoa(i) + " of the range proof is not 0 or 1.")
+		}
+		sum.Add(sum, suite.Point().Mul(suite.Scalar().SetInt64(int64(1)<<uint(i)), bit))
+	}
+	if !sum.Equal(c) {
+		return errors.New("The range proof is not for the commitment.")
+	}
+	return nil
+}
+
+// VerifyConfidentialTax checks that the amount and the tax are in the range,
+// and that the tax is the percentage of the amount rounded down.
+func VerifyConfidentialTax(conf Confidential, percentage int) (amount kyber.Point, tax kyber.Point, err error) {
+	if len(conf.Amount) == 0 || len(conf.Tax) == 0 {
+		return nil, nil, errors.New("The commitments of the amount and the tax are missing.")
+	}
+	amount, err = unmarshalCommitment(conf.Amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	tax, err = unmarshalCommitment(conf.Tax)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = VerifyRangeProof(amount, conf.AmountProof, CONFIDENTIAL_BITS)
+	if err != nil {
+		return nil, nil, errors.New("The amount: " + err.Error())
+	}
+	err = VerifyRangeProof(tax, conf.TaxProof, CONFIDENTIAL_BITS)
+	if err != nil {
+		return nil, nil, errors.New("The tax: " + err.Error())
+	}
+
+	if len(conf.RemainderProofs) != 2 {
+		return nil, nil, errors.New("The tax needs two range proofs for its remainder.")
+	}
+	// remainder = percentage*amount - 100*tax, it is 0 or more and 99 or less
+	remainder := suite.Point().Sub(
+		suite.Point().Mul(suite.Scalar().SetInt64(int64(percentage)), amount),
+		suite.Point().Mul(suite.Scalar().SetInt64(100), tax))
+	err = VerifyRangeProof(remainder, &conf.RemainderProofs[0], CONFIDENTIAL_REMAINDER_BITS)
+	if err != nil {
+		return nil, nil, errors.New("The tax's remainder: " + err.Error())
+	}
+	err = VerifyRangeProof(suite.Point().Sub(valueCommitment(CONFIDENTIAL_MAX_REMAINDER), remainder), &conf.RemainderProofs[1], CONFIDENTIAL_REMAINDER_BITS)
+	if err != nil {
+		return nil, nil, errors.New("The tax's remainder: " + err.Error())
+	}
+	return amount, tax, nil
+}
+
+func (tca *TCApplication) validateConfidentialCoins(dr DeliveryRequest) (uint32, error) {
+	if dr.Data.Confidential == nil {
+		return CodeTypeUnauthorized, errors.New("The confidential data is missing.")
+	}
+	_, err := ConfidentialUnits(dr.Data.Coins)
+	if err != nil {
+		return CodeTypeUnauthorized, err
+	}
+	return CodeTypeOK, nil
+}
+
+func (tca *TCApplication) validateConfidentialSend(dr DeliveryRequest) (uint32, error) {
+	if dr.Data.Coins != 0 {
+		return CodeTypeUnauthorized, errors.New("The coins of a confidential send are only in the commitment.")
+	}
+	if dr.Data.Confidential == nil {
+		return CodeTypeUnauthorized, errors.New("The confidential data is missing.")
+	}
+	code, err := tca.validateSend(dr)
+	if err != nil {
+		return code, err
+	}
+	conf := dr.Data.Confidential
+	if len(conf.ReceiverNote) == 0 || len(conf.TaxNote) == 0 {
+		return CodeTypeUnauthorized, errors.New("The notes for the receiver and the tax receiver are missing.")
+	}
+	_, _, err = VerifyConfidentialTax(*conf, confs.Conf.Tax.Percentage)
+	if err != nil {
+		return CodeTypeUnauthorized, err
+	}
+	return CodeTypeOK, nil
+}
+
+// confidentialBalance returns the balance of the account after subtracting the commitment,
+// if the range proof shows that the balance is not negative.
+func (tca *TCApplication) confidentialBalance(from []byte, c kyber.Point, proof *RangeProof) ([]byte, error) {
+	cj := tca.state.GetConfidential(from)
+	balance, err := unmarshalCommitment(cj.Balance)
+	if err != nil {
+		return nil, err
+	}
+	balance.Sub(balance, c)
+	err = VerifyRangeProof(balance, proof, CONFIDENTIAL_BITS)
+	if err != nil {
+		return nil, errors.New("You dont have enough confidential money: " + err.Error())
+	}
+	return balance.MarshalBinary()
+}
+
+func (tca *TCApplication) addConfidential(to []byte, c kyber.Point, note []byte) {
+	cj := tca.state.GetConfidential(to)
+	balance, err := unmarshalCommitment(cj.Balance)
+	if err != nil {
+		balance = suite.Point().Null()
+	}
+	balance.Add(balance, c)
+	cj.Balance, _ = balance.MarshalBinary()
+	if len(note) > 0 {
+		cj.Notes = append(cj.Notes, note)
+	}
+	tca.state.SetConfidential(to, cj)
+}
+
+func (tca *TCApplication) deliverConfidentialDeposit(dr DeliveryRequest) error {
+	err := tca.deliverRemove(dr)
+	if err != nil {
+		return errors.New("You dont have enough money to deposit.")
+	}
+	units, _ := ConfidentialUnits(dr.Data.Coins)
+	tca.addConfidential(dr.Data.From, valueCommitment(units), dr.Data.Confidential.SenderNote)
+	return nil
+}
+
+func (tca *TCApplication) deliverConfidentialWithdraw(dr DeliveryRequest) error {
+	units, _ := ConfidentialUnits(dr.Data.Coins)
+	balance, err := tca.confidentialBalance(dr.Data.From, valueCommitment(units), dr.Data.Confidential.BalanceProof)
+	if err != nil {
+		return err
+	}
+	tca.state.SetConfidential(dr.Data.From, ConfidentialJson{Balance: balance, Notes: [][]byte{dr.Data.Confidential.SenderNote}})
+	tca.deliverAdd(dr)
+	return nil
+}
+
+func (tca *TCApplication) deliverConfidentialSend(dr DeliveryRequest) error {
+	conf := dr.Data.Confidential
+	amount, tax, err := VerifyConfidentialTax(*conf, confs.Conf.Tax.Percentage)
+	if err != nil {
+		return err
+	}
+	balance, err := tca.confidentialBalance(dr.Data.From, amount, conf.BalanceProof)
+	if err != nil {
+		return err
+	}
+	tca.state.SetConfidential(dr.Data.From, ConfidentialJson{Balance: balance, Notes: [][]byte{conf.SenderNote}})
+
+	tca.addConfidential(*dr.Data.To, suite.Point().Sub(amount, tax), conf.ReceiverNote)
+
+	taxReceiver, err := confs.Conf.TaxReceiver.Bytes()
+	if err != nil {
+		return err
+	}
+	tca.addConfidential(taxReceiver, tax, conf.TaxNote)
+	tca.addConfidentialTax(tax)
+	return nil
+}
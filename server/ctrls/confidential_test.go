@@ -0,0 +1,189 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/stretchr/testify/assert"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/anon"
+)
+
+func (tu *testUtils) commit(v int64, r kyber.Scalar) kyber.Point {
+	return suite.Point().Add(suite.Point().Mul(r, nil), valueCommitment(v))
+}
+
+func (tu *testUtils) commitBytes(v int64, r kyber.Scalar) []byte {
+	b, _ := tu.commit(v, r).MarshalBinary()
+	return b
+}
+
+// proveRange commits to every bit of the value, the blindings of the bits sum to the blinding r.
+func (tu *testUtils) proveRange(t *testing.T, v int64, r kyber.Scalar, bits int) *RangeProof {
+	blindings := make([]kyber.Scalar, bits)
+	sum := suite.Scalar().Zero()
+	for i := 1; i < bits; i++ {
+		blindings[i] = suite.Scalar().Pick(suite.RandomStream())
+		sum.Add(sum, suite.Scalar().Mul(suite.Scalar().SetInt64(int64(1)<<uint(i)), blindings[i]))
+	}
+	blindings[0] = suite.Scalar().Sub(r, sum)
+
+	proof := &RangeProof{}
+	for i := 0; i < bits; i++ {
+		bit := int((v >> uint(i)) & 1)
+		c := tu.commit(int64(bit), blindings[i])
+		cb, err := c.MarshalBinary()
+		assert.Nil(t, err)
+		set := anon.Set{c, suite.Point().Sub(c, pedersenH)}
+		proof.Bits = append(proof.Bits, cb)
+		proof.Signatures = append(proof.Signatures, anon.Sign(suite, RangeProofMessage(cb), set, nil, bit, blindings[i]))
+	}
+	return proof
+}
+
+func (tu *testUtils) signDelivery(t *testing.T, dr *DeliveryRequest, from crypto.PrivKey) {
+	b, _ := json.Marshal(dr.Data)
+	var err error
+	dr.Signature, err = from.Sign(b)
+	assert.Nil(t, err)
+}
+
+// confidentialSend sends the units of the balance with the opening (balance, balanceR),
+// the tax is the one that the server expects only if the tax is not changed.
+func (tu *testUtils) confidentialSend(t *testing.T, from crypto.PrivKey, to crypto.PubKey, taxHash string,
+	balance int64, balanceR kyber.Scalar, amount int64, tax int64) (DeliveryRequest, kyber.Scalar, kyber.Scalar) {
+	dr := tu.sendCoins(t, from, to, taxHash, 0)
+	dr.Data.Action = CONFIDENTIAL_SEND_ACTION
+
+	rA := suite.Scalar().Pick(suite.RandomStream())
+	rT := suite.Scalar().Pick(suite.RandomStream())
+	percentage := int64(confs.Conf.Tax.Percentage)
+	remainder := percentage*amount - 100*tax
+	remainderR := suite.Scalar().Sub(suite.Scalar().Mul(suite.Scalar().SetInt64(percentage), rA), suite.Scalar().Mul(suite.Scalar().SetInt64(100), rT))
+
+	conf := Confidential{}
+	conf.Amount = tu.commitBytes(amount, rA)
+	conf.Tax = tu.commitBytes(tax, rT)
+	conf.AmountProof = tu.proveRange(t, amount, rA, CONFIDENTIAL_BITS)
+	conf.TaxProof = tu.proveRange(t, tax, rT, CONFIDENTIAL_BITS)
+	conf.RemainderProofs = []RangeProof{
+		*tu.proveRange(t, remainder, remainderR, CONFIDENTIAL_REMAINDER_BITS),
+		*tu.proveRange(t, CONFIDENTIAL_MAX_REMAINDER-remainder, suite.Scalar().Neg(remainderR), CONFIDENTIAL_REMAINDER_BITS),
+	}
+	conf.BalanceProof = tu.proveRange(t, balance-amount, suite.Scalar().Sub(balanceR, rA), CONFIDENTIAL_BITS)
+	conf.SenderNote = []byte("sender")
+	conf.ReceiverNote = []byte("receiver")
+	conf.TaxNote = []byte("tax")
+	dr.Data.Confidential = &conf
+	tu.signDelivery(t, &dr, from)
+	return dr, rA, rT
+}
+
+func (tu *testUtils) confidentialDeposit(t *testing.T, app *TCApplication, from crypto.PrivKey, coins float64) {
+	fromPubkB, _ := from.GetPublic().Bytes()
+	confs.Conf.IpfsInflators = tu.addInflator(t, fromPubkB)
+	confs.Conf.SubmitInflators()
+
+	dr := tu.inflatorCoins(t, from, ADD_ACTION, coins)
+	b, _ := json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	dr = tu.inflatorCoins(t, from, CONFIDENTIAL_DEPOSIT_ACTION, coins)
+	dr.Data.Confidential = &Confidential{SenderNote: []byte("deposit")}
+	tu.signDelivery(t, &dr, from)
+	b, _ = json.Marshal(dr)
+	resp = app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+}
+
+func TestConfidentialSendSuccessfully(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+
+	fromPrivk, fromPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, toPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+
+	tu.confidentialDeposit(t, app, fromPrivk, 100)
+	fromCj, _ := app.state.GetCoins(fromPubk)
+	assert.Equal(t, float64(0), fromCj.Coins)
+	fromPubkB, _ := fromPubk.Bytes()
+	assert.Equal(t, tu.commitBytes(10000, suite.Scalar().Zero()), app.state.GetConfidential(fromPubkB).Balance)
+
+	dr, rA, rT := tu.confidentialSend(t, fromPrivk, toPubk, taxHash, 10000, suite.Scalar().Zero(), 5000, 500)
+	b, _ := json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	toPubkB, _ := toPubk.Bytes()
+	taxPubkB, _ := taxPubk.Bytes()
+	fromConf := app.state.GetConfidential(fromPubkB)
+	assert.Equal(t, tu.commitBytes(5000, suite.Scalar().Neg(rA)), fromConf.Balance)
+	assert.Equal(t, [][]byte{[]byte("sender")}, fromConf.Notes)
+	toConf := app.state.GetConfidential(toPubkB)
+	assert.Equal(t, tu.commitBytes(4500, suite.Scalar().Sub(rA, rT)), toConf.Balance)
+	assert.Equal(t, [][]byte{[]byte("receiver")}, toConf.Notes)
+	assert.Equal(t, tu.commitBytes(500, rT), app.state.GetConfidential(taxPubkB).Balance)
+
+	// the sender withdraws the rest of the balance
+	dr = tu.inflatorCoins(t, fromPrivk, CONFIDENTIAL_WITHDRAW_ACTION, 50)
+	dr.Data.Confidential = &Confidential{
+		BalanceProof: tu.proveRange(t, 0, suite.Scalar().Neg(rA), CONFIDENTIAL_BITS),
+		SenderNote:   []byte("withdraw"),
+	}
+	tu.signDelivery(t, &dr, fromPrivk)
+	b, _ = json.Marshal(dr)
+	resp = app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	fromCj, _ = app.state.GetCoins(fromPubk)
+	assert.Equal(t, float64(50), fromCj.Coins)
+}
+
+func TestConfidentialSendFailWrongTax(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+
+	fromPrivk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, toPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+
+	tu.confidentialDeposit(t, app, fromPrivk, 100)
+
+	// the tax is one unit less than 10% of the amount
+	dr, _, _ := tu.confidentialSend(t, fromPrivk, toPubk, taxHash, 10000, suite.Scalar().Zero(), 5000, 499)
+	b, _ := json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+}
+
+func TestConfidentialSendFailNotEnoughBalance(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+
+	fromPrivk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, toPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+
+	tu.confidentialDeposit(t, app, fromPrivk, 100)
+
+	dr, _, _ := tu.confidentialSend(t, fromPrivk, toPubk, taxHash, 10000, suite.Scalar().Zero(), 20000, 2000)
+	b, _ := json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+}
@@ -0,0 +1,27 @@
+package ctrls
+
+import (
+	"bytes"
+	"errors"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/group/edwards25519"
+)
+
+var suite = edwards25519.NewBlakeSHA256Ed25519()
+
+// The protobuf header of an Ed25519 public key, the key type and the key's length.
+var ed25519PublicKeyHeader = []byte{0x08, 0x01, 0x12, 0x20}
+
+// publicKeyPoint returns the curve's point of an Ed25519 public key.
+func publicKeyPoint(pub []byte) (kyber.Point, error) {
+	if len(pub) != len(ed25519PublicKeyHeader)+32 || !bytes.HasPrefix(pub, ed25519PublicKeyHeader) {
+		return nil, errors.New("The public key is not an Ed25519 key.")
+	}
+	p := suite.Point()
+	err := p.UnmarshalBinary(pub[len(ed25519PublicKeyHeader):])
+	if err != nil {
+		return nil, errors.New("The public key is not a point of the curve.")
+	}
+	return p, nil
+}
@@ -34,14 +34,63 @@ func (tca *TCApplication) validateSend(dr DeliveryRequest) (uint32, error) {
 	if confs.Conf.IpfsTax != *dr.Data.TaxHash {
 		return CodeTypeUnauthorized, errors.New("The tax is not a validated UUID.")
 	}
+
+	if dr.Data.Stealth != nil {
+		code, err := tca.validateStealth(dr)
+		if err != nil {
+			return code, err
+		}
+	}
+	if dr.Data.Memo != nil {
+		code, err := tca.validateMemo(dr)
+		if err != nil {
+			return code, err
+		}
+	}
 	return CodeTypeOK, nil
 }
 
+// The actions that do not move public coins.
+var coinlessActions = map[ActionStruct]bool{
+	CONFIDENTIAL_SEND_ACTION:     true,
+	VIEW_GRANT_ACTION:            true,
+	VIEW_REVOKE_ACTION:           true,
+	AUDIT_ANCHOR_ACTION:          true,
+	ESCROW_RELEASE_ACTION:        true,
+	ESCROW_REFUND_ACTION:         true,
+	HTLC_CLAIM_ACTION:            true,
+	HTLC_REFUND_ACTION:           true,
+	STANDING_ORDER_CANCEL_ACTION: true,
+	FREEZE_ACTION:                true,
+	UNFREEZE_ACTION:              true,
+	SET_TIER_ACTION:              true,
+	ASSIGN_TIER_ACTION:           true,
+	ATTEST_ACTION:                true,
+	ATTEST_REVOKE_ACTION:         true,
+}
+
 func (tca *TCApplication) validateDelivery(dr DeliveryRequest) (uint32, error) {
-	if dr.Data.Coins <= 0 {
+	if dr.Data.Coins <= 0 && !coinlessActions[dr.Data.Action] {
 		return CodeTypeUnauthorized, errors.New("Coins can not be the number of zero or negative.")
 	}
 
+	if dr.Data.Stealth != nil && dr.Data.Action != SEND_ACTION {
+		return CodeTypeUnauthorized, errors.New("Only the send can be stealth.")
+	}
+	if dr.Data.Memo != nil && dr.Data.Action != SEND_ACTION {
+		return CodeTypeUnauthorized, errors.New("Only the send can have a memo.")
+	}
+	if dr.Data.Compliance != nil && dr.Data.Action != FREEZE_ACTION && dr.Data.Action != UNFREEZE_ACTION && dr.Data.Action != SEIZE_ACTION {
+		return CodeTypeUnauthorized, errors.New("Only the compliance actions can have a compliance's account.")
+	}
+	if dr.Data.Tier != nil && dr.Data.Action != SET_TIER_ACTION && dr.Data.Action != ASSIGN_TIER_ACTION {
+		return CodeTypeUnauthorized, errors.New("Only the tier's actions can have a tier.")
+	}
+	if dr.Data.Attestation != nil && dr.Data.Action != ATTEST_ACTION && dr.Data.Action != ATTEST_REVOKE_ACTION {
+		return CodeTypeUnauthorized, errors.New("Only the attestation's actions can have an attestation.")
+	}
+
+	// the one-time keys of the stealth sends sign as the other Ed25519 keys
 	ver, err := dr.VerifySignature()
 	if err != nil {
 		return CodeTypeEncodingError, err
@@ -49,6 +98,14 @@ func (tca *TCApplication) validateDelivery(dr DeliveryRequest) (uint32, error) {
 	if !ver {
 		return CodeTypeUnauthorized, errors.New("The signature does not validate the transaction.")
 	}
+	code, err := tca.validateNotFrozen(dr)
+	if err != nil {
+		return code, err
+	}
+	code, err = tca.validateLimits(dr)
+	if err != nil {
+		return code, err
+	}
 	switch dr.Data.Action {
 	case ADD_ACTION, REMOVE_ACTION:
 		code, err := tca.validateInflators(dr)
@@ -60,6 +117,75 @@ func (tca *TCApplication) validateDelivery(dr DeliveryRequest) (uint32, error) {
 		if err != nil {
 			return code, err
 		}
+	case CONFIDENTIAL_DEPOSIT_ACTION, CONFIDENTIAL_WITHDRAW_ACTION:
+		code, err := tca.validateConfidentialCoins(dr)
+		if err != nil {
+			return code, err
+		}
+	case CONFIDENTIAL_SEND_ACTION:
+		code, err := tca.validateConfidentialSend(dr)
+		if err != nil {
+			return code, err
+		}
+	case VIEW_GRANT_ACTION, VIEW_REVOKE_ACTION:
+		code, err := tca.validateViewGrant(dr)
+		if err != nil {
+			return code, err
+		}
+	case AUDIT_ANCHOR_ACTION:
+		code, err := tca.validateAuditAnchor(dr)
+		if err != nil {
+			return code, err
+		}
+	case ESCROW_OPEN_ACTION:
+		code, err := tca.validateEscrowOpen(dr)
+		if err != nil {
+			return code, err
+		}
+	case ESCROW_RELEASE_ACTION, ESCROW_REFUND_ACTION:
+		code, err := tca.validateEscrowClose(dr)
+		if err != nil {
+			return code, err
+		}
+	case HTLC_LOCK_ACTION:
+		code, err := tca.validateHtlcLock(dr)
+		if err != nil {
+			return code, err
+		}
+	case HTLC_CLAIM_ACTION, HTLC_REFUND_ACTION:
+		code, err := tca.validateHtlcClose(dr)
+		if err != nil {
+			return code, err
+		}
+	case STANDING_ORDER_ACTION:
+		code, err := tca.validateStandingOrder(dr)
+		if err != nil {
+			return code, err
+		}
+	case STANDING_ORDER_CANCEL_ACTION:
+		code, err := tca.validateStandingOrderCancel(dr)
+		if err != nil {
+			return code, err
+		}
+	case FREEZE_ACTION, UNFREEZE_ACTION, SEIZE_ACTION:
+		code, err := tca.validateCompliance(dr)
+		if err != nil {
+			return code, err
+		}
+	case SET_TIER_ACTION, ASSIGN_TIER_ACTION:
+		code, err := tca.validateTier(dr)
+		if err != nil {
+			return code, err
+		}
+	case ATTEST_ACTION, ATTEST_REVOKE_ACTION:
+		code, err := tca.validateAttestation(dr)
+		if err != nil {
+			return code, err
+		}
+	}
+	code, err = tca.validateKyc(dr)
+	if err != nil {
+		return code, err
 	}
 
 	return CodeTypeOK, nil
@@ -83,6 +209,10 @@ func (tca *TCApplication) deliverRemove(dr DeliveryRequest) error {
 	return nil
 }
 
+func (tca *TCApplication) taxCoins(coins float64) float64 {
+	return coins * float64(confs.Conf.Tax.Percentage) / 100
+}
+
 func (tca *TCApplication) deliverSend(dr DeliveryRequest) error {
 	from, _ := crypto.UnmarshalPublicKey(dr.Data.From)
 	fromCj, _ := tca.state.GetCoins(from)
@@ -92,7 +222,7 @@ func (tca *TCApplication) deliverSend(dr DeliveryRequest) error {
 	}
 	tca.state.SetCoins(from, newFromCoins)
 
-	taxCoins := dr.Data.Coins * float64(confs.Conf.Tax.Percentage) / 100
+	taxCoins := tca.taxCoins(dr.Data.Coins)
 	toCoins := dr.Data.Coins - taxCoins
 
 	to, _ := crypto.UnmarshalPublicKey(*dr.Data.To)
@@ -112,6 +242,7 @@ func (tca *TCApplication) DeliverTx(tx []byte) types.ResponseDeliverTx {
 	if err != nil {
 		return types.ResponseDeliverTx{Code: CodeTypeEncodingError, Log: "The json is not correct."}
 	}
+	taxReceiverCoins := tca.taxReceiverCoins()
 
 	code, err := tca.validateDelivery(dr)
 	if err != nil {
@@ -131,7 +262,76 @@ func (tca *TCApplication) DeliverTx(tx []byte) types.ResponseDeliverTx {
 		if err != nil {
 			return types.ResponseDeliverTx{Code: CodeTypeUnauthorized, Log: err.Error()}
 		}
+	case CONFIDENTIAL_DEPOSIT_ACTION:
+		err := tca.deliverConfidentialDeposit(dr)
+		if err != nil {
+			return types.ResponseDeliverTx{Code: CodeTypeUnauthorized, Log: err.Error()}
+		}
+	case CONFIDENTIAL_WITHDRAW_ACTION:
+		err := tca.deliverConfidentialWithdraw(dr)
+		if err != nil {
+			return types.ResponseDeliverTx{Code: CodeTypeUnauthorized, Log: err.Error()}
+		}
+	case CONFIDENTIAL_SEND_ACTION:
+		err := tca.deliverConfidentialSend(dr)
+		if err != nil {
+			return types.ResponseDeliverTx{Code: CodeTypeUnauthorized, Log: err.Error()}
+		}
+	case VIEW_GRANT_ACTION:
+		tca.deliverViewGrant(dr)
+	case VIEW_REVOKE_ACTION:
+		err := tca.deliverViewRevoke(dr)
+		if err != nil {
+			return types.ResponseDeliverTx{Code: CodeTypeUnauthorized, Log: err.Error()}
+		}
+	case AUDIT_ANCHOR_ACTION:
+		tca.deliverAuditAnchor(dr)
+	case ESCROW_OPEN_ACTION:
+		err := tca.deliverEscrowOpen(dr)
+		if err != nil {
+			return types.ResponseDeliverTx{Code: CodeTypeUnauthorized, Log: err.Error()}
+		}
+	case ESCROW_RELEASE_ACTION:
+		tca.deliverEscrowRelease(dr)
+	case ESCROW_REFUND_ACTION:
+		tca.deliverEscrowRefund(dr)
+	case HTLC_LOCK_ACTION:
+		err := tca.deliverHtlcLock(dr)
+		if err != nil {
+			return types.ResponseDeliverTx{Code: CodeTypeUnauthorized, Log: err.Error()}
+		}
+	case HTLC_CLAIM_ACTION:
+		tca.deliverHtlcClaim(dr)
+	case HTLC_REFUND_ACTION:
+		tca.deliverHtlcRefund(dr)
+	case STANDING_ORDER_ACTION:
+		tca.deliverStandingOrder(dr)
+	case STANDING_ORDER_CANCEL_ACTION:
+		tca.deliverStandingOrderCancel(dr)
+	case FREEZE_ACTION:
+		tca.deliverFreeze(dr)
+	case UNFREEZE_ACTION:
+		tca.deliverUnfreeze(dr)
+	case SEIZE_ACTION:
+		err := tca.deliverSeize(dr)
+		if err != nil {
+			return types.ResponseDeliverTx{Code: CodeTypeUnauthorized, Log: err.Error()}
+		}
+	case SET_TIER_ACTION, ASSIGN_TIER_ACTION:
+		tca.deliverTier(dr)
+	case ATTEST_ACTION, ATTEST_REVOKE_ACTION:
+		tca.deliverAttestation(dr)
+	}
+	// the standing order counts its payments
+	if limitedActions[dr.Data.Action] && dr.Data.Action != STANDING_ORDER_ACTION {
+		tca.recordSent(dr.Data.From, dr.Data.Coins)
 	}
 
-	return types.ResponseDeliverTx{Code: CodeTypeOK}
+	entry := tca.recordHistory(tx, dr)
+	tca.reconcileTax(entry, tca.deliveryCoins(dr, entry), taxReceiverCoins)
+	tags := deliveryTags(entry)
+	if dr.Data.Compliance != nil {
+		tags = append(tags, complianceTags(dr)...)
+	}
+	return types.ResponseDeliverTx{Code: CodeTypeOK, Tags: tags}
 }
@@ -11,6 +11,7 @@ import (
 	crypto "github.com/libp2p/go-libp2p-crypto"
 	"github.com/mragiadakos/theftcoin/server/confs"
 	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
 )
 
 type testUtils struct{}
@@ -50,6 +51,22 @@ func (tu *testUtils) sendCoins(t *testing.T, from crypto.PrivKey, to crypto.PubK
 	return dr
 }
 
+// deliver signs the data of the sender and delivers it.
+func (tu *testUtils) deliver(t *testing.T, app *TCApplication, from crypto.PrivKey, dd DeliveryData) types.ResponseDeliverTx {
+	var err error
+	dd.From, err = from.GetPublic().Bytes()
+	assert.Nil(t, err)
+	dr := DeliveryRequest{Data: dd}
+	tu.signDelivery(t, &dr, from)
+	b, _ := json.Marshal(dr)
+	return app.DeliverTx(b)
+}
+
+func (tu *testUtils) to(pubk crypto.PubKey) *[]byte {
+	b, _ := pubk.Bytes()
+	return &b
+}
+
 func (tu *testUtils) addInflator(t *testing.T, b []byte) string {
 	sh := shell.NewShell(confs.Conf.IpfsConnection)
 	inf := confs.Inflator{}
@@ -62,6 +79,19 @@ func (tu *testUtils) addInflator(t *testing.T, b []byte) string {
 	return hash
 }
 
+func (tu *testUtils) submitTax(t *testing.T, taxPubk crypto.PubKey) string {
+	sh := shell.NewShell(confs.Conf.IpfsConnection)
+	taxPubkB, _ := taxPubk.Bytes()
+	tax := confs.Tax{Percentage: 10, PublicKeyHex: hex.EncodeToString(taxPubkB)}
+	b, _ := json.Marshal(tax)
+	taxHash, err := sh.BlockPut(b)
+	assert.Nil(t, err)
+	confs.Conf.IpfsTax = taxHash
+	err = confs.Conf.SubmitTax()
+	assert.Nil(t, err)
+	return taxHash
+}
+
 func TestAnyTransactionFailSignature(t *testing.T) {
 	tu := testUtils{}
 	privk, pubk, err := crypto.GenerateEd25519Key(rand.Reader)
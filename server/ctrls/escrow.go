@@ -0,0 +1,155 @@
+package ctrls
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/tendermint/abci/types"
+)
+
+const ESCROW_ID_MAX_LENGTH = 64
+
+func (tca *TCApplication) validateEscrowOpen(dr DeliveryRequest) (uint32, error) {
+	escrow := dr.Data.Escrow
+	if escrow == nil {
+		return CodeTypeUnauthorized, errors.New("The escrow is missing.")
+	}
+	if len(escrow.ID) == 0 || len(escrow.ID) > ESCROW_ID_MAX_LENGTH {
+		return CodeTypeUnauthorized, errors.New("The escrow's ID should have from 1 to " + strconv.Itoa(ESCROW_ID_MAX_LENGTH) + " characters.")
+	}
+	if _, ok := tca.state.GetEscrow(escrow.ID); ok {
+		return CodeTypeUnauthorized, errors.New("The escrow " + escrow.ID + " already exists.")
+	}
+	if dr.Data.To == nil {
+		return CodeTypeUnauthorized, errors.New("The receiver's public key is empty.")
+	}
+	_, err := crypto.UnmarshalPublicKey(*dr.Data.To)
+	if err != nil {
+		return CodeTypeEncodingError, errors.New("The receiver's public key is not correct.")
+	}
+	if bytes.Equal(*dr.Data.To, dr.Data.From) {
+		return CodeTypeUnauthorized, errors.New("The escrow's receiver can not be the sender.")
+	}
+	if escrow.Arbiter != nil {
+		_, err := crypto.UnmarshalPublicKey(*escrow.Arbiter)
+		if err != nil {
+			return CodeTypeEncodingError, errors.New("The arbiter's public key is not correct.")
+		}
+		if bytes.Equal(*escrow.Arbiter, dr.Data.From) || bytes.Equal(*escrow.Arbiter, *dr.Data.To) {
+			return CodeTypeUnauthorized, errors.New("The arbiter can not be the sender or the receiver.")
+		}
+	}
+	if escrow.Timeout <= tca.blockHeight {
+		return CodeTypeUnauthorized, errors.New("The escrow's timeout should be after the height " + strconv.FormatInt(tca.blockHeight, 10) + ".")
+	}
+	if dr.Data.TaxHash == nil || confs.Conf.IpfsTax != *dr.Data.TaxHash {
+		return CodeTypeUnauthorized, errors.New("The tax is not a validated UUID.")
+	}
+	return CodeTypeOK, nil
+}
+
+// validateEscrowClose allows the release to the payer and the arbiter,
+// and the refund to the payee, the arbiter and after the timeout to the payer.
+func (tca *TCApplication) validateEscrowClose(dr DeliveryRequest) (uint32, error) {
+	if dr.Data.Escrow == nil {
+		return CodeTypeUnauthorized, errors.New("The escrow is missing.")
+	}
+	ej, ok := tca.state.GetEscrow(dr.Data.Escrow.ID)
+	if !ok {
+		return CodeTypeUnauthorized, errors.New("The escrow " + dr.Data.Escrow.ID + " does not exist.")
+	}
+	if ej.Status != ESCROW_OPEN {
+		return CodeTypeUnauthorized, errors.New("The escrow " + ej.ID + " is already " + string(ej.Status) + ".")
+	}
+	from := dr.Data.From
+	arbiter := ej.Arbiter != nil && bytes.Equal(*ej.Arbiter, from)
+	if dr.Data.Action == ESCROW_RELEASE_ACTION {
+		if !bytes.Equal(ej.Payer, from) && !arbiter {
+			return CodeTypeUnauthorized, errors.New("Only the payer and the arbiter can release the escrow.")
+		}
+		if tca.state.IsFrozen(ej.Payee) {
+			return CodeTypeUnauthorized, errors.New("The payee's account is frozen.")
+		}
+		// the release is taxed with the tax of the opening, a changed tax leaves only the refund
+		if ej.TaxHash != confs.Conf.IpfsTax {
+			return CodeTypeUnauthorized, errors.New("The tax changed after the escrow was opened, the escrow can only be refunded.")
+		}
+		return CodeTypeOK, nil
+	}
+	if bytes.Equal(ej.Payer, from) {
+		if tca.blockHeight <= ej.Timeout {
+			return CodeTypeUnauthorized, errors.New("The payer can refund the escrow after the height " + strconv.FormatInt(ej.Timeout, 10) + ".")
+		}
+		return CodeTypeOK, nil
+	}
+	if !bytes.Equal(ej.Payee, from) && !arbiter {
+		return CodeTypeUnauthorized, errors.New("Only the payer, the payee and the arbiter can refund the escrow.")
+	}
+	if tca.state.IsFrozen(ej.Payer) {
+		return CodeTypeUnauthorized, errors.New("The payer's account is frozen.")
+	}
+	return CodeTypeOK, nil
+}
+
+func (tca *TCApplication) deliverEscrowOpen(dr DeliveryRequest) error {
+	from, _ := crypto.UnmarshalPublicKey(dr.Data.From)
+	fromCj, _ := tca.state.GetCoins(from)
+	newFromCoins := fromCj.Coins - dr.Data.Coins
+	if newFromCoins < 0 {
+		return errors.New("You dont have enough money for the escrow.")
+	}
+	tca.state.SetCoins(from, newFromCoins)
+
+	escrow := dr.Data.Escrow
+	tca.state.SetEscrow(EscrowJson{
+		ID:      escrow.ID,
+		Payer:   dr.Data.From,
+		Payee:   *dr.Data.To,
+		Arbiter: escrow.Arbiter,
+		Coins:   dr.Data.Coins,
+		TaxHash: *dr.Data.TaxHash,
+		Timeout: escrow.Timeout,
+		Height:  tca.blockHeight,
+		Status:  ESCROW_OPEN,
+	})
+	return nil
+}
+
+// deliverEscrowRelease pays the payee, the tax is taken on the release.
+func (tca *TCApplication) deliverEscrowRelease(dr DeliveryRequest) {
+	ej, _ := tca.state.GetEscrow(dr.Data.Escrow.ID)
+	taxCoins := tca.taxCoins(ej.Coins)
+
+	payee, _ := crypto.UnmarshalPublicKey(ej.Payee)
+	payeeCj, _ := tca.state.GetCoins(payee)
+	tca.state.SetCoins(payee, payeeCj.Coins+ej.Coins-taxCoins)
+
+	taxCj, _ := tca.state.GetCoins(confs.Conf.TaxReceiver)
+	tca.state.SetCoins(confs.Conf.TaxReceiver, taxCj.Coins+taxCoins)
+
+	ej.Status = ESCROW_RELEASED
+	tca.state.SetEscrow(ej)
+}
+
+func (tca *TCApplication) deliverEscrowRefund(dr DeliveryRequest) {
+	ej, _ := tca.state.GetEscrow(dr.Data.Escrow.ID)
+	payer, _ := crypto.UnmarshalPublicKey(ej.Payer)
+	payerCj, _ := tca.state.GetCoins(payer)
+	tca.state.SetCoins(payer, payerCj.Coins+ej.Coins)
+
+	ej.Status = ESCROW_REFUNDED
+	tca.state.SetEscrow(ej)
+}
+
+func (tca *TCApplication) queryEscrow(id string) types.ResponseQuery {
+	ej, ok := tca.state.GetEscrow(id)
+	if !ok {
+		return types.ResponseQuery{Code: CodeTypeUnauthorized, Log: "The escrow " + id + " does not exist."}
+	}
+	b, _ := json.Marshal(ej)
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
@@ -0,0 +1,153 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+)
+
+func (tu *testUtils) addCoins(t *testing.T, app *TCApplication, inflator crypto.PrivKey, coins float64) {
+	b, _ := inflator.GetPublic().Bytes()
+	confs.Conf.IpfsInflators = tu.addInflator(t, b)
+	confs.Conf.SubmitInflators()
+	dr := tu.inflatorCoins(t, inflator, ADD_ACTION, coins)
+	b, _ = json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+}
+
+func TestEscrowReleaseSuccessfully(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	payerPrivk, payerPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	payeePrivk, payeePubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, payerPrivk, 100)
+
+	code := tu.deliver(t, app, payerPrivk, DeliveryData{Action: ESCROW_OPEN_ACTION, To: tu.to(payeePubk), Coins: 50, TaxHash: &taxHash, Escrow: &Escrow{ID: "order-1", Timeout: 10}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	cj, _ := app.state.GetCoins(payerPubk)
+	assert.Equal(t, float64(50), cj.Coins)
+
+	// the same ID can not be opened again
+	code = tu.deliver(t, app, payerPrivk, DeliveryData{Action: ESCROW_OPEN_ACTION, To: tu.to(payeePubk), Coins: 10, TaxHash: &taxHash, Escrow: &Escrow{ID: "order-1", Timeout: 10}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+
+	// the payee can not release the coins to itself
+	code = tu.deliver(t, app, payeePrivk, DeliveryData{Action: ESCROW_RELEASE_ACTION, Escrow: &Escrow{ID: "order-1"}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+
+	code = tu.deliver(t, app, payerPrivk, DeliveryData{Action: ESCROW_RELEASE_ACTION, Escrow: &Escrow{ID: "order-1"}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	cj, _ = app.state.GetCoins(payeePubk)
+	assert.Equal(t, float64(45), cj.Coins)
+	cj, _ = app.state.GetCoins(taxPubk)
+	assert.Equal(t, float64(5), cj.Coins)
+
+	code = tu.deliver(t, app, payeePrivk, DeliveryData{Action: ESCROW_REFUND_ACTION, Escrow: &Escrow{ID: "order-1"}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+
+	resp := app.Query(types.RequestQuery{Path: ESCROW_QUERY_PATH, Data: []byte("order-1")})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	ej := EscrowJson{}
+	json.Unmarshal(resp.Value, &ej)
+	assert.Equal(t, ESCROW_RELEASED, ej.Status)
+	assert.Equal(t, 2, len(app.state.GetHistory(ej.Payee)))
+}
+
+func TestEscrowRefundSuccessfully(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	payerPrivk, payerPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, payeePubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	arbiterPrivk, arbiterPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, payerPrivk, 100)
+
+	arbiterB, _ := arbiterPubk.Bytes()
+	code := tu.deliver(t, app, payerPrivk, DeliveryData{Action: ESCROW_OPEN_ACTION, To: tu.to(payeePubk), Coins: 30, TaxHash: &taxHash, Escrow: &Escrow{ID: "order-1", Arbiter: &arbiterB, Timeout: 5}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	code = tu.deliver(t, app, payerPrivk, DeliveryData{Action: ESCROW_OPEN_ACTION, To: tu.to(payeePubk), Coins: 20, TaxHash: &taxHash, Escrow: &Escrow{ID: "order-2", Timeout: 5}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+
+	// the arbiter can not refund to a frozen payer
+	authorityPrivk, authorityPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	conf := confs.Conf
+	defer func() { confs.Conf = conf }()
+	tu.addAuthority(t, authorityPubk)
+	resp := tu.deliver(t, app, authorityPrivk, DeliveryData{Action: FREEZE_ACTION, Compliance: &Compliance{Account: tu.pubBytes(payerPubk), Reason: "case-1"}})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	code = tu.deliver(t, app, arbiterPrivk, DeliveryData{Action: ESCROW_REFUND_ACTION, Escrow: &Escrow{ID: "order-1"}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	resp = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: UNFREEZE_ACTION, Compliance: &Compliance{Account: tu.pubBytes(payerPubk), Reason: "case-1 closed"}})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	// the arbiter refunds before the timeout
+	code = tu.deliver(t, app, arbiterPrivk, DeliveryData{Action: ESCROW_REFUND_ACTION, Escrow: &Escrow{ID: "order-1"}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	cj, _ := app.state.GetCoins(payerPubk)
+	assert.Equal(t, float64(80), cj.Coins)
+
+	// the payer refunds only after the timeout
+	code = tu.deliver(t, app, payerPrivk, DeliveryData{Action: ESCROW_REFUND_ACTION, Escrow: &Escrow{ID: "order-2"}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 6}})
+	code = tu.deliver(t, app, payerPrivk, DeliveryData{Action: ESCROW_REFUND_ACTION, Escrow: &Escrow{ID: "order-2"}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	cj, _ = app.state.GetCoins(payerPubk)
+	assert.Equal(t, float64(100), cj.Coins)
+	cj, _ = app.state.GetCoins(payeePubk)
+	assert.Equal(t, float64(0), cj.Coins)
+}
+
+func TestEscrowReleaseWithAChangedTax(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	payerPrivk, payerPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, payeePubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, payerPrivk, 100)
+
+	code := tu.deliver(t, app, payerPrivk, DeliveryData{Action: ESCROW_OPEN_ACTION, To: tu.to(payeePubk), Coins: 50, TaxHash: &taxHash, Escrow: &Escrow{ID: "order-1", Timeout: 10}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+
+	// the release is not taxed with the new tax
+	_, newTaxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	tu.submitTax(t, newTaxPubk)
+	code = tu.deliver(t, app, payerPrivk, DeliveryData{Action: ESCROW_RELEASE_ACTION, Escrow: &Escrow{ID: "order-1"}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	cj, _ := app.state.GetCoins(payeePubk)
+	assert.Equal(t, float64(0), cj.Coins)
+
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 11}})
+	code = tu.deliver(t, app, payerPrivk, DeliveryData{Action: ESCROW_REFUND_ACTION, Escrow: &Escrow{ID: "order-1"}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	cj, _ = app.state.GetCoins(payerPubk)
+	assert.Equal(t, float64(100), cj.Coins)
+}
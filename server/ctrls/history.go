@@ -0,0 +1,100 @@
+package ctrls
+
+import (
+	"encoding/binary"
+
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// txHash is the hash that tendermint shows for the transaction,
+// the ripemd160 of the transaction's bytes with their length.
+func txHash(tx []byte) []byte {
+	length := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(length, uint64(len(tx)))
+	h := ripemd160.New()
+	h.Write(length[:n])
+	h.Write(tx)
+	return h.Sum(nil)
+}
+
+// recordHistory keeps the delivered transaction in the history of the sender, of the receiver and of the parties of the locked coins.
+func (tca *TCApplication) recordHistory(tx []byte, dr DeliveryRequest) HistoryEntry {
+	entry := HistoryEntry{}
+	entry.Height = tca.blockHeight
+	entry.Time = tca.blockTime
+	entry.Hash = txHash(tx)
+	entry.Action = dr.Data.Action
+	entry.From = dr.Data.From
+	entry.To = dr.Data.To
+	entry.Coins = dr.Data.Coins
+	entry.Memo = dr.Data.Memo
+	entry.StandingOrder = dr.Data.StandingOrder
+
+	accounts := [][]byte{dr.Data.From}
+	if dr.Data.To != nil {
+		accounts = append(accounts, *dr.Data.To)
+	}
+	// the release, the claim and the refunds move the locked coins between the parties
+	if dr.Data.Escrow != nil {
+		if ej, ok := tca.state.GetEscrow(dr.Data.Escrow.ID); ok {
+			entry.Coins = ej.Coins
+			accounts = append(accounts, ej.Payer, ej.Payee)
+			if dr.Data.Action == ESCROW_RELEASE_ACTION {
+				tca.setPayment(&entry, ej.Payer, ej.Payee, true)
+			}
+			if ej.Arbiter != nil {
+				accounts = append(accounts, *ej.Arbiter)
+			}
+		}
+	}
+	if dr.Data.Htlc != nil {
+		if hj, ok := tca.state.GetHtlc(dr.Data.Htlc.Hashlock); ok {
+			entry.Coins = hj.Coins
+			accounts = append(accounts, hj.Sender, hj.Receiver)
+			if dr.Data.Action == HTLC_CLAIM_ACTION {
+				tca.setPayment(&entry, hj.Sender, hj.Receiver, true)
+			}
+		}
+	}
+	// the frozen or seized account
+	if dr.Data.Compliance != nil {
+		accounts = append(accounts, dr.Data.Compliance.Account)
+		if dr.Data.Action == SEIZE_ACTION {
+			tca.setPayment(&entry, dr.Data.Compliance.Account, *dr.Data.To, false)
+		}
+	}
+	if dr.Data.Action == SEND_ACTION {
+		tca.setPayment(&entry, dr.Data.From, *dr.Data.To, true)
+	}
+	if entry.TaxReceiver != nil {
+		accounts = append(accounts, entry.TaxReceiver)
+	}
+	if dr.Data.StandingOrder != nil {
+		if so, ok := tca.state.GetStandingOrder(dr.Data.StandingOrder.ID); ok {
+			entry.Coins = so.Coins
+			accounts = append(accounts, so.Receiver)
+		}
+	}
+	added := map[string]bool{}
+	for _, account := range accounts {
+		if added[string(account)] {
+			continue
+		}
+		added[string(account)] = true
+		tca.state.AddHistory(account, tca.blockTxs, entry)
+	}
+	tca.blockTxs++
+	return entry
+}
+
+// setPayment sets the payer, the payee and the tax of the entry's coins.
+func (tca *TCApplication) setPayment(entry *HistoryEntry, payer, payee []byte, taxed bool) {
+	entry.Payer = payer
+	entry.Payee = payee
+	if !taxed || confs.Conf.TaxReceiver == nil {
+		return
+	}
+	entry.Tax = tca.taxCoins(entry.Coins)
+	entry.TaxReceiver, _ = confs.Conf.TaxReceiver.Bytes()
+}
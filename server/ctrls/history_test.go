@@ -0,0 +1,51 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+)
+
+func TestHistoryPaymentsWithTheTax(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	payerPrivk, payerPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, payeePubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, payerPrivk, 100)
+
+	dr := tu.sendCoins(t, payerPrivk, payeePubk, taxHash, 20)
+	b, _ := json.Marshal(dr)
+	assert.Equal(t, CodeTypeOK, app.DeliverTx(b).Code)
+	code := tu.deliver(t, app, payerPrivk, DeliveryData{Action: ESCROW_OPEN_ACTION, To: tu.to(payeePubk), Coins: 50, TaxHash: &taxHash, Escrow: &Escrow{ID: "order-1", Timeout: 10}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	code = tu.deliver(t, app, payerPrivk, DeliveryData{Action: ESCROW_RELEASE_ACTION, Escrow: &Escrow{ID: "order-1"}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+
+	// the open escrow is not a payment until the release
+	entries := app.state.GetHistory(tu.pubBytes(payeePubk))
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, tu.pubBytes(payerPubk), entries[0].Payer)
+	assert.Equal(t, tu.pubBytes(payeePubk), entries[0].Payee)
+	assert.Equal(t, float64(2), entries[0].Tax)
+	assert.Equal(t, tu.pubBytes(taxPubk), entries[0].TaxReceiver)
+	assert.Nil(t, entries[1].Payer)
+	assert.Equal(t, tu.pubBytes(payerPubk), entries[2].Payer)
+	assert.Equal(t, float64(5), entries[2].Tax)
+
+	// the tax receiver has the payments of its tax
+	entries = app.state.GetHistory(tu.pubBytes(taxPubk))
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, SEND_ACTION, entries[0].Action)
+	assert.Equal(t, ESCROW_RELEASE_ACTION, entries[1].Action)
+}
@@ -0,0 +1,147 @@
+package ctrls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/tendermint/abci/types"
+)
+
+const HTLC_PREIMAGE_MAX_SIZE = 64
+
+func (tca *TCApplication) validateHtlcLock(dr DeliveryRequest) (uint32, error) {
+	htlc := dr.Data.Htlc
+	if htlc == nil {
+		return CodeTypeUnauthorized, errors.New("The HTLC is missing.")
+	}
+	if len(htlc.Hashlock) != sha256.Size {
+		return CodeTypeEncodingError, errors.New("The hashlock should be a SHA-256 hash.")
+	}
+	if len(htlc.Preimage) > 0 {
+		return CodeTypeUnauthorized, errors.New("The lock can not reveal the preimage.")
+	}
+	if _, ok := tca.state.GetHtlc(htlc.Hashlock); ok {
+		return CodeTypeUnauthorized, errors.New("The hashlock is already used.")
+	}
+	if dr.Data.To == nil {
+		return CodeTypeUnauthorized, errors.New("The receiver's public key is empty.")
+	}
+	_, err := crypto.UnmarshalPublicKey(*dr.Data.To)
+	if err != nil {
+		return CodeTypeEncodingError, errors.New("The receiver's public key is not correct.")
+	}
+	if htlc.Timeout <= tca.blockHeight {
+		return CodeTypeUnauthorized, errors.New("The HTLC's timeout should be after the height " + strconv.FormatInt(tca.blockHeight, 10) + ".")
+	}
+	if dr.Data.TaxHash == nil || confs.Conf.IpfsTax != *dr.Data.TaxHash {
+		return CodeTypeUnauthorized, errors.New("The tax is not a validated UUID.")
+	}
+	return CodeTypeOK, nil
+}
+
+// validateHtlcClose allows the claim with the preimage until the timeout, the coins go to the receiver whoever claims them,
+// and the sender's refund after the timeout.
+func (tca *TCApplication) validateHtlcClose(dr DeliveryRequest) (uint32, error) {
+	if dr.Data.Htlc == nil {
+		return CodeTypeUnauthorized, errors.New("The HTLC is missing.")
+	}
+	hj, ok := tca.state.GetHtlc(dr.Data.Htlc.Hashlock)
+	if !ok {
+		return CodeTypeUnauthorized, errors.New("The hashlock is not locked.")
+	}
+	if hj.Status != HTLC_LOCKED {
+		return CodeTypeUnauthorized, errors.New("The HTLC is already " + string(hj.Status) + ".")
+	}
+	if dr.Data.Action == HTLC_CLAIM_ACTION {
+		preimage := dr.Data.Htlc.Preimage
+		if len(preimage) == 0 || len(preimage) > HTLC_PREIMAGE_MAX_SIZE {
+			return CodeTypeUnauthorized, errors.New("The preimage should have from 1 to " + strconv.Itoa(HTLC_PREIMAGE_MAX_SIZE) + " bytes.")
+		}
+		h := sha256.Sum256(preimage)
+		if !bytes.Equal(h[:], hj.Hashlock) {
+			return CodeTypeUnauthorized, errors.New("The preimage does not match the hashlock.")
+		}
+		if tca.blockHeight > hj.Timeout {
+			return CodeTypeUnauthorized, errors.New("The HTLC expired at the height " + strconv.FormatInt(hj.Timeout, 10) + ".")
+		}
+		if tca.state.IsFrozen(hj.Receiver) {
+			return CodeTypeUnauthorized, errors.New("The receiver's account is frozen.")
+		}
+		// the claim is taxed with the tax of the lock, a changed tax leaves only the refund
+		if hj.TaxHash != confs.Conf.IpfsTax {
+			return CodeTypeUnauthorized, errors.New("The tax changed after the HTLC was locked, the HTLC can only be refunded.")
+		}
+		return CodeTypeOK, nil
+	}
+	if !bytes.Equal(hj.Sender, dr.Data.From) {
+		return CodeTypeUnauthorized, errors.New("Only the sender can refund the HTLC.")
+	}
+	if tca.blockHeight <= hj.Timeout {
+		return CodeTypeUnauthorized, errors.New("The sender can refund the HTLC after the height " + strconv.FormatInt(hj.Timeout, 10) + ".")
+	}
+	return CodeTypeOK, nil
+}
+
+func (tca *TCApplication) deliverHtlcLock(dr DeliveryRequest) error {
+	from, _ := crypto.UnmarshalPublicKey(dr.Data.From)
+	fromCj, _ := tca.state.GetCoins(from)
+	newFromCoins := fromCj.Coins - dr.Data.Coins
+	if newFromCoins < 0 {
+		return errors.New("You dont have enough money for the HTLC.")
+	}
+	tca.state.SetCoins(from, newFromCoins)
+
+	tca.state.SetHtlc(HtlcJson{
+		Hashlock: dr.Data.Htlc.Hashlock,
+		Sender:   dr.Data.From,
+		Receiver: *dr.Data.To,
+		Coins:    dr.Data.Coins,
+		TaxHash:  *dr.Data.TaxHash,
+		Timeout:  dr.Data.Htlc.Timeout,
+		Height:   tca.blockHeight,
+		Status:   HTLC_LOCKED,
+	})
+	return nil
+}
+
+// deliverHtlcClaim pays the receiver and keeps the preimage, the tax is taken on the claim.
+func (tca *TCApplication) deliverHtlcClaim(dr DeliveryRequest) {
+	hj, _ := tca.state.GetHtlc(dr.Data.Htlc.Hashlock)
+	taxCoins := tca.taxCoins(hj.Coins)
+
+	receiver, _ := crypto.UnmarshalPublicKey(hj.Receiver)
+	receiverCj, _ := tca.state.GetCoins(receiver)
+	tca.state.SetCoins(receiver, receiverCj.Coins+hj.Coins-taxCoins)
+
+	taxCj, _ := tca.state.GetCoins(confs.Conf.TaxReceiver)
+	tca.state.SetCoins(confs.Conf.TaxReceiver, taxCj.Coins+taxCoins)
+
+	hj.Status = HTLC_CLAIMED
+	hj.Preimage = dr.Data.Htlc.Preimage
+	tca.state.SetHtlc(hj)
+}
+
+func (tca *TCApplication) deliverHtlcRefund(dr DeliveryRequest) {
+	hj, _ := tca.state.GetHtlc(dr.Data.Htlc.Hashlock)
+	sender, _ := crypto.UnmarshalPublicKey(hj.Sender)
+	senderCj, _ := tca.state.GetCoins(sender)
+	tca.state.SetCoins(sender, senderCj.Coins+hj.Coins)
+
+	hj.Status = HTLC_REFUNDED
+	tca.state.SetHtlc(hj)
+}
+
+// queryHtlc returns the HTLC of the hashlock in the data, with the preimage after the claim.
+func (tca *TCApplication) queryHtlc(hashlock []byte) types.ResponseQuery {
+	hj, ok := tca.state.GetHtlc(hashlock)
+	if !ok {
+		return types.ResponseQuery{Code: CodeTypeUnauthorized, Log: "The hashlock is not locked."}
+	}
+	b, _ := json.Marshal(hj)
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
@@ -0,0 +1,117 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"testing"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+)
+
+func TestHtlcClaimSuccessfully(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	senderPrivk, senderPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	receiverPrivk, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, senderPrivk, 100)
+
+	preimage := []byte("the secret of the swap")
+	hashlock := sha256.Sum256(preimage)
+	code := tu.deliver(t, app, senderPrivk, DeliveryData{Action: HTLC_LOCK_ACTION, To: tu.to(receiverPubk), Coins: 50, TaxHash: &taxHash, Htlc: &Htlc{Hashlock: hashlock[:], Timeout: 10}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	cj, _ := app.state.GetCoins(senderPubk)
+	assert.Equal(t, float64(50), cj.Coins)
+
+	code = tu.deliver(t, app, receiverPrivk, DeliveryData{Action: HTLC_CLAIM_ACTION, Htlc: &Htlc{Hashlock: hashlock[:], Preimage: []byte("a wrong secret")}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	code = tu.deliver(t, app, senderPrivk, DeliveryData{Action: HTLC_REFUND_ACTION, Htlc: &Htlc{Hashlock: hashlock[:]}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+
+	code = tu.deliver(t, app, receiverPrivk, DeliveryData{Action: HTLC_CLAIM_ACTION, Htlc: &Htlc{Hashlock: hashlock[:], Preimage: preimage}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	cj, _ = app.state.GetCoins(receiverPubk)
+	assert.Equal(t, float64(45), cj.Coins)
+
+	// the other chain is claimed with the revealed preimage
+	resp := app.Query(types.RequestQuery{Path: HTLC_QUERY_PATH, Data: hashlock[:]})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	hj := HtlcJson{}
+	json.Unmarshal(resp.Value, &hj)
+	assert.Equal(t, HTLC_CLAIMED, hj.Status)
+	assert.Equal(t, preimage, hj.Preimage)
+}
+
+func TestHtlcRefundAfterTimeout(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	senderPrivk, senderPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	receiverPrivk, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, senderPrivk, 100)
+
+	preimage := []byte("the secret of the swap")
+	hashlock := sha256.Sum256(preimage)
+	code := tu.deliver(t, app, senderPrivk, DeliveryData{Action: HTLC_LOCK_ACTION, To: tu.to(receiverPubk), Coins: 50, TaxHash: &taxHash, Htlc: &Htlc{Hashlock: hashlock[:], Timeout: 5}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 6}})
+	code = tu.deliver(t, app, receiverPrivk, DeliveryData{Action: HTLC_CLAIM_ACTION, Htlc: &Htlc{Hashlock: hashlock[:], Preimage: preimage}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	code = tu.deliver(t, app, senderPrivk, DeliveryData{Action: HTLC_REFUND_ACTION, Htlc: &Htlc{Hashlock: hashlock[:]}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	cj, _ := app.state.GetCoins(senderPubk)
+	assert.Equal(t, float64(100), cj.Coins)
+	cj, _ = app.state.GetCoins(receiverPubk)
+	assert.Equal(t, float64(0), cj.Coins)
+}
+
+func TestHtlcClaimWithAChangedTax(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	senderPrivk, senderPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	receiverPrivk, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, senderPrivk, 100)
+
+	preimage := []byte("the secret of the swap")
+	hashlock := sha256.Sum256(preimage)
+	code := tu.deliver(t, app, senderPrivk, DeliveryData{Action: HTLC_LOCK_ACTION, To: tu.to(receiverPubk), Coins: 50, TaxHash: &taxHash, Htlc: &Htlc{Hashlock: hashlock[:], Timeout: 5}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+
+	// the claim is not taxed with the new tax
+	_, newTaxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	tu.submitTax(t, newTaxPubk)
+	code = tu.deliver(t, app, receiverPrivk, DeliveryData{Action: HTLC_CLAIM_ACTION, Htlc: &Htlc{Hashlock: hashlock[:], Preimage: preimage}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	cj, _ := app.state.GetCoins(receiverPubk)
+	assert.Equal(t, float64(0), cj.Coins)
+
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 6}})
+	code = tu.deliver(t, app, senderPrivk, DeliveryData{Action: HTLC_REFUND_ACTION, Htlc: &Htlc{Hashlock: hashlock[:]}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	cj, _ = app.state.GetCoins(senderPubk)
+	assert.Equal(t, float64(100), cj.Coins)
+}
@@ -0,0 +1,140 @@
+package ctrls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/tendermint/abci/types"
+)
+
+// signedBytes returns the json of the attestation without the signature.
+func (a Attestation) signedBytes() []byte {
+	a.Signature = nil
+	b, _ := json.Marshal(a)
+	return b
+}
+
+func (a *Attestation) VerifySignature() (bool, error) {
+	pub, err := crypto.UnmarshalPublicKey(a.Attestor)
+	if err != nil {
+		return false, errors.New("The attestor's public key is not correct")
+	}
+	ver, err := pub.Verify(a.signedBytes(), a.Signature)
+	if err != nil {
+		return false, errors.New("The attestation's signature format is not correct.")
+	}
+	return ver, nil
+}
+
+// kycLevel returns the level of the account's attestation, 0 if it has none or it is expired.
+func (tca *TCApplication) kycLevel(account []byte) int {
+	aj, ok := tca.state.GetAttestation(account)
+	if !ok || aj.Expiry <= tca.blockHeight {
+		return 0
+	}
+	return aj.Level
+}
+
+// The actions that move the public coins of the sender, the KYC levels check their coins.
+// The confidential sends hide their coins, so their coins are checked when they are deposited and withdrawn.
+var kycActions = map[ActionStruct]bool{
+	SEND_ACTION:                  true,
+	ESCROW_OPEN_ACTION:           true,
+	HTLC_LOCK_ACTION:             true,
+	STANDING_ORDER_ACTION:        true,
+	CONFIDENTIAL_DEPOSIT_ACTION:  true,
+	CONFIDENTIAL_WITHDRAW_ACTION: true,
+}
+
+// checkKyc returns an error if the coins are above a KYC level and the sender or the receiver
+// do not have a valid attestation of the level, the receiver is nil when the coins stay with the sender.
+func (tca *TCApplication) checkKyc(from []byte, to *[]byte, coins float64) error {
+	level := confs.Conf.RequiredKycLevel(coins)
+	if level == 0 {
+		return nil
+	}
+	if tca.kycLevel(from) < level {
+		return errors.New("The coins need the sender's attestation of level " + strconv.Itoa(level) + ".")
+	}
+	if to != nil && tca.kycLevel(*to) < level {
+		return errors.New("The coins need the receiver's attestation of level " + strconv.Itoa(level) + ".")
+	}
+	return nil
+}
+
+func (tca *TCApplication) validateKyc(dr DeliveryRequest) (uint32, error) {
+	if !kycActions[dr.Data.Action] {
+		return CodeTypeOK, nil
+	}
+	err := tca.checkKyc(dr.Data.From, dr.Data.To, dr.Data.Coins)
+	if err != nil {
+		return CodeTypeUnauthorized, err
+	}
+	return CodeTypeOK, nil
+}
+
+// validateAttestation allows the attestors to submit their signed attestations and to revoke the attestation of an account.
+func (tca *TCApplication) validateAttestation(dr DeliveryRequest) (uint32, error) {
+	if !confs.Conf.AttestorExists(string(dr.Data.From)) {
+		return CodeTypeUnauthorized, errors.New("You are not an attestor.")
+	}
+	a := dr.Data.Attestation
+	if a == nil {
+		return CodeTypeUnauthorized, errors.New("The attestation is missing.")
+	}
+	_, err := crypto.UnmarshalPublicKey(a.Account)
+	if err != nil {
+		return CodeTypeEncodingError, errors.New("The account's public key is not correct.")
+	}
+	if dr.Data.Action == ATTEST_REVOKE_ACTION {
+		if _, ok := tca.state.GetAttestation(a.Account); !ok {
+			return CodeTypeUnauthorized, errors.New("The account does not have an attestation.")
+		}
+		return CodeTypeOK, nil
+	}
+
+	if !bytes.Equal(a.Attestor, dr.Data.From) {
+		return CodeTypeUnauthorized, errors.New("The attestation is not of the sender.")
+	}
+	if a.Level < 1 {
+		return CodeTypeUnauthorized, errors.New("The attestation's level should be 1 or more.")
+	}
+	if a.Expiry <= tca.blockHeight {
+		return CodeTypeUnauthorized, errors.New("The attestation's expiry should be after the height " + strconv.FormatInt(tca.blockHeight, 10) + ".")
+	}
+	if len(a.Document) != sha256.Size {
+		return CodeTypeUnauthorized, errors.New("The document should be a SHA-256 hash.")
+	}
+	ver, err := a.VerifySignature()
+	if err != nil {
+		return CodeTypeEncodingError, err
+	}
+	if !ver {
+		return CodeTypeUnauthorized, errors.New("The signature does not validate the attestation.")
+	}
+	return CodeTypeOK, nil
+}
+
+func (tca *TCApplication) deliverAttestation(dr DeliveryRequest) {
+	if dr.Data.Action == ATTEST_REVOKE_ACTION {
+		tca.state.DeleteAttestation(dr.Data.Attestation.Account)
+		return
+	}
+	tca.state.SetAttestation(AttestationJson{Attestation: *dr.Data.Attestation, Height: tca.blockHeight})
+}
+
+// queryAttestation returns the attestation of the public key in the data.
+func (tca *TCApplication) queryAttestation(account []byte) types.ResponseQuery {
+	ar := AttestationResponse{}
+	if aj, ok := tca.state.GetAttestation(account); ok {
+		ar.Attestation = &aj
+		ar.Valid = aj.Expiry > tca.blockHeight
+	}
+	b, _ := json.Marshal(ar)
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
@@ -0,0 +1,185 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/ipfs/go-ipfs-api"
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+)
+
+func (tu *testUtils) addAttestor(t *testing.T, attestor crypto.PubKey) {
+	b, _ := attestor.Bytes()
+	attestors := []confs.Attestor{{PublicKeyHex: hex.EncodeToString(b)}}
+	attB, _ := json.Marshal(attestors)
+	sh := shell.NewShell(confs.Conf.IpfsConnection)
+	attHash, err := sh.BlockPut(attB)
+	assert.Nil(t, err)
+	confs.Conf.IpfsAttestors = attHash
+	err = confs.Conf.SubmitAttestors()
+	assert.Nil(t, err)
+}
+
+func (tu *testUtils) signAttestation(attestor crypto.PrivKey, a Attestation) *Attestation {
+	a.Attestor, _ = attestor.GetPublic().Bytes()
+	a.Signature, _ = attestor.Sign(a.signedBytes())
+	return &a
+}
+
+func TestKycGatesTheLargeSends(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+	confs.Conf.KycLevels = []confs.KycLevel{{Coins: 100, Level: 1}, {Coins: 500, Level: 2}}
+	defer func() { confs.Conf.KycLevels = nil }()
+
+	attestorPrivk, attestorPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	conf := confs.Conf
+	defer func() { confs.Conf = conf }()
+	tu.addAttestor(t, attestorPubk)
+	senderPrivk, senderPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, senderPrivk, 1000)
+
+	send := func(coins float64) uint32 {
+		dr := tu.sendCoins(t, senderPrivk, receiverPubk, taxHash, coins)
+		b, _ := json.Marshal(dr)
+		return app.DeliverTx(b).Code
+	}
+	// the sends until the first level do not need attestations
+	assert.Equal(t, CodeTypeOK, send(100))
+	assert.Equal(t, CodeTypeUnauthorized, send(200))
+
+	document := sha256.Sum256([]byte("passport"))
+	sender := tu.pubBytes(senderPubk)
+	receiver := tu.pubBytes(receiverPubk)
+	code := tu.deliver(t, app, senderPrivk, DeliveryData{Action: ATTEST_ACTION, Attestation: tu.signAttestation(senderPrivk, Attestation{Account: sender, Level: 1, Expiry: 10, Document: document[:]})}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	code = tu.deliver(t, app, attestorPrivk, DeliveryData{Action: ATTEST_ACTION, Attestation: tu.signAttestation(attestorPrivk, Attestation{Account: sender, Level: 1, Expiry: 1, Document: document[:]})}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	code = tu.deliver(t, app, attestorPrivk, DeliveryData{Action: ATTEST_ACTION, Attestation: tu.signAttestation(attestorPrivk, Attestation{Account: sender, Level: 1, Expiry: 10, Document: []byte("passport")})}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	code = tu.deliver(t, app, attestorPrivk, DeliveryData{Action: ATTEST_ACTION, Attestation: tu.signAttestation(attestorPrivk, Attestation{Account: sender, Level: 2, Expiry: 10, Document: document[:]})}).Code
+	assert.Equal(t, CodeTypeOK, code)
+
+	// both accounts need the level
+	assert.Equal(t, CodeTypeUnauthorized, send(200))
+	code = tu.deliver(t, app, attestorPrivk, DeliveryData{Action: ATTEST_ACTION, Attestation: tu.signAttestation(attestorPrivk, Attestation{Account: receiver, Level: 1, Expiry: 10, Document: document[:]})}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	assert.Equal(t, CodeTypeOK, send(200))
+	assert.Equal(t, CodeTypeUnauthorized, send(600))
+
+	resp := app.Query(types.RequestQuery{Path: ATTESTATION_QUERY_PATH, Data: receiver})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	ar := AttestationResponse{}
+	json.Unmarshal(resp.Value, &ar)
+	assert.True(t, ar.Valid)
+	assert.Equal(t, document[:], ar.Attestation.Document)
+	ver, err := ar.Attestation.VerifySignature()
+	assert.Nil(t, err)
+	assert.True(t, ver)
+
+	// the attestations expire and the attestors revoke them
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 10}})
+	assert.Equal(t, CodeTypeUnauthorized, send(200))
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 11}})
+	code = tu.deliver(t, app, attestorPrivk, DeliveryData{Action: ATTEST_ACTION, Attestation: tu.signAttestation(attestorPrivk, Attestation{Account: sender, Level: 1, Expiry: 20, Document: document[:]})}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	code = tu.deliver(t, app, attestorPrivk, DeliveryData{Action: ATTEST_ACTION, Attestation: tu.signAttestation(attestorPrivk, Attestation{Account: receiver, Level: 1, Expiry: 20, Document: document[:]})}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	assert.Equal(t, CodeTypeOK, send(200))
+	code = tu.deliver(t, app, attestorPrivk, DeliveryData{Action: ATTEST_REVOKE_ACTION, Attestation: &Attestation{Account: receiver}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	assert.Equal(t, CodeTypeUnauthorized, send(200))
+}
+
+func TestKycGatesTheOtherTransfers(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+	confs.Conf.KycLevels = []confs.KycLevel{{Coins: 100, Level: 1}}
+	defer func() { confs.Conf.KycLevels = nil }()
+
+	attestorPrivk, attestorPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	conf := confs.Conf
+	defer func() { confs.Conf = conf }()
+	tu.addAttestor(t, attestorPubk)
+	senderPrivk, senderPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, senderPrivk, 1000)
+
+	preimage := []byte("the secret of the swap")
+	hashlock := sha256.Sum256(preimage)
+	deposit := func(coins float64) uint32 {
+		dr := tu.inflatorCoins(t, senderPrivk, CONFIDENTIAL_DEPOSIT_ACTION, coins)
+		dr.Data.Confidential = &Confidential{SenderNote: []byte("deposit")}
+		tu.signDelivery(t, &dr, senderPrivk)
+		b, _ := json.Marshal(dr)
+		return app.DeliverTx(b).Code
+	}
+	withdraw := func(coins float64, rest int64) uint32 {
+		dr := tu.inflatorCoins(t, senderPrivk, CONFIDENTIAL_WITHDRAW_ACTION, coins)
+		dr.Data.Confidential = &Confidential{
+			BalanceProof: tu.proveRange(t, rest, suite.Scalar().Zero(), CONFIDENTIAL_BITS),
+			SenderNote:   []byte("withdraw"),
+		}
+		tu.signDelivery(t, &dr, senderPrivk)
+		b, _ := json.Marshal(dr)
+		return app.DeliverTx(b).Code
+	}
+
+	// the transfers above the level need the attestations of both accounts
+	assert.Equal(t, CodeTypeUnauthorized, tu.deliver(t, app, senderPrivk, DeliveryData{Action: ESCROW_OPEN_ACTION, To: tu.to(receiverPubk), Coins: 200, TaxHash: &taxHash, Escrow: &Escrow{ID: "order-1", Timeout: 10}}).Code)
+	assert.Equal(t, CodeTypeUnauthorized, tu.deliver(t, app, senderPrivk, DeliveryData{Action: HTLC_LOCK_ACTION, To: tu.to(receiverPubk), Coins: 200, TaxHash: &taxHash, Htlc: &Htlc{Hashlock: hashlock[:], Timeout: 10}}).Code)
+	assert.Equal(t, CodeTypeUnauthorized, tu.deliver(t, app, senderPrivk, DeliveryData{Action: STANDING_ORDER_ACTION, To: tu.to(receiverPubk), Coins: 200, TaxHash: &taxHash, StandingOrder: &StandingOrder{ID: "rent", Interval: 1, End: 10}}).Code)
+	assert.Equal(t, CodeTypeUnauthorized, deposit(200))
+
+	document := sha256.Sum256([]byte("passport"))
+	sender := tu.pubBytes(senderPubk)
+	receiver := tu.pubBytes(receiverPubk)
+	code := tu.deliver(t, app, attestorPrivk, DeliveryData{Action: ATTEST_ACTION, Attestation: tu.signAttestation(attestorPrivk, Attestation{Account: sender, Level: 1, Expiry: 20, Document: document[:]})}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	assert.Equal(t, CodeTypeUnauthorized, tu.deliver(t, app, senderPrivk, DeliveryData{Action: ESCROW_OPEN_ACTION, To: tu.to(receiverPubk), Coins: 200, TaxHash: &taxHash, Escrow: &Escrow{ID: "order-1", Timeout: 10}}).Code)
+	code = tu.deliver(t, app, attestorPrivk, DeliveryData{Action: ATTEST_ACTION, Attestation: tu.signAttestation(attestorPrivk, Attestation{Account: receiver, Level: 1, Expiry: 20, Document: document[:]})}).Code
+	assert.Equal(t, CodeTypeOK, code)
+
+	assert.Equal(t, CodeTypeOK, tu.deliver(t, app, senderPrivk, DeliveryData{Action: ESCROW_OPEN_ACTION, To: tu.to(receiverPubk), Coins: 200, TaxHash: &taxHash, Escrow: &Escrow{ID: "order-1", Timeout: 10}}).Code)
+	assert.Equal(t, CodeTypeOK, tu.deliver(t, app, senderPrivk, DeliveryData{Action: HTLC_LOCK_ACTION, To: tu.to(receiverPubk), Coins: 200, TaxHash: &taxHash, Htlc: &Htlc{Hashlock: hashlock[:], Timeout: 10}}).Code)
+	assert.Equal(t, CodeTypeOK, tu.deliver(t, app, senderPrivk, DeliveryData{Action: STANDING_ORDER_ACTION, To: tu.to(receiverPubk), Coins: 200, TaxHash: &taxHash, StandingOrder: &StandingOrder{ID: "rent", Interval: 1, End: 10}}).Code)
+	assert.Equal(t, CodeTypeOK, deposit(200))
+	assert.Equal(t, CodeTypeOK, deposit(200))
+	app.EndBlock(types.RequestEndBlock{Height: 1})
+
+	// the standing order's payments check the attestations again
+	code = tu.deliver(t, app, attestorPrivk, DeliveryData{Action: ATTEST_REVOKE_ACTION, Attestation: &Attestation{Account: receiver}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	tu.block(app, 2)
+	so, _ := app.state.GetStandingOrder("rent")
+	assert.Equal(t, 0, so.Payments)
+	assert.Equal(t, 1, len(so.Failures))
+
+	// the withdrawals need only the sender's attestation
+	assert.Equal(t, CodeTypeOK, withdraw(150, 25000))
+	code = tu.deliver(t, app, attestorPrivk, DeliveryData{Action: ATTEST_REVOKE_ACTION, Attestation: &Attestation{Account: sender}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	assert.Equal(t, CodeTypeUnauthorized, withdraw(150, 10000))
+	assert.Equal(t, CodeTypeOK, withdraw(50, 20000))
+}
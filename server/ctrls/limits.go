@@ -0,0 +1,138 @@
+package ctrls
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/tendermint/abci/types"
+)
+
+const TIER_NAME_MAX_LENGTH = 32
+
+// The actions that send public coins of the sender to another account or to the sender's confidential balance,
+// the limits count them.
+var limitedActions = map[ActionStruct]bool{
+	SEND_ACTION:                 true,
+	ESCROW_OPEN_ACTION:          true,
+	HTLC_LOCK_ACTION:            true,
+	STANDING_ORDER_ACTION:       true,
+	CONFIDENTIAL_DEPOSIT_ACTION: true,
+}
+
+// accountLimits returns the account's tier and the coins that the account sent in the tier's window.
+func (tca *TCApplication) accountLimits(account []byte) (TierJson, float64) {
+	tj := tca.state.GetTier(tca.state.GetAccountTier(account))
+	sent := float64(0)
+	if tj.Window > 0 {
+		for _, e := range tca.state.GetSent(account, tca.blockHeight-tj.Window) {
+			sent += e.Coins
+		}
+	}
+	return tj, sent
+}
+
+// checkLimits returns an error if the coins are more than the account's limits of a send or of the window.
+func (tca *TCApplication) checkLimits(account []byte, coins float64) error {
+	tj, sent := tca.accountLimits(account)
+	if tj.MaxSend > 0 && coins > tj.MaxSend {
+		return errors.New("The coins are more than the send's limit of " + strconv.FormatFloat(tj.MaxSend, 'f', -1, 64) +
+			" for the tier " + tj.Name + ".")
+	}
+	if tj.MaxWindow > 0 && tj.Window > 0 && sent+coins > tj.MaxWindow {
+		return errors.New("The coins are more than the remaining " + strconv.FormatFloat(tj.MaxWindow-sent, 'f', -1, 64) +
+			" of the tier " + tj.Name + " in " + strconv.FormatInt(tj.Window, 10) + " blocks.")
+	}
+	return nil
+}
+
+// recordSent counts the coins in the account's window, the standing order counts on its payments.
+func (tca *TCApplication) recordSent(account []byte, coins float64) {
+	tj := tca.state.GetTier(tca.state.GetAccountTier(account))
+	if tj.Window == 0 {
+		return
+	}
+	tca.state.AddSent(account, tca.blockHeight-tj.Window, SentEntry{Height: tca.blockHeight, Coins: coins})
+}
+
+// validateLimits rejects the confidential sends of the tiers with limits, because their coins are hidden.
+func (tca *TCApplication) validateLimits(dr DeliveryRequest) (uint32, error) {
+	if dr.Data.Action == CONFIDENTIAL_SEND_ACTION {
+		tj := tca.state.GetTier(tca.state.GetAccountTier(dr.Data.From))
+		if tj.MaxSend > 0 || tj.MaxWindow > 0 {
+			return CodeTypeUnauthorized, errors.New("The tier " + tj.Name + " has limits, its accounts can not send confidential coins.")
+		}
+		return CodeTypeOK, nil
+	}
+	if !limitedActions[dr.Data.Action] {
+		return CodeTypeOK, nil
+	}
+	err := tca.checkLimits(dr.Data.From, dr.Data.Coins)
+	if err != nil {
+		return CodeTypeUnauthorized, err
+	}
+	return CodeTypeOK, nil
+}
+
+// validateTier allows the compliance authorities to set the limits of a tier and to assign an account to a tier.
+func (tca *TCApplication) validateTier(dr DeliveryRequest) (uint32, error) {
+	if !confs.Conf.AuthorityExists(string(dr.Data.From)) {
+		return CodeTypeUnauthorized, errors.New("You are not a compliance authority.")
+	}
+	tier := dr.Data.Tier
+	if tier == nil {
+		return CodeTypeUnauthorized, errors.New("The tier is missing.")
+	}
+	if len(tier.Name) == 0 || len(tier.Name) > TIER_NAME_MAX_LENGTH {
+		return CodeTypeUnauthorized, errors.New("The tier's name should have from 1 to " + strconv.Itoa(TIER_NAME_MAX_LENGTH) + " characters.")
+	}
+	if dr.Data.Action == SET_TIER_ACTION {
+		if tier.Account != nil {
+			return CodeTypeUnauthorized, errors.New("The tier's limits do not have an account.")
+		}
+		if tier.MaxSend < 0 || tier.MaxWindow < 0 || tier.Window < 0 {
+			return CodeTypeUnauthorized, errors.New("The tier's limits can not be negative.")
+		}
+		if (tier.MaxWindow > 0) != (tier.Window > 0) {
+			return CodeTypeUnauthorized, errors.New("The tier's limit of the window needs the window's blocks.")
+		}
+		return CodeTypeOK, nil
+	}
+	if tier.Account == nil {
+		return CodeTypeUnauthorized, errors.New("The tier's account is missing.")
+	}
+	_, err := crypto.UnmarshalPublicKey(*tier.Account)
+	if err != nil {
+		return CodeTypeEncodingError, errors.New("The account's public key is not correct.")
+	}
+	return CodeTypeOK, nil
+}
+
+func (tca *TCApplication) deliverTier(dr DeliveryRequest) {
+	tier := dr.Data.Tier
+	if dr.Data.Action == SET_TIER_ACTION {
+		tca.state.SetTier(TierJson{Name: tier.Name, MaxSend: tier.MaxSend, MaxWindow: tier.MaxWindow, Window: tier.Window})
+		return
+	}
+	tca.state.SetAccountTier(*tier.Account, tier.Name)
+}
+
+// queryAllowance returns the limits of the public key in the data and the coins that it can send in the window.
+func (tca *TCApplication) queryAllowance(account []byte) types.ResponseQuery {
+	_, err := crypto.UnmarshalPublicKey(account)
+	if err != nil {
+		return types.ResponseQuery{Code: CodeTypeEncodingError, Log: "The public key is not correct."}
+	}
+	tj, sent := tca.accountLimits(account)
+	ar := AllowanceResponse{Tier: tj.Name, MaxSend: tj.MaxSend, MaxWindow: tj.MaxWindow, Window: tj.Window, Sent: sent, Remaining: -1}
+	if tj.MaxWindow > 0 {
+		ar.Remaining = tj.MaxWindow - sent
+		if ar.Remaining < 0 {
+			ar.Remaining = 0
+		}
+	}
+	b, _ := json.Marshal(ar)
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
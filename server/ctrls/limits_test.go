@@ -0,0 +1,135 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+)
+
+func (tu *testUtils) allowance(t *testing.T, app *TCApplication, account crypto.PubKey) AllowanceResponse {
+	resp := app.Query(types.RequestQuery{Path: ALLOWANCE_QUERY_PATH, Data: tu.pubBytes(account)})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	ar := AllowanceResponse{}
+	json.Unmarshal(resp.Value, &ar)
+	return ar
+}
+
+func TestLimitsOfTheTier(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	authorityPrivk, authorityPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	conf := confs.Conf
+	defer func() { confs.Conf = conf }()
+	tu.addAuthority(t, authorityPubk)
+	accountPrivk, accountPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, accountPrivk, 1000)
+
+	// the accounts without a tier have no limits
+	ar := tu.allowance(t, app, accountPubk)
+	assert.Equal(t, DEFAULT_TIER, ar.Tier)
+	assert.Equal(t, float64(-1), ar.Remaining)
+
+	code := tu.deliver(t, app, accountPrivk, DeliveryData{Action: SET_TIER_ACTION, Tier: &Tier{Name: "retail", MaxSend: 100, MaxWindow: 150, Window: 10}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	code = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: SET_TIER_ACTION, Tier: &Tier{Name: "retail", MaxSend: 100, MaxWindow: 150}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	code = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: SET_TIER_ACTION, Tier: &Tier{Name: "retail", MaxSend: 100, MaxWindow: 150, Window: 10}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	account := tu.pubBytes(accountPubk)
+	code = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: ASSIGN_TIER_ACTION, Tier: &Tier{Name: "retail", Account: &account}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+
+	send := func(coins float64) []byte {
+		dr := tu.sendCoins(t, accountPrivk, receiverPubk, taxHash, coins)
+		b, _ := json.Marshal(dr)
+		return b
+	}
+	assert.Equal(t, CodeTypeUnauthorized, app.CheckTx(send(120)).Code)
+	assert.Equal(t, CodeTypeUnauthorized, app.DeliverTx(send(120)).Code)
+	assert.Equal(t, CodeTypeOK, app.DeliverTx(send(100)).Code)
+	assert.Equal(t, CodeTypeUnauthorized, app.CheckTx(send(60)).Code)
+	assert.Equal(t, CodeTypeUnauthorized, app.DeliverTx(send(60)).Code)
+	assert.Equal(t, CodeTypeOK, app.DeliverTx(send(50)).Code)
+
+	ar = tu.allowance(t, app, accountPubk)
+	assert.Equal(t, "retail", ar.Tier)
+	assert.Equal(t, float64(150), ar.Sent)
+	assert.Equal(t, float64(0), ar.Remaining)
+
+	// the window rolls after its blocks
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 11}})
+	ar = tu.allowance(t, app, accountPubk)
+	assert.Equal(t, float64(150), ar.Remaining)
+	assert.Equal(t, CodeTypeOK, app.CheckTx(send(60)).Code)
+	assert.Equal(t, CodeTypeOK, app.DeliverTx(send(60)).Code)
+
+	// the default tier is the account's tier again
+	code = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: ASSIGN_TIER_ACTION, Tier: &Tier{Name: DEFAULT_TIER, Account: &account}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	assert.Equal(t, CodeTypeOK, app.DeliverTx(send(500)).Code)
+}
+
+func TestLimitsOfTheConfidentialCoins(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	authorityPrivk, authorityPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	conf := confs.Conf
+	defer func() { confs.Conf = conf }()
+	tu.addAuthority(t, authorityPubk)
+	accountPrivk, accountPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.confidentialDeposit(t, app, accountPrivk, 100)
+	tu.addCoins(t, app, accountPrivk, 1000)
+
+	code := tu.deliver(t, app, authorityPrivk, DeliveryData{Action: SET_TIER_ACTION, Tier: &Tier{Name: "retail", MaxSend: 100, MaxWindow: 150, Window: 10}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	account := tu.pubBytes(accountPubk)
+	code = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: ASSIGN_TIER_ACTION, Tier: &Tier{Name: "retail", Account: &account}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+
+	// the deposits count in the limits
+	deposit := func(coins float64) []byte {
+		dr := tu.inflatorCoins(t, accountPrivk, CONFIDENTIAL_DEPOSIT_ACTION, coins)
+		dr.Data.Confidential = &Confidential{SenderNote: []byte("deposit")}
+		tu.signDelivery(t, &dr, accountPrivk)
+		b, _ := json.Marshal(dr)
+		return b
+	}
+	assert.Equal(t, CodeTypeUnauthorized, app.CheckTx(deposit(120)).Code)
+	assert.Equal(t, CodeTypeUnauthorized, app.DeliverTx(deposit(120)).Code)
+	assert.Equal(t, CodeTypeOK, app.DeliverTx(deposit(100)).Code)
+	assert.Equal(t, CodeTypeUnauthorized, app.DeliverTx(deposit(60)).Code)
+	assert.Equal(t, float64(100), tu.allowance(t, app, accountPubk).Sent)
+
+	// the limited tiers can not send the hidden coins
+	dr, _, _ := tu.confidentialSend(t, accountPrivk, receiverPubk, taxHash, 20000, suite.Scalar().Zero(), 100, 10)
+	b, _ := json.Marshal(dr)
+	assert.Equal(t, CodeTypeUnauthorized, app.CheckTx(b).Code)
+	assert.Equal(t, CodeTypeUnauthorized, app.DeliverTx(b).Code)
+
+	code = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: ASSIGN_TIER_ACTION, Tier: &Tier{Name: DEFAULT_TIER, Account: &account}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	assert.Equal(t, CodeTypeOK, app.DeliverTx(b).Code)
+}
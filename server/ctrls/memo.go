@@ -0,0 +1,54 @@
+package ctrls
+
+import (
+	"errors"
+	"strconv"
+)
+
+const (
+	MEMO_MAX_SIZE = 256 // bytes of the text
+	// the sender, the receiver and a watcher
+	MEMO_MAX_READERS = 3
+
+	memoNonceSize = 24
+	memoOverhead  = 16 // secretbox's authenticator
+	memoKeySize   = memoNonceSize + 32 + memoOverhead
+)
+
+// validateMemo checks the size of the memo, the server can not read the encrypted memos.
+func (tca *TCApplication) validateMemo(dr DeliveryRequest) (uint32, error) {
+	memo := dr.Data.Memo
+	encrypted := len(memo.Box) > 0 || len(memo.Keys) > 0 || len(memo.Ephemeral) > 0
+	if len(memo.Text) > 0 && encrypted {
+		return CodeTypeUnauthorized, errors.New("The memo can not be both text and encrypted.")
+	}
+	if !encrypted {
+		if len(memo.Text) == 0 {
+			return CodeTypeUnauthorized, errors.New("The memo is empty.")
+		}
+		if len(memo.Text) > MEMO_MAX_SIZE {
+			return CodeTypeUnauthorized, errors.New("The memo can not be more than " + strconv.Itoa(MEMO_MAX_SIZE) + " bytes.")
+		}
+		return CodeTypeOK, nil
+	}
+
+	if len(memo.Box) <= memoNonceSize+memoOverhead {
+		return CodeTypeEncodingError, errors.New("The encrypted memo is not correct.")
+	}
+	if len(memo.Box) > memoNonceSize+memoOverhead+MEMO_MAX_SIZE {
+		return CodeTypeUnauthorized, errors.New("The memo can not be more than " + strconv.Itoa(MEMO_MAX_SIZE) + " bytes.")
+	}
+	if len(memo.Keys) == 0 || len(memo.Keys) > MEMO_MAX_READERS {
+		return CodeTypeUnauthorized, errors.New("The encrypted memo should have from 1 to " + strconv.Itoa(MEMO_MAX_READERS) + " readers.")
+	}
+	for _, k := range memo.Keys {
+		if len(k) != memoKeySize {
+			return CodeTypeEncodingError, errors.New("The key of the encrypted memo is not correct.")
+		}
+	}
+	p := suite.Point()
+	if len(memo.Ephemeral) != p.MarshalSize() || p.UnmarshalBinary(memo.Ephemeral) != nil {
+		return CodeTypeEncodingError, errors.New("The memo's ephemeral key is not a point of the curve.")
+	}
+	return CodeTypeOK, nil
+}
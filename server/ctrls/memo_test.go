@@ -0,0 +1,93 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoSendSuccessfully(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+
+	fromPrivk, fromPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, toPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+
+	fromPubkB, _ := fromPubk.Bytes()
+	confs.Conf.IpfsInflators = tu.addInflator(t, fromPubkB)
+	confs.Conf.SubmitInflators()
+	dr := tu.inflatorCoins(t, fromPrivk, ADD_ACTION, 100)
+	b, _ := json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	dr = tu.sendCoins(t, fromPrivk, toPubk, taxHash, 50)
+	dr.Data.Memo = &Memo{Text: "invoice 42"}
+	tu.signDelivery(t, &dr, fromPrivk)
+	b, _ = json.Marshal(dr)
+	resp = app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	resp2 := tu.ownQuery(t, app, HISTORY_QUERY_PATH, fromPrivk)
+	assert.Equal(t, CodeTypeOK, resp2.Code)
+	hr := HistoryResponse{}
+	err = json.Unmarshal(resp2.Value, &hr)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(hr.Entries))
+	assert.Equal(t, &Memo{Text: "invoice 42"}, hr.Entries[1].Memo)
+
+	// the memo is covered by the signature
+	dr.Data.Memo.Text = "invoice 43"
+	b, _ = json.Marshal(dr)
+	resp = app.DeliverTx(b)
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+}
+
+func TestMemoSendFailWrongMemo(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+
+	fromPrivk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, toPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+
+	ephemeral, _ := suite.Point().Pick(suite.RandomStream()).MarshalBinary()
+	memos := map[uint32]*Memo{
+		CodeTypeUnauthorized:  {Text: strings.Repeat("a", MEMO_MAX_SIZE+1)},
+		CodeTypeEncodingError: {Ephemeral: ephemeral, Keys: [][]byte{make([]byte, 10)}, Box: make([]byte, 50)},
+	}
+	for code, memo := range memos {
+		dr := tu.sendCoins(t, fromPrivk, toPubk, taxHash, 50)
+		dr.Data.Memo = memo
+		tu.signDelivery(t, &dr, fromPrivk)
+		b, _ := json.Marshal(dr)
+		resp := app.DeliverTx(b)
+		assert.Equal(t, code, resp.Code)
+	}
+
+	// the encrypted memo can not have more readers than the sender, the receiver and a watcher
+	keys := [][]byte{}
+	for i := 0; i <= MEMO_MAX_READERS; i++ {
+		keys = append(keys, make([]byte, memoKeySize))
+	}
+	dr := tu.sendCoins(t, fromPrivk, toPubk, taxHash, 50)
+	dr.Data.Memo = &Memo{Ephemeral: ephemeral, Keys: keys, Box: make([]byte, 50)}
+	tu.signDelivery(t, &dr, fromPrivk)
+	b, _ := json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+}
@@ -21,23 +21,232 @@ const (
 	ADD_ACTION    = ActionStruct("add")
 	REMOVE_ACTION = ActionStruct("remove")
 	SEND_ACTION   = ActionStruct("send")
+
+	CONFIDENTIAL_DEPOSIT_ACTION  = ActionStruct("confidential_deposit")
+	CONFIDENTIAL_WITHDRAW_ACTION = ActionStruct("confidential_withdraw")
+	CONFIDENTIAL_SEND_ACTION     = ActionStruct("confidential_send")
+
+	VIEW_GRANT_ACTION  = ActionStruct("view_grant")
+	VIEW_REVOKE_ACTION = ActionStruct("view_revoke")
+
+	AUDIT_ANCHOR_ACTION = ActionStruct("audit_anchor")
+
+	ESCROW_OPEN_ACTION    = ActionStruct("escrow_open")
+	ESCROW_RELEASE_ACTION = ActionStruct("escrow_release")
+	ESCROW_REFUND_ACTION  = ActionStruct("escrow_refund")
+
+	HTLC_LOCK_ACTION   = ActionStruct("htlc_lock")
+	HTLC_CLAIM_ACTION  = ActionStruct("htlc_claim")
+	HTLC_REFUND_ACTION = ActionStruct("htlc_refund")
+
+	STANDING_ORDER_ACTION        = ActionStruct("standing_order")
+	STANDING_ORDER_CANCEL_ACTION = ActionStruct("standing_order_cancel")
+	// the history's action of a payment that the chain executes
+	STANDING_ORDER_PAYMENT_ACTION = ActionStruct("standing_order_payment")
+
+	FREEZE_ACTION   = ActionStruct("freeze")
+	UNFREEZE_ACTION = ActionStruct("unfreeze")
+	SEIZE_ACTION    = ActionStruct("seize")
+
+	SET_TIER_ACTION    = ActionStruct("set_tier")
+	ASSIGN_TIER_ACTION = ActionStruct("assign_tier")
+
+	ATTEST_ACTION        = ActionStruct("attest")
+	ATTEST_REVOKE_ACTION = ActionStruct("attest_revoke")
+)
+
+const (
+	TAX_QUERY_PATH = "/tax"
+
+	TAX_REPORT_QUERY_PATH = "/tax/report"
+
+	CONFIDENTIAL_QUERY_PATH = "/confidential"
+
+	HISTORY_QUERY_PATH     = "/history"
+	VIEW_GRANTS_QUERY_PATH = "/view/grants"
+
+	AUDIT_QUERY_PATH      = "/audit"
+	AUDIT_HEAD_QUERY_PATH = "/audit/head"
+
+	ESCROW_QUERY_PATH = "/escrow"
+	HTLC_QUERY_PATH   = "/htlc"
+
+	STANDING_ORDER_QUERY_PATH          = "/standing-order"
+	STANDING_ORDER_PAYMENTS_QUERY_PATH = "/standing-order/payments"
+
+	COMPLIANCE_QUERY_PATH = "/compliance"
+
+	ALLOWANCE_QUERY_PATH = "/allowance"
+
+	ATTESTATION_QUERY_PATH = "/attestation"
+)
+
+type ViewKind string
+
+const (
+	VIEW_BALANCE = ViewKind("balance")
+	VIEW_HISTORY = ViewKind("history") // the balance and the history
 )
 
+// RangeProof proves that a commitment hides a number of len(Bits) bits,
+// every bit is a commitment with a ring signature that it hides 0 or 1.
+type RangeProof struct {
+	Bits       [][]byte // commitments
+	Signatures [][]byte
+}
+
+// Confidential hides the coins of the confidential actions in Pedersen commitments.
+// The notes are the openings of the new commitments, encrypted to their owners.
+type Confidential struct {
+	Amount          []byte // commitment of the coins of a send
+	Tax             []byte // commitment of the tax of a send
+	AmountProof     *RangeProof
+	TaxProof        *RangeProof
+	RemainderProofs []RangeProof // the tax's rounding is between 0 and 99
+	BalanceProof    *RangeProof  // the sender's balance after the action
+	SenderNote      []byte
+	ReceiverNote    []byte
+	TaxNote         []byte
+}
+
+// ViewGrant lets a watcher see the sender's account between the two dates.
+type ViewGrant struct {
+	Watcher   []byte // public key
+	Kind      ViewKind
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// AuditAnchor keeps the hash of an entry of a node's audit log in the chain,
+// only the log's anchorer can anchor it, the sender and the height are set by the chain.
+type AuditAnchor struct {
+	Log    string
+	Index  int64
+	Hash   []byte
+	From   []byte `json:",omitempty"`
+	Height int64  `json:",omitempty"`
+}
+
+// Stealth is a send to a one-time key, the receiver finds it with the scan key of its stealth address
+// and the ephemeral key that the sender used to derive the one-time key.
+type Stealth struct {
+	Ephemeral []byte // point of the curve
+}
+
+// Memo is the context of a send, as text or encrypted for the sender, the receiver and a watcher.
+// The encrypted memo's key is sealed for every reader with the X25519 secret of the ephemeral key and the reader's key.
+type Memo struct {
+	Text      string   `json:",omitempty"`
+	Ephemeral []byte   `json:",omitempty"` // point of the curve
+	Keys      [][]byte `json:",omitempty"` // nonce and sealed key of every reader
+	Box       []byte   `json:",omitempty"` // nonce and sealed text
+}
+
+// Escrow locks the coins of the sender for the receiver until the sender or the arbiter releases them,
+// the open escrow sets the arbiter and the height of the timeout.
+type Escrow struct {
+	ID      string
+	Arbiter *[]byte `json:",omitempty"` // public key
+	Timeout int64   `json:",omitempty"` // height
+}
+
+// Htlc locks the coins of the sender under the SHA-256 hash of a secret until the timeout,
+// the claim reveals the secret and the sender refunds after the timeout.
+type Htlc struct {
+	Hashlock []byte
+	Timeout  int64  `json:",omitempty"` // height
+	Preimage []byte `json:",omitempty"`
+}
+
+// StandingOrder pays the coins of the send to the receiver every interval of blocks,
+// from the start until the end height.
+type StandingOrder struct {
+	ID       string
+	Interval int64 `json:",omitempty"` // blocks
+	Start    int64 `json:",omitempty"` // height, by default the next block
+	End      int64 `json:",omitempty"` // height
+}
+
+// Compliance is the account that an authority freezes, unfreezes or seizes, with the reference of the order.
+type Compliance struct {
+	Account []byte // public key
+	Reason  string
+}
+
+// Tier is the limits of the accounts of the tier, or the tier of an account for the assignment.
+// The limits of zero do not limit.
+type Tier struct {
+	Name      string
+	MaxSend   float64 `json:",omitempty"` // coins of a send
+	MaxWindow float64 `json:",omitempty"` // coins that are sent in the window
+	Window    int64   `json:",omitempty"` // blocks
+	Account   *[]byte `json:",omitempty"` // public key
+}
+
+// Attestation is an attestor's signed record of the identity's level of an account until the expiry,
+// the identity's document stays off the chain and the attestation keeps only its SHA-256 hash.
+// The revocation needs only the account.
+type Attestation struct {
+	Account   []byte // public key
+	Level     int    `json:",omitempty"`
+	Expiry    int64  `json:",omitempty"` // height
+	Document  []byte `json:",omitempty"` // SHA-256 hash
+	Attestor  []byte `json:",omitempty"` // public key
+	Signature []byte `json:",omitempty"`
+}
+
 type DeliveryData struct {
 	From    []byte  // public key
 	To      *[]byte // public key
 	Action  ActionStruct
 	TaxHash *string
 	Coins   float64
+	Stealth *Stealth `json:",omitempty"`
+	Memo    *Memo    `json:",omitempty"`
+
+	Confidential *Confidential `json:",omitempty"`
+
+	ViewGrant *ViewGrant `json:",omitempty"`
+
+	AuditAnchor *AuditAnchor `json:",omitempty"`
+
+	Escrow *Escrow `json:",omitempty"`
+	Htlc   *Htlc   `json:",omitempty"`
+
+	StandingOrder *StandingOrder `json:",omitempty"`
+
+	Compliance *Compliance `json:",omitempty"`
+	Tier       *Tier       `json:",omitempty"`
+
+	Attestation *Attestation `json:",omitempty"`
+}
+
+// Multisig is the threshold and the members' keys of a multisig account, the account's key is derived from them.
+type Multisig struct {
+	Threshold int
+	Members   [][]byte // public keys
+}
+
+type MultisigSignature struct {
+	Member    []byte // public key
+	Signature []byte
 }
 
 type DeliveryRequest struct {
 	Signature []byte
 	Date      time.Time
 	Data      DeliveryData
+
+	// the multisig account signs with the signatures of its members instead of the signature
+	Multisig   *Multisig           `json:",omitempty"`
+	Signatures []MultisigSignature `json:",omitempty"`
 }
 
 func (dr *DeliveryRequest) VerifySignature() (bool, error) {
+	if dr.Multisig != nil {
+		b, _ := json.Marshal(dr.Data)
+		return dr.Multisig.verify(dr.Data.From, b, dr.Signatures, dr.Multisig.Threshold)
+	}
 	pub, err := crypto.UnmarshalPublicKey(dr.Data.From)
 	if err != nil {
 		return false, errors.New("The sender's public key is not correct")
@@ -55,14 +264,26 @@ type QueryData struct {
 	Date  time.Time
 	Nonce string
 	User  *[]byte
+
+	// the range of the blocks of the tax report, by default until the last block
+	FromHeight int64 `json:",omitempty"`
+	ToHeight   int64 `json:",omitempty"`
 }
 
 type QueryRequest struct {
 	Signature []byte
 	Data      QueryData
+
+	// a member's signature is enough to read the multisig account
+	Multisig   *Multisig           `json:",omitempty"`
+	Signatures []MultisigSignature `json:",omitempty"`
 }
 
 func (qr *QueryRequest) VerifySignature() (bool, error) {
+	if qr.Multisig != nil {
+		b, _ := json.Marshal(qr.Data)
+		return qr.Multisig.verify(qr.Data.From, b, qr.Signatures, 1)
+	}
 	pub, err := crypto.UnmarshalPublicKey(qr.Data.From)
 	if err != nil {
 		return false, errors.New("The public key is not correct")
@@ -78,3 +299,102 @@ func (qr *QueryRequest) VerifySignature() (bool, error) {
 type QueryResponse struct {
 	Coins float64
 }
+
+type TaxResponse struct {
+	Hash       string
+	Percentage int
+	Receiver   []byte // public key
+}
+
+type ConfidentialResponse struct {
+	Balance []byte // commitment
+	Notes   [][]byte
+}
+
+type HistoryEntry struct {
+	Height int64
+	Time   time.Time
+	Hash   []byte // the transaction's hash
+	Action ActionStruct
+	From   []byte  // public key
+	To     *[]byte // public key
+	Coins  float64
+	Memo   *Memo `json:",omitempty"`
+
+	StandingOrder *StandingOrder `json:",omitempty"`
+
+	// the public coins that the transaction moved, the payee receives the coins without the tax
+	Payer       []byte  `json:",omitempty"` // public key
+	Payee       []byte  `json:",omitempty"` // public key
+	Tax         float64 `json:",omitempty"`
+	TaxReceiver []byte  `json:",omitempty"` // public key
+}
+
+// StandingOrderPaymentsRequest is the range of heights of the payments, a zero to height is until the latest.
+type StandingOrderPaymentsRequest struct {
+	FromHeight int64
+	ToHeight   int64
+}
+
+type StandingOrderPaymentsResponse struct {
+	Payments []StandingOrderPayment
+}
+
+type ComplianceResponse struct {
+	Frozen   *FreezeJson `json:",omitempty"`
+	Seizures []SeizureJson
+}
+
+type AllowanceResponse struct {
+	Tier      string
+	MaxSend   float64
+	MaxWindow float64
+	Window    int64 // blocks
+	Sent      float64
+	Remaining float64 // the coins that the account can send in the window, -1 without limit
+}
+
+type AttestationResponse struct {
+	Attestation *AttestationJson `json:",omitempty"`
+	Valid       bool             // the attestation is not expired
+}
+
+type TaxReportResponse struct {
+	FromHeight int64
+	ToHeight   int64
+	Receiver   []byte // public key
+	Blocks     []TaxBlockJson
+}
+
+type HistoryResponse struct {
+	Entries []HistoryEntry
+}
+
+type ViewGrantsResponse struct {
+	Grants []ViewGrant
+}
+
+// AuditEntry is a watcher's query in the audit log of the node.
+type AuditEntry struct {
+	Index    int64
+	Watcher  []byte // public key
+	User     []byte // public key
+	Path     string
+	Time     time.Time
+	Nonce    string
+	Previous []byte // the hash of the previous entry
+	Hash     []byte
+}
+
+type AuditHead struct {
+	Log   string `json:",omitempty"`
+	Index int64
+	Hash  []byte
+}
+
+type AuditResponse struct {
+	Entries    []AuditEntry
+	Head       AuditHead
+	Anchored   int64 // the highest index of the log in an anchor
+	Mismatches []AuditAnchor
+}
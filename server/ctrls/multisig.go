@@ -0,0 +1,85 @@
+package ctrls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+)
+
+const MULTISIG_MAX_MEMBERS = 16
+
+// Account returns the key of the multisig account, an Ed25519 key of the hash of the threshold and the members,
+// so the account works as any other account and nobody has its private key.
+func (m *Multisig) Account() []byte {
+	h := sha256.New()
+	h.Write([]byte("theftcoin-multisig"))
+	n := make([]byte, 4)
+	binary.BigEndian.PutUint32(n, uint32(m.Threshold))
+	h.Write(n)
+	for _, member := range m.Members {
+		binary.BigEndian.PutUint32(n, uint32(len(member)))
+		h.Write(n)
+		h.Write(member)
+	}
+	return append(append([]byte{}, ed25519PublicKeyHeader...), h.Sum(nil)...)
+}
+
+func (m *Multisig) validate() error {
+	if len(m.Members) == 0 || len(m.Members) > MULTISIG_MAX_MEMBERS {
+		return errors.New("The multisig should have from 1 to " + strconv.Itoa(MULTISIG_MAX_MEMBERS) + " members.")
+	}
+	if m.Threshold <= 0 || m.Threshold > len(m.Members) {
+		return errors.New("The multisig's threshold should be from 1 to the number of the members.")
+	}
+	// the members are sorted, so the same members have one account
+	for i, member := range m.Members {
+		if i > 0 && bytes.Compare(m.Members[i-1], member) >= 0 {
+			return errors.New("The multisig's members should be sorted and different.")
+		}
+		_, err := crypto.UnmarshalPublicKey(member)
+		if err != nil {
+			return errors.New("The multisig's member " + strconv.Itoa(i) + " is not a public key.")
+		}
+	}
+	return nil
+}
+
+func (m *Multisig) isMember(pub []byte) bool {
+	for _, member := range m.Members {
+		if bytes.Equal(member, pub) {
+			return true
+		}
+	}
+	return false
+}
+
+// verify checks that the multisig is the account's and that at least the threshold of different members signed the bytes.
+func (m *Multisig) verify(account []byte, b []byte, sigs []MultisigSignature, threshold int) (bool, error) {
+	err := m.validate()
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(m.Account(), account) {
+		return false, errors.New("The multisig is not the sender's account.")
+	}
+	signed := map[string]bool{}
+	for _, sig := range sigs {
+		if !m.isMember(sig.Member) || signed[string(sig.Member)] {
+			return false, errors.New("The signatures should be of different members of the multisig.")
+		}
+		pub, _ := crypto.UnmarshalPublicKey(sig.Member)
+		ver, err := pub.Verify(b, sig.Signature)
+		if err != nil {
+			return false, errors.New("The signature's format is not correct.")
+		}
+		if !ver {
+			return false, nil
+		}
+		signed[string(sig.Member)] = true
+	}
+	return len(signed) >= threshold, nil
+}
@@ -0,0 +1,126 @@
+package ctrls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+)
+
+func (tu *testUtils) multisig(t *testing.T, threshold int, n int) (*Multisig, []crypto.PrivKey) {
+	members := []crypto.PrivKey{}
+	for i := 0; i < n; i++ {
+		privk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+		assert.Nil(t, err)
+		members = append(members, privk)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		a, _ := members[i].GetPublic().Bytes()
+		b, _ := members[j].GetPublic().Bytes()
+		return bytes.Compare(a, b) < 0
+	})
+	m := &Multisig{Threshold: threshold}
+	for _, privk := range members {
+		b, _ := privk.GetPublic().Bytes()
+		m.Members = append(m.Members, b)
+	}
+	return m, members
+}
+
+func (tu *testUtils) multisigSign(t *testing.T, b []byte, signers ...crypto.PrivKey) []MultisigSignature {
+	sigs := []MultisigSignature{}
+	for _, signer := range signers {
+		sig, err := signer.Sign(b)
+		assert.Nil(t, err)
+		member, _ := signer.GetPublic().Bytes()
+		sigs = append(sigs, MultisigSignature{Member: member, Signature: sig})
+	}
+	return sigs
+}
+
+func TestMultisigSendWithTheThreshold(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+
+	inflatorPrivk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, inflatorPrivk, 100)
+
+	m, members := tu.multisig(t, 2, 3)
+	accountPubk, err := crypto.UnmarshalPublicKey(m.Account())
+	assert.Nil(t, err)
+	dr := tu.sendCoins(t, inflatorPrivk, accountPubk, taxHash, 100)
+	b, _ := json.Marshal(dr)
+	assert.Equal(t, CodeTypeOK, app.DeliverTx(b).Code)
+
+	dr = DeliveryRequest{Multisig: m}
+	dr.Data.Action = SEND_ACTION
+	dr.Data.From = m.Account()
+	to, _ := receiverPubk.Bytes()
+	dr.Data.To = &to
+	dr.Data.TaxHash = &taxHash
+	dr.Data.Coins = 50
+	data, _ := json.Marshal(dr.Data)
+
+	// one member is not enough
+	dr.Signatures = tu.multisigSign(t, data, members[0])
+	b, _ = json.Marshal(dr)
+	assert.Equal(t, CodeTypeUnauthorized, app.DeliverTx(b).Code)
+
+	// the same member twice
+	dr.Signatures = tu.multisigSign(t, data, members[0], members[0])
+	b, _ = json.Marshal(dr)
+	assert.Equal(t, CodeTypeEncodingError, app.DeliverTx(b).Code)
+
+	// the members of another account
+	other, otherMembers := tu.multisig(t, 2, 3)
+	dr.Multisig = other
+	dr.Signatures = tu.multisigSign(t, data, otherMembers[0], otherMembers[1])
+	b, _ = json.Marshal(dr)
+	assert.Equal(t, CodeTypeEncodingError, app.DeliverTx(b).Code)
+
+	dr.Multisig = m
+	dr.Signatures = tu.multisigSign(t, data, members[0], members[2])
+	b, _ = json.Marshal(dr)
+	assert.Equal(t, CodeTypeOK, app.DeliverTx(b).Code)
+	cj, _ := app.state.GetCoins(accountPubk)
+	assert.Equal(t, float64(40), cj.Coins)
+	cj, _ = app.state.GetCoins(receiverPubk)
+	assert.Equal(t, float64(45), cj.Coins)
+}
+
+func TestMultisigMemberQueriesTheAccount(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	m, members := tu.multisig(t, 2, 2)
+
+	qr := QueryRequest{Multisig: m}
+	qr.Data.From = m.Account()
+	qr.Data.Date = time.Now().UTC()
+	qr.Data.Nonce = tu.nonce()
+	b, _ := json.Marshal(qr.Data)
+	qr.Signatures = tu.multisigSign(t, b, members[1])
+	b, _ = json.Marshal(qr)
+	resp := app.Query(types.RequestQuery{Data: b})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	// the members are sorted, so the other order is another account
+	m.Members[0], m.Members[1] = m.Members[1], m.Members[0]
+	qr.Data.Nonce = tu.nonce()
+	b, _ = json.Marshal(qr.Data)
+	qr.Signatures = tu.multisigSign(t, b, members[1])
+	b, _ = json.Marshal(qr)
+	resp = app.Query(types.RequestQuery{Data: b})
+	assert.Equal(t, CodeTypeEncodingError, resp.Code)
+}
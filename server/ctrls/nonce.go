@@ -0,0 +1,36 @@
+package ctrls
+
+import (
+	"sync"
+	"time"
+)
+
+const QUERY_NONCE_MAX_LENGTH = 64
+
+// queryNonces remembers the nonces of the queries while their dates are accepted.
+type queryNonces struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func newQueryNonces() *queryNonces {
+	return &queryNonces{seen: map[string]time.Time{}}
+}
+
+// use returns false if the key used the nonce before,
+// the nonces with dates before the oldest are forgotten because their queries are rejected anyway.
+func (qn *queryNonces) use(from []byte, nonce string, date time.Time, oldest time.Time) bool {
+	qn.mu.Lock()
+	defer qn.mu.Unlock()
+	for k, d := range qn.seen {
+		if d.Before(oldest) {
+			delete(qn.seen, k)
+		}
+	}
+	key := string(from) + ":" + nonce
+	if _, ok := qn.seen[key]; ok {
+		return false
+	}
+	qn.seen[key] = date
+	return true
+}
@@ -2,12 +2,38 @@ package ctrls
 
 import (
 	"encoding/binary"
+	"encoding/json"
+	"time"
 
 	"github.com/tendermint/abci/example/code"
 	"github.com/tendermint/abci/types"
 )
 
+func (tca *TCApplication) BeginBlock(req types.RequestBeginBlock) types.ResponseBeginBlock {
+	tca.blockHeight = req.Header.Height
+	tca.blockTime = time.Unix(req.Header.Time, 0).UTC()
+	tca.blockTxs = 0
+	tca.taxBlock = TaxBlockJson{Height: tca.blockHeight, BalanceBefore: tca.taxReceiverCoins()}
+	return types.ResponseBeginBlock{}
+}
+
+func (tca *TCApplication) EndBlock(req types.RequestEndBlock) types.ResponseEndBlock {
+	tags := tca.executeStandingOrders()
+	tca.saveTaxBlock()
+	return types.ResponseEndBlock{Tags: tags}
+}
+
+// CheckTx keeps the sends over the limits out of the mempool.
 func (tca *TCApplication) CheckTx(tx []byte) types.ResponseCheckTx {
+	dr := DeliveryRequest{}
+	err := json.Unmarshal(tx, &dr)
+	if err != nil {
+		return types.ResponseCheckTx{Code: CodeTypeEncodingError, Log: "The json is not correct."}
+	}
+	c, err := tca.validateLimits(dr)
+	if err != nil {
+		return types.ResponseCheckTx{Code: c, Log: err.Error()}
+	}
 	return types.ResponseCheckTx{Code: code.CodeTypeOK}
 }
 
@@ -3,6 +3,7 @@ package ctrls
 import (
 	"encoding/json"
 	"errors"
+	"strconv"
 	"time"
 
 	crypto "github.com/libp2p/go-libp2p-crypto"
@@ -10,18 +11,35 @@ import (
 	"github.com/tendermint/abci/types"
 )
 
-func (tca *TCApplication) validateQuery(qr QueryRequest) (uint32, error) {
-	now := time.Now().UTC()
-	since := now.Sub(qr.Data.Date)
-	if since > time.Duration(time.Duration(confs.Conf.WaitingRequestTime)*time.Second) {
+func (tca *TCApplication) validateQuery(qr QueryRequest, now time.Time) (uint32, error) {
+	return verifyQuery(qr, now, tca.nonces)
+}
+
+// QueryVerifier checks the signed queries for the services outside the chain, like the explorer, the same way as the chain.
+type QueryVerifier struct {
+	nonces *queryNonces
+}
+
+func NewQueryVerifier() *QueryVerifier {
+	return &QueryVerifier{nonces: newQueryNonces()}
+}
+
+func (qv *QueryVerifier) Verify(qr QueryRequest, now time.Time) (uint32, error) {
+	return verifyQuery(qr, now, qv.nonces)
+}
+
+// verifyQuery accepts the dates of the waiting time, and the clocks that differ by the skew in both directions.
+// The nonce is checked after the signature, so only the key can use its nonces.
+func verifyQuery(qr QueryRequest, now time.Time, nonces *queryNonces) (uint32, error) {
+	skew := time.Duration(confs.Conf.QuerySkew) * time.Second
+	oldest := now.Add(-time.Duration(confs.Conf.WaitingRequestTime)*time.Second - skew)
+	if qr.Data.Date.Before(oldest) {
 		return CodeTypeUnauthorized, errors.New("Request passed its time.")
 	}
-
-	if qr.Data.User != nil {
-		if !confs.Conf.WatcherExists(string(qr.Data.From)) {
-			return CodeTypeUnauthorized, errors.New("You are not a watcher.")
-		}
+	if qr.Data.Date.After(now.Add(skew)) {
+		return CodeTypeUnauthorized, errors.New("Request is in the future.")
 	}
+
 	ver, err := qr.VerifySignature()
 	if err != nil {
 		return CodeTypeEncodingError, err
@@ -29,32 +47,117 @@ func (tca *TCApplication) validateQuery(qr QueryRequest) (uint32, error) {
 	if !ver {
 		return CodeTypeUnauthorized, errors.New("The signature does not validate the query.")
 	}
+
+	if len(qr.Data.Nonce) == 0 || len(qr.Data.Nonce) > QUERY_NONCE_MAX_LENGTH {
+		return CodeTypeBadNonce, errors.New("The nonce should have from 1 to " + strconv.Itoa(QUERY_NONCE_MAX_LENGTH) + " characters.")
+	}
+	if !nonces.use(qr.Data.From, qr.Data.Nonce, qr.Data.Date, oldest) {
+		return CodeTypeBadNonce, errors.New("The nonce is already used.")
+	}
 	return CodeTypeOK, nil
 }
 
+func (tca *TCApplication) queryTax() types.ResponseQuery {
+	if len(confs.Conf.IpfsTax) == 0 {
+		return types.ResponseQuery{Code: CodeTypeUnauthorized, Log: "The tax is not submitted."}
+	}
+	tr := TaxResponse{}
+	tr.Hash = confs.Conf.IpfsTax
+	tr.Percentage = confs.Conf.Tax.Percentage
+	tr.Receiver, _ = confs.Conf.Tax.Bytes()
+	b, _ := json.Marshal(tr)
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
+
+// queryConfidential returns the confidential balance of the public key in the data,
+// the commitment and the notes are public in the blocks too.
+func (tca *TCApplication) queryConfidential(pub []byte) types.ResponseQuery {
+	_, err := crypto.UnmarshalPublicKey(pub)
+	if err != nil {
+		return types.ResponseQuery{Code: CodeTypeEncodingError, Log: "The public key is not correct."}
+	}
+	cj := tca.state.GetConfidential(pub)
+	cr := ConfidentialResponse{Balance: cj.Balance, Notes: cj.Notes}
+	b, _ := json.Marshal(cr)
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
+
 func (tca *TCApplication) Query(qreq types.RequestQuery) types.ResponseQuery {
+	switch qreq.Path {
+	case TAX_QUERY_PATH:
+		return tca.queryTax()
+	case CONFIDENTIAL_QUERY_PATH:
+		return tca.queryConfidential(qreq.Data)
+	case VIEW_GRANTS_QUERY_PATH:
+		return tca.queryViewGrants(qreq.Data)
+	case AUDIT_HEAD_QUERY_PATH:
+		return tca.queryAuditHead()
+	case ESCROW_QUERY_PATH:
+		return tca.queryEscrow(string(qreq.Data))
+	case HTLC_QUERY_PATH:
+		return tca.queryHtlc(qreq.Data)
+	case STANDING_ORDER_QUERY_PATH:
+		return tca.queryStandingOrder(string(qreq.Data))
+	case STANDING_ORDER_PAYMENTS_QUERY_PATH:
+		return tca.queryStandingOrderPayments(qreq.Data)
+	case COMPLIANCE_QUERY_PATH:
+		return tca.queryCompliance(qreq.Data)
+	case ALLOWANCE_QUERY_PATH:
+		return tca.queryAllowance(qreq.Data)
+	case ATTESTATION_QUERY_PATH:
+		return tca.queryAttestation(qreq.Data)
+	}
+
 	qr := QueryRequest{}
 	err := json.Unmarshal(qreq.Data, &qr)
 	if err != nil {
 
 		return types.ResponseQuery{Code: CodeTypeEncodingError, Log: "The query request is not json."}
 	}
-	code, err := tca.validateQuery(qr)
+	now := time.Now().UTC()
+	code, err := tca.validateQuery(qr, now)
 	if err != nil {
-		return types.ResponseQuery{Code: code, Log: err.Error()}
+		// the server's time helps the client to find if its clock is wrong
+		return types.ResponseQuery{Code: code, Log: err.Error(), Info: now.Format(time.RFC3339Nano)}
 	}
 
-	qresp := QueryResponse{}
-	if qr.Data.User == nil {
+	if qreq.Path == AUDIT_QUERY_PATH {
+		return tca.queryAudit(qr)
+	}
+	if qreq.Path == TAX_REPORT_QUERY_PATH {
+		return tca.queryTaxReport(qr)
+	}
 
-		from, _ := crypto.UnmarshalPublicKey(qr.Data.From)
-		cj, _ := tca.state.GetCoins(from)
-		qresp.Coins = cj.Coins
-	} else {
-		user, _ := crypto.UnmarshalPublicKey(*qr.Data.User)
-		cj, _ := tca.state.GetCoins(user)
-		qresp.Coins = cj.Coins
+	kind := VIEW_BALANCE
+	if qreq.Path == HISTORY_QUERY_PATH {
+		kind = VIEW_HISTORY
+	}
+	account := qr.Data.From
+	if qr.Data.User != nil {
+		code, err := tca.validateView(qr, kind)
+		if err != nil {
+			return types.ResponseQuery{Code: code, Log: err.Error()}
+		}
+		account = *qr.Data.User
+		_, err = tca.audit.Append(qr.Data.From, account, qreq.Path, qr.Data.Nonce)
+		if err != nil {
+			return types.ResponseQuery{Code: CodeTypeUnauthorized, Log: "The query can not be audited: " + err.Error()}
+		}
+	}
+
+	if kind == VIEW_HISTORY {
+		hr := HistoryResponse{Entries: tca.state.GetHistory(account)}
+		b, _ := json.Marshal(hr)
+		return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+	}
+
+	qresp := QueryResponse{}
+	pub, err := crypto.UnmarshalPublicKey(account)
+	if err != nil {
+		return types.ResponseQuery{Code: CodeTypeEncodingError, Log: "The user's public key is not correct."}
 	}
+	cj, _ := tca.state.GetCoins(pub)
+	qresp.Coins = cj.Coins
 	b, _ := json.Marshal(qresp)
 	resp := types.ResponseQuery{Code: CodeTypeOK, Value: b}
 	return resp
@@ -16,6 +16,12 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func (tu *testUtils) nonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 func TestQuerySuccessfully(t *testing.T) {
 	tu := testUtils{}
 	app := NewTCApplication()
@@ -34,6 +40,7 @@ func TestQuerySuccessfully(t *testing.T) {
 	assert.Equal(t, CodeTypeOK, dresp.Code)
 	qr := QueryRequest{}
 	qr.Data.Date = time.Now().UTC()
+	qr.Data.Nonce = tu.nonce()
 	qr.Data.From, err = pubk.Bytes()
 	assert.Nil(t, err)
 
@@ -74,6 +81,7 @@ func TestQueryFailSignature(t *testing.T) {
 	assert.Equal(t, CodeTypeOK, dresp.Code)
 	qr := QueryRequest{}
 	qr.Data.Date = time.Now().UTC()
+	qr.Data.Nonce = tu.nonce()
 	qr.Data.From, err = pubk.Bytes()
 	assert.Nil(t, err)
 
@@ -96,6 +104,9 @@ func TestQueryFailOnTime(t *testing.T) {
 	app := NewTCApplication()
 
 	confs.Conf.WaitingRequestTime = 1
+	skew := confs.Conf.QuerySkew
+	confs.Conf.QuerySkew = 0
+	defer func() { confs.Conf.QuerySkew = skew }()
 
 	privk, pubk, err := crypto.GenerateEd25519Key(rand.Reader)
 	assert.Nil(t, err)
@@ -111,6 +122,7 @@ func TestQueryFailOnTime(t *testing.T) {
 	assert.Equal(t, CodeTypeOK, dresp.Code)
 	qr := QueryRequest{}
 	qr.Data.Date = time.Now().UTC()
+	qr.Data.Nonce = tu.nonce()
 	qr.Data.From, err = pubk.Bytes()
 	assert.Nil(t, err)
 
@@ -148,6 +160,7 @@ func TestQueryFailNotWatcher(t *testing.T) {
 	qr := QueryRequest{}
 	data := QueryData{}
 	data.Date = time.Now().UTC()
+	data.Nonce = tu.nonce()
 	data.From, err = otherPrivk.GetPublic().Bytes()
 	assert.Nil(t, err)
 
@@ -193,6 +206,7 @@ func TestQuerySuccesfullWatcher(t *testing.T) {
 	qr := QueryRequest{}
 	data := QueryData{}
 	data.Date = time.Now().UTC()
+	data.Nonce = tu.nonce()
 	data.From, err = otherPrivk.GetPublic().Bytes()
 	assert.Nil(t, err)
 
@@ -220,7 +234,12 @@ func TestQuerySuccesfullWatcher(t *testing.T) {
 	req := types.RequestQuery{}
 	req.Data = b
 
+	// the watcher can not see the user before the grant
 	resp := app.Query(req)
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+
+	tu.grantView(t, app, fromPrivk, otherPrivk.GetPublic(), VIEW_BALANCE)
+	resp = tu.watcherQuery(t, app, "", otherPrivk, fromPrivk.GetPublic())
 	assert.Equal(t, CodeTypeOK, resp.Code)
 
 	qresp := QueryResponse{}
@@ -229,3 +248,107 @@ func TestQuerySuccesfullWatcher(t *testing.T) {
 
 	assert.Equal(t, expectedCoins, qresp.Coins)
 }
+
+func TestQueryTaxSuccessfully(t *testing.T) {
+	app := NewTCApplication()
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+
+	// submitting the tax
+	sh := shell.NewShell(confs.Conf.IpfsConnection)
+	taxPubkB, _ := taxPubk.Bytes()
+	tax := confs.Tax{Percentage: 10, PublicKeyHex: hex.EncodeToString(taxPubkB)}
+	b, _ := json.Marshal(tax)
+	taxHash, err := sh.BlockPut(b)
+	assert.Nil(t, err)
+	confs.Conf.IpfsTax = taxHash
+	err = confs.Conf.SubmitTax()
+	assert.Nil(t, err)
+
+	req := types.RequestQuery{}
+	req.Path = TAX_QUERY_PATH
+	resp := app.Query(req)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	tr := TaxResponse{}
+	err = json.Unmarshal(resp.Value, &tr)
+	assert.Nil(t, err)
+	assert.Equal(t, taxHash, tr.Hash)
+	assert.Equal(t, tax.Percentage, tr.Percentage)
+	assert.Equal(t, taxPubkB, tr.Receiver)
+}
+
+func (tu *testUtils) signQuery(t *testing.T, from crypto.PrivKey, date time.Time, nonce string) types.RequestQuery {
+	qr := QueryRequest{}
+	qr.Data.Date = date
+	qr.Data.Nonce = nonce
+	qr.Data.From, _ = from.GetPublic().Bytes()
+	b, _ := json.Marshal(qr.Data)
+	var err error
+	qr.Signature, err = from.Sign(b)
+	assert.Nil(t, err)
+	b, _ = json.Marshal(qr)
+	return types.RequestQuery{Data: b}
+}
+
+func TestQueryFailReplayedNonce(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	privk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+
+	req := tu.signQuery(t, privk, time.Now().UTC(), tu.nonce())
+	resp := app.Query(req)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	resp = app.Query(req)
+	assert.Equal(t, CodeTypeBadNonce, resp.Code)
+	serverTime, err := time.Parse(time.RFC3339Nano, resp.Info)
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().UTC(), serverTime, time.Second)
+
+	// another key can use the same nonce
+	otherPrivk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	qr := QueryRequest{}
+	json.Unmarshal(req.Data, &qr)
+	resp = app.Query(tu.signQuery(t, otherPrivk, time.Now().UTC(), qr.Data.Nonce))
+	assert.Equal(t, CodeTypeOK, resp.Code)
+}
+
+func TestQueryFailMissingNonce(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	privk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+
+	resp := app.Query(tu.signQuery(t, privk, time.Now().UTC(), ""))
+	assert.Equal(t, CodeTypeBadNonce, resp.Code)
+}
+
+func TestQueryClockSkew(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	privk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+
+	confs.Conf.WaitingRequestTime = 5
+	skew := confs.Conf.QuerySkew
+	confs.Conf.QuerySkew = 10
+	defer func() { confs.Conf.QuerySkew = skew }()
+
+	// the client's clock is ahead of the server's
+	resp := app.Query(tu.signQuery(t, privk, time.Now().UTC().Add(5*time.Second), tu.nonce()))
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	// the client's clock is behind the server's more than the waiting time
+	resp = app.Query(tu.signQuery(t, privk, time.Now().UTC().Add(-12*time.Second), tu.nonce()))
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	resp = app.Query(tu.signQuery(t, privk, time.Now().UTC().Add(15*time.Second), tu.nonce()))
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+	assert.NotEmpty(t, resp.Info)
+
+	resp = app.Query(tu.signQuery(t, privk, time.Now().UTC().Add(-20*time.Second), tu.nonce()))
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+}
@@ -0,0 +1,222 @@
+package ctrls
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/tendermint/abci/types"
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+const (
+	STANDING_ORDER_ID_MAX_LENGTH = 64
+	// the order keeps only the last failures
+	STANDING_ORDER_MAX_FAILURES = 10
+)
+
+func (tca *TCApplication) validateStandingOrder(dr DeliveryRequest) (uint32, error) {
+	so := dr.Data.StandingOrder
+	if so == nil {
+		return CodeTypeUnauthorized, errors.New("The standing order is missing.")
+	}
+	if len(so.ID) == 0 || len(so.ID) > STANDING_ORDER_ID_MAX_LENGTH {
+		return CodeTypeUnauthorized, errors.New("The standing order's ID should have from 1 to " + strconv.Itoa(STANDING_ORDER_ID_MAX_LENGTH) + " characters.")
+	}
+	if _, ok := tca.state.GetStandingOrder(so.ID); ok {
+		return CodeTypeUnauthorized, errors.New("The standing order " + so.ID + " already exists.")
+	}
+	if dr.Data.To == nil {
+		return CodeTypeUnauthorized, errors.New("The receiver's public key is empty.")
+	}
+	_, err := crypto.UnmarshalPublicKey(*dr.Data.To)
+	if err != nil {
+		return CodeTypeEncodingError, errors.New("The receiver's public key is not correct.")
+	}
+	if bytes.Equal(*dr.Data.To, dr.Data.From) {
+		return CodeTypeUnauthorized, errors.New("The standing order's receiver can not be the sender.")
+	}
+	if so.Interval <= 0 {
+		return CodeTypeUnauthorized, errors.New("The standing order's interval should be more than 0 blocks.")
+	}
+	if so.Start != 0 && so.Start <= tca.blockHeight {
+		return CodeTypeUnauthorized, errors.New("The standing order's start should be after the height " + strconv.FormatInt(tca.blockHeight, 10) + ".")
+	}
+	if so.End < tca.standingOrderStart(so) {
+		return CodeTypeUnauthorized, errors.New("The standing order's end should not be before its start.")
+	}
+	if dr.Data.TaxHash == nil || confs.Conf.IpfsTax != *dr.Data.TaxHash {
+		return CodeTypeUnauthorized, errors.New("The tax is not a validated UUID.")
+	}
+	return CodeTypeOK, nil
+}
+
+func (tca *TCApplication) standingOrderStart(so *StandingOrder) int64 {
+	if so.Start == 0 {
+		return tca.blockHeight + 1
+	}
+	return so.Start
+}
+
+func (tca *TCApplication) validateStandingOrderCancel(dr DeliveryRequest) (uint32, error) {
+	if dr.Data.StandingOrder == nil {
+		return CodeTypeUnauthorized, errors.New("The standing order is missing.")
+	}
+	so, ok := tca.state.GetStandingOrder(dr.Data.StandingOrder.ID)
+	if !ok {
+		return CodeTypeUnauthorized, errors.New("The standing order " + dr.Data.StandingOrder.ID + " does not exist.")
+	}
+	if !bytes.Equal(so.Owner, dr.Data.From) {
+		return CodeTypeUnauthorized, errors.New("Only the owner can cancel the standing order.")
+	}
+	if so.Status != STANDING_ORDER_ACTIVE {
+		return CodeTypeUnauthorized, errors.New("The standing order " + so.ID + " is already " + string(so.Status) + ".")
+	}
+	return CodeTypeOK, nil
+}
+
+// deliverStandingOrder keeps the schedule, the coins move on every payment.
+func (tca *TCApplication) deliverStandingOrder(dr DeliveryRequest) {
+	so := dr.Data.StandingOrder
+	tca.state.SetStandingOrder(StandingOrderJson{
+		ID:       so.ID,
+		Owner:    dr.Data.From,
+		Receiver: *dr.Data.To,
+		Coins:    dr.Data.Coins,
+		TaxHash:  *dr.Data.TaxHash,
+		Interval: so.Interval,
+		Next:     tca.standingOrderStart(so),
+		End:      so.End,
+		Status:   STANDING_ORDER_ACTIVE,
+	})
+}
+
+func (tca *TCApplication) deliverStandingOrderCancel(dr DeliveryRequest) {
+	so, _ := tca.state.GetStandingOrder(dr.Data.StandingOrder.ID)
+	so.Status = STANDING_ORDER_CANCELLED
+	tca.state.SetStandingOrder(so)
+}
+
+// executeStandingOrders pays the due orders at the end of the block, ordered by their height and their ID,
+// so every node executes them the same way. It returns the tags of the payments one after the other.
+func (tca *TCApplication) executeStandingOrders() []cmn.KVPair {
+	tags := []cmn.KVPair{}
+	index := 0
+	for _, id := range tca.state.DueStandingOrders(tca.blockHeight) {
+		so, _ := tca.state.GetStandingOrder(id)
+		entry, err := tca.payStandingOrder(so)
+		if err != nil {
+			so.Failures = append(so.Failures, StandingOrderFailure{Height: tca.blockHeight, Reason: err.Error()})
+			if len(so.Failures) > STANDING_ORDER_MAX_FAILURES {
+				so.Failures = so.Failures[len(so.Failures)-STANDING_ORDER_MAX_FAILURES:]
+			}
+		} else {
+			so.Payments++
+			sp := StandingOrderPayment{ID: so.ID, Height: tca.blockHeight, Index: index, Tags: deliveryTags(entry)}
+			tca.state.AddStandingOrderPayment(sp)
+			tags = append(tags, sp.Tags...)
+			index++
+		}
+		// a missed payment is not paid later, the next one is on the schedule
+		for so.Next <= tca.blockHeight {
+			so.Next += so.Interval
+		}
+		if so.Next > so.End {
+			so.Status = STANDING_ORDER_FINISHED
+		}
+		tca.state.SetStandingOrder(so)
+	}
+	return tags
+}
+
+func (tca *TCApplication) payStandingOrder(so StandingOrderJson) (HistoryEntry, error) {
+	if tca.state.IsFrozen(so.Owner) || tca.state.IsFrozen(so.Receiver) {
+		return HistoryEntry{}, errors.New("The owner's or the receiver's account is frozen.")
+	}
+	// the payments are taxed with the tax of the order, a changed tax fails them until the owner cancels the order
+	if so.TaxHash != confs.Conf.IpfsTax {
+		return HistoryEntry{}, errors.New("The tax changed after the standing order was created.")
+	}
+	err := tca.checkLimits(so.Owner, so.Coins)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	// the attestations can expire or be revoked after the order is created
+	err = tca.checkKyc(so.Owner, &so.Receiver, so.Coins)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	owner, _ := crypto.UnmarshalPublicKey(so.Owner)
+	ownerCj, _ := tca.state.GetCoins(owner)
+	newOwnerCoins := ownerCj.Coins - so.Coins
+	if newOwnerCoins < 0 {
+		return HistoryEntry{}, errors.New("The owner does not have enough money for the payment.")
+	}
+	taxReceiverCoins := tca.taxReceiverCoins()
+	tca.state.SetCoins(owner, newOwnerCoins)
+
+	taxCoins := tca.taxCoins(so.Coins)
+	receiver, _ := crypto.UnmarshalPublicKey(so.Receiver)
+	receiverCj, _ := tca.state.GetCoins(receiver)
+	tca.state.SetCoins(receiver, receiverCj.Coins+so.Coins-taxCoins)
+
+	taxCj, _ := tca.state.GetCoins(confs.Conf.TaxReceiver)
+	tca.state.SetCoins(confs.Conf.TaxReceiver, taxCj.Coins+taxCoins)
+	tca.recordSent(so.Owner, so.Coins)
+
+	to := so.Receiver
+	entry := HistoryEntry{
+		Height:        tca.blockHeight,
+		Time:          tca.blockTime,
+		Action:        STANDING_ORDER_PAYMENT_ACTION,
+		From:          so.Owner,
+		To:            &to,
+		Coins:         so.Coins,
+		StandingOrder: &StandingOrder{ID: so.ID},
+	}
+	tca.setPayment(&entry, so.Owner, so.Receiver, true)
+	added := map[string]bool{}
+	for _, account := range [][]byte{so.Owner, so.Receiver, entry.TaxReceiver} {
+		if account == nil || added[string(account)] {
+			continue
+		}
+		added[string(account)] = true
+		tca.state.AddHistory(account, tca.blockTxs, entry)
+	}
+	tca.reconcileTax(entry, func(account []byte) float64 {
+		return paymentCoins(entry, account, false)
+	}, taxReceiverCoins)
+	tca.blockTxs++
+	return entry, nil
+}
+
+// queryStandingOrderPayments returns the payments of the standing orders in the range of heights,
+// their tags are public like the tags of the transactions.
+func (tca *TCApplication) queryStandingOrderPayments(data []byte) types.ResponseQuery {
+	spr := StandingOrderPaymentsRequest{}
+	err := json.Unmarshal(data, &spr)
+	if err != nil {
+		return types.ResponseQuery{Code: CodeTypeEncodingError, Log: "The request of the payments is not json."}
+	}
+	to := spr.ToHeight
+	if to == 0 {
+		to = tca.blockHeight
+	}
+	if spr.FromHeight < 0 || to < spr.FromHeight {
+		return types.ResponseQuery{Code: CodeTypeEncodingError, Log: "The range of the heights is not correct."}
+	}
+	b, _ := json.Marshal(StandingOrderPaymentsResponse{Payments: tca.state.GetStandingOrderPayments(spr.FromHeight, to)})
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
+
+func (tca *TCApplication) queryStandingOrder(id string) types.ResponseQuery {
+	so, ok := tca.state.GetStandingOrder(id)
+	if !ok {
+		return types.ResponseQuery{Code: CodeTypeUnauthorized, Log: "The standing order " + id + " does not exist."}
+	}
+	b, _ := json.Marshal(so)
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
@@ -0,0 +1,139 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+)
+
+func (tu *testUtils) block(app *TCApplication, height int64) {
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: height}})
+	app.EndBlock(types.RequestEndBlock{Height: height})
+}
+
+func TestStandingOrderPaysUntilTheEnd(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	ownerPrivk, ownerPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, ownerPrivk, 25)
+
+	code := tu.deliver(t, app, ownerPrivk, DeliveryData{Action: STANDING_ORDER_ACTION, To: tu.to(receiverPubk), Coins: 10, TaxHash: &taxHash, StandingOrder: &StandingOrder{ID: "rent", Interval: 0, End: 6}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	code = tu.deliver(t, app, ownerPrivk, DeliveryData{Action: STANDING_ORDER_ACTION, To: tu.to(receiverPubk), Coins: 10, TaxHash: &taxHash, StandingOrder: &StandingOrder{ID: "rent", Interval: 2, End: 6}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	code = tu.deliver(t, app, ownerPrivk, DeliveryData{Action: STANDING_ORDER_ACTION, To: tu.to(receiverPubk), Coins: 10, TaxHash: &taxHash, StandingOrder: &StandingOrder{ID: "rent", Interval: 2, End: 6}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	app.EndBlock(types.RequestEndBlock{Height: 1})
+
+	// the payments are on the heights 2 and 4, the third fails for the owner's coins
+	for height := int64(2); height <= 7; height++ {
+		tu.block(app, height)
+	}
+	cj, _ := app.state.GetCoins(ownerPubk)
+	assert.Equal(t, float64(5), cj.Coins)
+	cj, _ = app.state.GetCoins(receiverPubk)
+	assert.Equal(t, float64(18), cj.Coins)
+
+	resp := app.Query(types.RequestQuery{Path: STANDING_ORDER_QUERY_PATH, Data: []byte("rent")})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	so := StandingOrderJson{}
+	json.Unmarshal(resp.Value, &so)
+	assert.Equal(t, STANDING_ORDER_FINISHED, so.Status)
+	assert.Equal(t, 2, so.Payments)
+	assert.Equal(t, 1, len(so.Failures))
+	assert.Equal(t, int64(6), so.Failures[0].Height)
+
+	// the order's creation and its payments
+	receiverPubkB, _ := receiverPubk.Bytes()
+	entries := app.state.GetHistory(receiverPubkB)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, STANDING_ORDER_ACTION, entries[0].Action)
+	assert.Equal(t, STANDING_ORDER_PAYMENT_ACTION, entries[2].Action)
+	assert.Equal(t, int64(4), entries[2].Height)
+	assert.Equal(t, "rent", entries[2].StandingOrder.ID)
+}
+
+func TestStandingOrderCancelByTheOwner(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	ownerPrivk, ownerPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	receiverPrivk, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, ownerPrivk, 100)
+
+	code := tu.deliver(t, app, ownerPrivk, DeliveryData{Action: STANDING_ORDER_ACTION, To: tu.to(receiverPubk), Coins: 10, TaxHash: &taxHash, StandingOrder: &StandingOrder{ID: "salary", Interval: 1, Start: 3, End: 100}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	app.EndBlock(types.RequestEndBlock{Height: 1})
+	tu.block(app, 2)
+	tu.block(app, 3)
+
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 4}})
+	code = tu.deliver(t, app, receiverPrivk, DeliveryData{Action: STANDING_ORDER_CANCEL_ACTION, StandingOrder: &StandingOrder{ID: "salary"}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	code = tu.deliver(t, app, ownerPrivk, DeliveryData{Action: STANDING_ORDER_CANCEL_ACTION, StandingOrder: &StandingOrder{ID: "salary"}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	code = tu.deliver(t, app, ownerPrivk, DeliveryData{Action: STANDING_ORDER_CANCEL_ACTION, StandingOrder: &StandingOrder{ID: "salary"}}).Code
+	assert.Equal(t, CodeTypeUnauthorized, code)
+	app.EndBlock(types.RequestEndBlock{Height: 4})
+	tu.block(app, 5)
+
+	cj, _ := app.state.GetCoins(ownerPubk)
+	assert.Equal(t, float64(90), cj.Coins)
+	so, _ := app.state.GetStandingOrder("salary")
+	assert.Equal(t, STANDING_ORDER_CANCELLED, so.Status)
+	assert.Equal(t, 1, so.Payments)
+	assert.Equal(t, 0, len(app.state.DueStandingOrders(100)))
+}
+
+func TestStandingOrderFailsWithAChangedTax(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	ownerPrivk, ownerPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, ownerPrivk, 100)
+
+	code := tu.deliver(t, app, ownerPrivk, DeliveryData{Action: STANDING_ORDER_ACTION, To: tu.to(receiverPubk), Coins: 10, TaxHash: &taxHash, StandingOrder: &StandingOrder{ID: "rent", Interval: 1, Start: 2, End: 3}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	app.EndBlock(types.RequestEndBlock{Height: 1})
+	tu.block(app, 2)
+
+	// the payments are not taxed with the new tax
+	_, newTaxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	tu.submitTax(t, newTaxPubk)
+	tu.block(app, 3)
+
+	cj, _ := app.state.GetCoins(ownerPubk)
+	assert.Equal(t, float64(90), cj.Coins)
+	cj, _ = app.state.GetCoins(receiverPubk)
+	assert.Equal(t, float64(9), cj.Coins)
+	so, _ := app.state.GetStandingOrder("rent")
+	assert.Equal(t, 1, so.Payments)
+	assert.Equal(t, 1, len(so.Failures))
+	assert.Equal(t, "The tax changed after the standing order was created.", so.Failures[0].Reason)
+}
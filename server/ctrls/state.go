@@ -1,16 +1,34 @@
 package ctrls
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 
 	crypto "github.com/libp2p/go-libp2p-crypto"
+	cmn "github.com/tendermint/tmlibs/common"
 	dbm "github.com/tendermint/tmlibs/db"
 )
 
 var (
-	stateKey = []byte("stateKey")
-	coinKey  = []byte("coinKey:")
+	stateKey            = []byte("stateKey")
+	coinKey             = []byte("coinKey:")
+	confidentialKey     = []byte("confidentialKey:")
+	viewGrantKey        = []byte("viewGrantKey:")
+	historyKey          = []byte("historyKey:")
+	auditAnchorKey      = []byte("auditAnchorKey:")
+	escrowKey           = []byte("escrowKey:")
+	htlcKey             = []byte("htlcKey:")
+	standingOrderKey    = []byte("standingOrderKey:")
+	standingOrderDueKey = []byte("standingOrderDueKey:")
+	standingOrderPayKey = []byte("standingOrderPayKey:")
+	freezeKey           = []byte("freezeKey:")
+	seizureKey          = []byte("seizureKey:")
+	tierKey             = []byte("tierKey:")
+	accountTierKey      = []byte("accountTierKey:")
+	sentKey             = []byte("sentKey:")
+	attestationKey      = []byte("attestationKey:")
+	taxBlockKey         = []byte("taxBlockKey:")
 )
 
 func prefixCoinKey(pubk crypto.PubKey) ([]byte, error) {
@@ -54,6 +72,109 @@ func (s *State) SetCoins(pubk crypto.PubKey, coins float64) error {
 	return nil
 }
 
+// prefixValues returns the values of the keys with the prefix, ordered by the keys.
+func (s *State) prefixValues(prefix []byte) [][]byte {
+	values := [][]byte{}
+	it := dbm.IteratePrefix(s.db, prefix)
+	defer it.Close()
+	for ; it.Valid(); it.Next() {
+		values = append(values, it.Value())
+	}
+	return values
+}
+
+// ConfidentialJson is the confidential balance of an account,
+// the notes open the balance for the owner.
+type ConfidentialJson struct {
+	Balance []byte
+	Notes   [][]byte
+}
+
+func (s *State) GetConfidential(pub []byte) ConfidentialJson {
+	cj := ConfidentialJson{}
+	b := s.db.Get(append(confidentialKey, pub...))
+	json.Unmarshal(b, &cj)
+	return cj
+}
+
+func (s *State) SetConfidential(pub []byte, cj ConfidentialJson) {
+	b, _ := json.Marshal(cj)
+	s.db.Set(append(confidentialKey, pub...), b)
+}
+
+func prefixAccountKey(prefix []byte, account []byte) []byte {
+	key := append([]byte{}, prefix...)
+	key = append(key, account...)
+	return append(key, ':')
+}
+
+func (s *State) GetViewGrant(account []byte, watcher []byte) (ViewGrant, bool) {
+	vg := ViewGrant{}
+	b := s.db.Get(append(prefixAccountKey(viewGrantKey, account), watcher...))
+	if b == nil {
+		return vg, false
+	}
+	json.Unmarshal(b, &vg)
+	return vg, true
+}
+
+func (s *State) SetViewGrant(account []byte, vg ViewGrant) {
+	b, _ := json.Marshal(vg)
+	s.db.Set(append(prefixAccountKey(viewGrantKey, account), vg.Watcher...), b)
+}
+
+func (s *State) DeleteViewGrant(account []byte, watcher []byte) {
+	s.db.Delete(append(prefixAccountKey(viewGrantKey, account), watcher...))
+}
+
+func (s *State) GetViewGrants(account []byte) []ViewGrant {
+	grants := []ViewGrant{}
+	for _, b := range s.prefixValues(prefixAccountKey(viewGrantKey, account)) {
+		vg := ViewGrant{}
+		json.Unmarshal(b, &vg)
+		grants = append(grants, vg)
+	}
+	return grants
+}
+
+// AddHistory keeps the entry for the account, ordered by the height and the position in the block.
+func (s *State) AddHistory(account []byte, index int, entry HistoryEntry) {
+	key := prefixAccountKey(historyKey, account)
+	position := make([]byte, 12)
+	binary.BigEndian.PutUint64(position, uint64(entry.Height))
+	binary.BigEndian.PutUint32(position[8:], uint32(index))
+	b, _ := json.Marshal(entry)
+	s.db.Set(append(key, position...), b)
+}
+
+func (s *State) GetHistory(account []byte) []HistoryEntry {
+	entries := []HistoryEntry{}
+	for _, b := range s.prefixValues(prefixAccountKey(historyKey, account)) {
+		entry := HistoryEntry{}
+		json.Unmarshal(b, &entry)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func (s *State) AddAuditAnchor(anchor AuditAnchor) {
+	index := make([]byte, 8)
+	binary.BigEndian.PutUint64(index, uint64(anchor.Index))
+	key := append(append([]byte{}, auditAnchorKey...), index...)
+	b, _ := json.Marshal(anchor)
+	s.db.Set(append(key, anchor.From...), b)
+}
+
+func (s *State) GetAuditAnchors() []AuditAnchor {
+	anchors := []AuditAnchor{}
+	for _, b := range s.prefixValues(auditAnchorKey) {
+		anchor := AuditAnchor{}
+		json.Unmarshal(b, &anchor)
+		anchors = append(anchors, anchor)
+	}
+	return anchors
+}
+
 func loadState(db dbm.DB) State {
 	stateBytes := db.Get(stateKey)
 	var state State
@@ -74,3 +195,379 @@ func saveState(state State) {
 	}
 	state.db.Set(stateKey, stateBytes)
 }
+
+type EscrowStatus string
+
+const (
+	ESCROW_OPEN     = EscrowStatus("open")
+	ESCROW_RELEASED = EscrowStatus("released")
+	ESCROW_REFUNDED = EscrowStatus("refunded")
+)
+
+// EscrowJson is the escrow's locked coins, it is kept after the release or the refund.
+type EscrowJson struct {
+	ID      string
+	Payer   []byte  // public key
+	Payee   []byte  // public key
+	Arbiter *[]byte `json:",omitempty"` // public key
+	Coins   float64
+	TaxHash string
+	Timeout int64 // height
+	Height  int64 // the height of the open
+	Status  EscrowStatus
+}
+
+func (s *State) GetEscrow(id string) (EscrowJson, bool) {
+	ej := EscrowJson{}
+	b := s.db.Get(append(escrowKey, []byte(id)...))
+	if b == nil {
+		return ej, false
+	}
+	json.Unmarshal(b, &ej)
+	return ej, true
+}
+
+func (s *State) SetEscrow(ej EscrowJson) {
+	b, _ := json.Marshal(ej)
+	s.db.Set(append(escrowKey, []byte(ej.ID)...), b)
+}
+
+type HtlcStatus string
+
+const (
+	HTLC_LOCKED   = HtlcStatus("locked")
+	HTLC_CLAIMED  = HtlcStatus("claimed")
+	HTLC_REFUNDED = HtlcStatus("refunded")
+)
+
+// HtlcJson is the locked coins of a hashlock, the preimage is kept after the claim for the other chain.
+type HtlcJson struct {
+	Hashlock []byte
+	Sender   []byte // public key
+	Receiver []byte // public key
+	Coins    float64
+	TaxHash  string
+	Timeout  int64 // height
+	Height   int64 // the height of the lock
+	Status   HtlcStatus
+	Preimage []byte `json:",omitempty"`
+}
+
+func (s *State) GetHtlc(hashlock []byte) (HtlcJson, bool) {
+	hj := HtlcJson{}
+	b := s.db.Get(append(htlcKey, hashlock...))
+	if b == nil {
+		return hj, false
+	}
+	json.Unmarshal(b, &hj)
+	return hj, true
+}
+
+func (s *State) SetHtlc(hj HtlcJson) {
+	b, _ := json.Marshal(hj)
+	s.db.Set(append(htlcKey, hj.Hashlock...), b)
+}
+
+type StandingOrderStatus string
+
+const (
+	STANDING_ORDER_ACTIVE    = StandingOrderStatus("active")
+	STANDING_ORDER_CANCELLED = StandingOrderStatus("cancelled")
+	STANDING_ORDER_FINISHED  = StandingOrderStatus("finished")
+)
+
+type StandingOrderFailure struct {
+	Height int64
+	Reason string
+}
+
+// StandingOrderJson is the schedule of a standing order, with the payments and the recent failures.
+type StandingOrderJson struct {
+	ID       string
+	Owner    []byte // public key
+	Receiver []byte // public key
+	Coins    float64
+	TaxHash  string
+	Interval int64 // blocks
+	Next     int64 // the height of the next payment
+	End      int64 // height
+	Status   StandingOrderStatus
+	Payments int
+	Failures []StandingOrderFailure
+}
+
+func dueStandingOrderKey(next int64, id string) []byte {
+	height := make([]byte, 8)
+	binary.BigEndian.PutUint64(height, uint64(next))
+	key := append(append([]byte{}, standingOrderDueKey...), height...)
+	return append(key, []byte(id)...)
+}
+
+func (s *State) GetStandingOrder(id string) (StandingOrderJson, bool) {
+	so := StandingOrderJson{}
+	b := s.db.Get(append(standingOrderKey, []byte(id)...))
+	if b == nil {
+		return so, false
+	}
+	json.Unmarshal(b, &so)
+	return so, true
+}
+
+// SetStandingOrder keeps the order, and the active order in the index of the due payments.
+func (s *State) SetStandingOrder(so StandingOrderJson) {
+	if old, ok := s.GetStandingOrder(so.ID); ok {
+		s.db.Delete(dueStandingOrderKey(old.Next, old.ID))
+	}
+	b, _ := json.Marshal(so)
+	s.db.Set(append(standingOrderKey, []byte(so.ID)...), b)
+	if so.Status == STANDING_ORDER_ACTIVE {
+		s.db.Set(dueStandingOrderKey(so.Next, so.ID), []byte(so.ID))
+	}
+}
+
+// DueStandingOrders returns the IDs of the active orders with a payment until the height,
+// ordered by the height of the payment and the ID.
+func (s *State) DueStandingOrders(height int64) []string {
+	ids := []string{}
+	it := dbm.IteratePrefix(s.db, standingOrderDueKey)
+	defer it.Close()
+	for ; it.Valid(); it.Next() {
+		next := int64(binary.BigEndian.Uint64(it.Key()[len(standingOrderDueKey):]))
+		if next > height {
+			break
+		}
+		ids = append(ids, string(it.Value()))
+	}
+	return ids
+}
+
+// StandingOrderPayment is a payment of a standing order at the end of the block with the tags of a transaction,
+// the payments are not transactions so the consumers of the tx_search read them from the state by their height.
+type StandingOrderPayment struct {
+	ID     string
+	Height int64
+	Index  int // the order of the payment at the end of the block
+	Tags   []cmn.KVPair
+}
+
+func (s *State) AddStandingOrderPayment(sp StandingOrderPayment) {
+	key := make([]byte, 12)
+	binary.BigEndian.PutUint64(key, uint64(sp.Height))
+	binary.BigEndian.PutUint32(key[8:], uint32(sp.Index))
+	b, _ := json.Marshal(sp)
+	s.db.Set(append(append([]byte{}, standingOrderPayKey...), key...), b)
+}
+
+// GetStandingOrderPayments returns the payments from the height until the height, both included, in their order.
+func (s *State) GetStandingOrderPayments(from, to int64) []StandingOrderPayment {
+	payments := []StandingOrderPayment{}
+	it := dbm.IteratePrefix(s.db, standingOrderPayKey)
+	defer it.Close()
+	for ; it.Valid(); it.Next() {
+		height := int64(binary.BigEndian.Uint64(it.Key()[len(standingOrderPayKey):]))
+		if height < from {
+			continue
+		}
+		if height > to {
+			break
+		}
+		sp := StandingOrderPayment{}
+		json.Unmarshal(it.Value(), &sp)
+		payments = append(payments, sp)
+	}
+	return payments
+}
+
+// FreezeJson is the freeze of an account, the account can not send or receive until the unfreeze.
+type FreezeJson struct {
+	Account   []byte // public key
+	Authority []byte // public key
+	Reason    string
+	Height    int64
+}
+
+func (s *State) GetFreeze(account []byte) (FreezeJson, bool) {
+	fj := FreezeJson{}
+	b := s.db.Get(prefixAccountKey(freezeKey, account))
+	if b == nil {
+		return fj, false
+	}
+	json.Unmarshal(b, &fj)
+	return fj, true
+}
+
+func (s *State) SetFreeze(fj FreezeJson) {
+	b, _ := json.Marshal(fj)
+	s.db.Set(prefixAccountKey(freezeKey, fj.Account), b)
+}
+
+func (s *State) DeleteFreeze(account []byte) {
+	s.db.Delete(prefixAccountKey(freezeKey, account))
+}
+
+func (s *State) IsFrozen(account []byte) bool {
+	return s.db.Has(prefixAccountKey(freezeKey, account))
+}
+
+// SeizureJson is a court order's seizure of the coins of an account.
+type SeizureJson struct {
+	Account   []byte // public key
+	To        []byte // public key
+	Coins     float64
+	Authority []byte // public key
+	Reason    string
+	Height    int64
+}
+
+// AddSeizure keeps the seizure for the account, ordered by the height and the position in the block.
+func (s *State) AddSeizure(index int, sj SeizureJson) {
+	key := prefixAccountKey(seizureKey, sj.Account)
+	position := make([]byte, 12)
+	binary.BigEndian.PutUint64(position, uint64(sj.Height))
+	binary.BigEndian.PutUint32(position[8:], uint32(index))
+	b, _ := json.Marshal(sj)
+	s.db.Set(append(key, position...), b)
+}
+
+func (s *State) GetSeizures(account []byte) []SeizureJson {
+	seizures := []SeizureJson{}
+	for _, b := range s.prefixValues(prefixAccountKey(seizureKey, account)) {
+		sj := SeizureJson{}
+		json.Unmarshal(b, &sj)
+		seizures = append(seizures, sj)
+	}
+	return seizures
+}
+
+// The tier of the accounts without an assignment.
+const DEFAULT_TIER = "default"
+
+type TierJson struct {
+	Name      string
+	MaxSend   float64
+	MaxWindow float64
+	Window    int64 // blocks
+}
+
+// GetTier returns the tier of the name, a tier that is not set has no limits.
+func (s *State) GetTier(name string) TierJson {
+	tj := TierJson{Name: name}
+	b := s.db.Get(append(tierKey, []byte(name)...))
+	json.Unmarshal(b, &tj)
+	return tj
+}
+
+func (s *State) SetTier(tj TierJson) {
+	b, _ := json.Marshal(tj)
+	s.db.Set(append(tierKey, []byte(tj.Name)...), b)
+}
+
+func (s *State) GetAccountTier(account []byte) string {
+	b := s.db.Get(prefixAccountKey(accountTierKey, account))
+	if b == nil {
+		return DEFAULT_TIER
+	}
+	return string(b)
+}
+
+func (s *State) SetAccountTier(account []byte, name string) {
+	if name == DEFAULT_TIER {
+		s.db.Delete(prefixAccountKey(accountTierKey, account))
+		return
+	}
+	s.db.Set(prefixAccountKey(accountTierKey, account), []byte(name))
+}
+
+type SentEntry struct {
+	Height int64
+	Coins  float64
+}
+
+// GetSent returns the account's sends after the height.
+func (s *State) GetSent(account []byte, after int64) []SentEntry {
+	entries := []SentEntry{}
+	all := []SentEntry{}
+	json.Unmarshal(s.db.Get(prefixAccountKey(sentKey, account)), &all)
+	for _, e := range all {
+		if e.Height > after {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// AddSent keeps the send of the account, and drops the sends until the height.
+func (s *State) AddSent(account []byte, after int64, entry SentEntry) {
+	entries := append(s.GetSent(account, after), entry)
+	b, _ := json.Marshal(entries)
+	s.db.Set(prefixAccountKey(sentKey, account), b)
+}
+
+// AttestationJson is the account's attestation, a new attestation replaces the previous one.
+type AttestationJson struct {
+	Attestation
+	Height int64
+}
+
+func (s *State) GetAttestation(account []byte) (AttestationJson, bool) {
+	aj := AttestationJson{}
+	b := s.db.Get(prefixAccountKey(attestationKey, account))
+	if b == nil {
+		return aj, false
+	}
+	json.Unmarshal(b, &aj)
+	return aj, true
+}
+
+func (s *State) SetAttestation(aj AttestationJson) {
+	b, _ := json.Marshal(aj)
+	s.db.Set(prefixAccountKey(attestationKey, aj.Account), b)
+}
+
+func (s *State) DeleteAttestation(account []byte) {
+	s.db.Delete(prefixAccountKey(attestationKey, account))
+}
+
+type TaxRateJson struct {
+	TaxHash    string
+	Percentage int
+	Transfers  int
+	Volume     float64 // the coins of the taxed transfers
+	Tax        float64 // the tax that the tax receiver received
+
+	ConfidentialTransfers int
+	ConfidentialTax       []byte `json:",omitempty"` // the sum of the commitments of the confidential sends' tax
+}
+
+// TaxBlockJson is the tax of a block by the version of the tax, and the tax receiver's balance before and after the block.
+// The transactions change the balance by the tax and the coins of the tax receiver's own transactions,
+// the measured change is what the node saw the transactions change.
+type TaxBlockJson struct {
+	Height        int64
+	Rates         []TaxRateJson
+	Own           float64
+	Measured      float64
+	BalanceBefore float64
+	BalanceAfter  float64
+}
+
+func (s *State) SetTaxBlock(tb TaxBlockJson) {
+	height := make([]byte, 8)
+	binary.BigEndian.PutUint64(height, uint64(tb.Height))
+	b, _ := json.Marshal(tb)
+	s.db.Set(append(taxBlockKey, height...), b)
+}
+
+// GetTaxBlocks returns the blocks with tax from the height until the height, both included.
+func (s *State) GetTaxBlocks(from, to int64) []TaxBlockJson {
+	blocks := []TaxBlockJson{}
+	for _, b := range s.prefixValues(taxBlockKey) {
+		tb := TaxBlockJson{}
+		json.Unmarshal(b, &tb)
+		if tb.Height < from || tb.Height > to {
+			continue
+		}
+		blocks = append(blocks, tb)
+	}
+	return blocks
+}
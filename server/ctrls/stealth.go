@@ -0,0 +1,24 @@
+package ctrls
+
+import (
+	"errors"
+)
+
+// validateStealth checks the send to a one-time key.
+// The receiver's client derives the one-time key's scalar, which has no Ed25519 seed,
+// and signs with Schnorr signatures that Ed25519 verifies, so the one-time key spends as any other key.
+func (tca *TCApplication) validateStealth(dr DeliveryRequest) (uint32, error) {
+	ephemeral := dr.Data.Stealth.Ephemeral
+	p := suite.Point()
+	if len(ephemeral) != p.MarshalSize() || p.UnmarshalBinary(ephemeral) != nil {
+		return CodeTypeEncodingError, errors.New("The stealth's ephemeral key is not a point of the curve.")
+	}
+	if so, ok := p.(interface{ HasSmallOrder() bool }); ok && so.HasSmallOrder() {
+		return CodeTypeUnauthorized, errors.New("The stealth's ephemeral key has a small order.")
+	}
+	_, err := publicKeyPoint(*dr.Data.To)
+	if err != nil {
+		return CodeTypeEncodingError, errors.New("The one-time key of the receiver is not correct. " + err.Error())
+	}
+	return CodeTypeOK, nil
+}
@@ -0,0 +1,117 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/json"
+	"testing"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/stretchr/testify/assert"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+)
+
+// privateKeyScalar returns the scalar of an Ed25519 private key,
+// the key is the protobuf header and then the seed with the public key.
+func (tu *testUtils) privateKeyScalar(t *testing.T, privk crypto.PrivKey) kyber.Scalar {
+	b, err := privk.Bytes()
+	assert.Nil(t, err)
+	h := sha512.Sum512(b[4:36])
+	h[0] &= 248
+	h[31] &= 127
+	h[31] |= 64
+	return suite.Scalar().SetBytes(h[:32])
+}
+
+// stealthKey derives the one-time key of the receiver's scan and spend keys,
+// it returns the scalar of the one-time key as the receiver derives it.
+func (tu *testUtils) stealthKey(t *testing.T, scan kyber.Scalar, spend kyber.Scalar) ([]byte, []byte, kyber.Scalar) {
+	r := suite.Scalar().Pick(suite.RandomStream())
+	ephemeral, _ := suite.Point().Mul(r, nil).MarshalBinary()
+	shared, _ := suite.Point().Mul(r, suite.Point().Mul(scan, nil)).MarshalBinary()
+	h := sha512.Sum512(append([]byte("theftcoin-stealth"), shared...))
+	x := suite.Scalar().Add(suite.Scalar().SetBytes(h[:]), spend)
+	oneTime, _ := suite.Point().Mul(x, nil).MarshalBinary()
+	return append(append([]byte{}, ed25519PublicKeyHeader...), oneTime...), ephemeral, x
+}
+
+func TestStealthSendAndSpendSuccessfully(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+
+	fromPrivk, fromPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	receiverPrivk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, otherPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+
+	fromPubkB, _ := fromPubk.Bytes()
+	confs.Conf.IpfsInflators = tu.addInflator(t, fromPubkB)
+	confs.Conf.SubmitInflators()
+	dr := tu.inflatorCoins(t, fromPrivk, ADD_ACTION, 100)
+	b, _ := json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	scan := suite.Scalar().Pick(suite.RandomStream())
+	oneTime, ephemeral, x := tu.stealthKey(t, scan, tu.privateKeyScalar(t, receiverPrivk))
+	oneTimePubk, err := crypto.UnmarshalPublicKey(oneTime)
+	assert.Nil(t, err)
+
+	dr = tu.sendCoins(t, fromPrivk, oneTimePubk, taxHash, 50)
+	dr.Data.Stealth = &Stealth{Ephemeral: ephemeral}
+	tu.signDelivery(t, &dr, fromPrivk)
+	b, _ = json.Marshal(dr)
+	resp = app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	cj, _ := app.state.GetCoins(oneTimePubk)
+	assert.Equal(t, float64(45), cj.Coins)
+
+	// the one-time key spends with a Schnorr signature of its scalar
+	dr = tu.sendCoins(t, fromPrivk, otherPubk, taxHash, 20)
+	dr.Data.From = oneTime
+	b, _ = json.Marshal(dr.Data)
+	dr.Signature, err = schnorr.Sign(suite, x, b)
+	assert.Nil(t, err)
+	b, _ = json.Marshal(dr)
+	resp = app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	cj, _ = app.state.GetCoins(oneTimePubk)
+	assert.Equal(t, float64(25), cj.Coins)
+	cj, _ = app.state.GetCoins(otherPubk)
+	assert.Equal(t, float64(18), cj.Coins)
+}
+
+func TestStealthSendFailWrongEphemeral(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+
+	fromPrivk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, toPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+
+	dr := tu.sendCoins(t, fromPrivk, toPubk, taxHash, 50)
+	dr.Data.Stealth = &Stealth{Ephemeral: []byte("ephemeral")}
+	tu.signDelivery(t, &dr, fromPrivk)
+	b, _ := json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeEncodingError, resp.Code)
+
+	// the shared secret of an ephemeral key of a small order is known to anyone
+	identity, _ := suite.Point().Null().MarshalBinary()
+	dr.Data.Stealth = &Stealth{Ephemeral: identity}
+	tu.signDelivery(t, &dr, fromPrivk)
+	b, _ = json.Marshal(dr)
+	resp = app.DeliverTx(b)
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+}
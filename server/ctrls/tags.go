@@ -0,0 +1,49 @@
+package ctrls
+
+import (
+	"encoding/hex"
+	"strconv"
+
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+// The tags of the delivered transactions, for the tx_search of tendermint.
+// The accounts are the public keys in hex, the from is the account that pays the coins and the signer signed the transaction.
+const (
+	TAG_ACTION       = "action"
+	TAG_SIGNER       = "signer"
+	TAG_FROM         = "from"
+	TAG_TO           = "to"
+	TAG_AMOUNT       = "amount"
+	TAG_TAX          = "tax"
+	TAG_TAX_RECEIVER = "tax_receiver"
+)
+
+// deliveryTags returns the tags of the entry, the payer of the seizure, the release and the claim is not the signer,
+// and the receiver of the locked coins is the payee of the release and the claim.
+func deliveryTags(entry HistoryEntry) []cmn.KVPair {
+	from := entry.From
+	if entry.Payer != nil {
+		from = entry.Payer
+	}
+	tags := []cmn.KVPair{
+		{Key: []byte(TAG_ACTION), Value: []byte(entry.Action)},
+		{Key: []byte(TAG_SIGNER), Value: []byte(hex.EncodeToString(entry.From))},
+		{Key: []byte(TAG_FROM), Value: []byte(hex.EncodeToString(from))},
+	}
+	if entry.To != nil {
+		tags = append(tags, cmn.KVPair{Key: []byte(TAG_TO), Value: []byte(hex.EncodeToString(*entry.To))})
+	} else if entry.Payee != nil {
+		tags = append(tags, cmn.KVPair{Key: []byte(TAG_TO), Value: []byte(hex.EncodeToString(entry.Payee))})
+	}
+	if entry.Coins > 0 {
+		tags = append(tags, cmn.KVPair{Key: []byte(TAG_AMOUNT), Value: []byte(strconv.FormatFloat(entry.Coins, 'f', -1, 64))})
+	}
+	if entry.Tax > 0 {
+		tags = append(tags, cmn.KVPair{Key: []byte(TAG_TAX), Value: []byte(strconv.FormatFloat(entry.Tax, 'f', -1, 64))})
+	}
+	if entry.TaxReceiver != nil {
+		tags = append(tags, cmn.KVPair{Key: []byte(TAG_TAX_RECEIVER), Value: []byte(hex.EncodeToString(entry.TaxReceiver))})
+	}
+	return tags
+}
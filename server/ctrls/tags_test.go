@@ -0,0 +1,132 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+)
+
+func (tu *testUtils) tag(resp types.ResponseDeliverTx, key string) string {
+	for _, tag := range resp.Tags {
+		if string(tag.Key) == key {
+			return string(tag.Value)
+		}
+	}
+	return ""
+}
+
+func TestTagsOfTheSend(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	senderPrivk, senderPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, senderPrivk, 100)
+
+	dr := tu.sendCoins(t, senderPrivk, receiverPubk, taxHash, 50)
+	b, _ := json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	assert.Equal(t, "send", tu.tag(resp, TAG_ACTION))
+	assert.Equal(t, hex.EncodeToString(tu.pubBytes(senderPubk)), tu.tag(resp, TAG_FROM))
+	assert.Equal(t, hex.EncodeToString(tu.pubBytes(receiverPubk)), tu.tag(resp, TAG_TO))
+	assert.Equal(t, "50", tu.tag(resp, TAG_AMOUNT))
+	assert.Equal(t, "5", tu.tag(resp, TAG_TAX))
+	assert.Equal(t, hex.EncodeToString(tu.pubBytes(taxPubk)), tu.tag(resp, TAG_TAX_RECEIVER))
+
+	// the release has the payee as the receiver
+	code := tu.deliver(t, app, senderPrivk, DeliveryData{Action: ESCROW_OPEN_ACTION, To: tu.to(receiverPubk), Coins: 20, TaxHash: &taxHash, Escrow: &Escrow{ID: "order-1", Timeout: 10}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	dd := DeliveryData{Action: ESCROW_RELEASE_ACTION, Escrow: &Escrow{ID: "order-1"}}
+	dd.From = tu.pubBytes(senderPubk)
+	dr = DeliveryRequest{Data: dd}
+	tu.signDelivery(t, &dr, senderPrivk)
+	b, _ = json.Marshal(dr)
+	resp = app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	assert.Equal(t, hex.EncodeToString(tu.pubBytes(receiverPubk)), tu.tag(resp, TAG_TO))
+	assert.Equal(t, "2", tu.tag(resp, TAG_TAX))
+
+	// the seizure is from the seized account and signed by the authority
+	authorityPrivk, authorityPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	conf := confs.Conf
+	defer func() { confs.Conf = conf }()
+	tu.addAuthority(t, authorityPubk)
+	_, courtPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	resp = tu.deliver(t, app, authorityPrivk, DeliveryData{Action: SEIZE_ACTION, To: tu.to(courtPubk), Coins: 10, Compliance: &Compliance{Account: tu.pubBytes(senderPubk), Reason: "case-1"}})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	assert.Equal(t, hex.EncodeToString(tu.pubBytes(senderPubk)), tu.tag(resp, TAG_FROM))
+	assert.Equal(t, hex.EncodeToString(tu.pubBytes(authorityPubk)), tu.tag(resp, TAG_SIGNER))
+	assert.Equal(t, hex.EncodeToString(tu.pubBytes(courtPubk)), tu.tag(resp, TAG_TO))
+	assert.Equal(t, "10", tu.tag(resp, TAG_AMOUNT))
+}
+
+func TestTagsOfTheStandingOrderPayments(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	ownerPrivk, ownerPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, ownerPrivk, 100)
+
+	code := tu.deliver(t, app, ownerPrivk, DeliveryData{Action: STANDING_ORDER_ACTION, To: tu.to(receiverPubk), Coins: 10, TaxHash: &taxHash, StandingOrder: &StandingOrder{ID: "rent", Interval: 1, End: 10}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	assert.Equal(t, 0, len(app.EndBlock(types.RequestEndBlock{Height: 1}).Tags))
+
+	// the payments are in the tags of the end of the block
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 2}})
+	resp := types.ResponseDeliverTx{Tags: app.EndBlock(types.RequestEndBlock{Height: 2}).Tags}
+	assert.Equal(t, string(STANDING_ORDER_PAYMENT_ACTION), tu.tag(resp, TAG_ACTION))
+	assert.Equal(t, hex.EncodeToString(tu.pubBytes(ownerPubk)), tu.tag(resp, TAG_FROM))
+	assert.Equal(t, hex.EncodeToString(tu.pubBytes(receiverPubk)), tu.tag(resp, TAG_TO))
+	assert.Equal(t, "10", tu.tag(resp, TAG_AMOUNT))
+	assert.Equal(t, "1", tu.tag(resp, TAG_TAX))
+	tu.block(app, 3)
+
+	// the payments are read by their heights like the transactions
+	for _, v := range []struct {
+		request  StandingOrderPaymentsRequest
+		payments []int64
+	}{
+		{StandingOrderPaymentsRequest{FromHeight: 2, ToHeight: 2}, []int64{2}},
+		{StandingOrderPaymentsRequest{FromHeight: 1}, []int64{2, 3}},
+		{StandingOrderPaymentsRequest{FromHeight: 4, ToHeight: 10}, []int64{}},
+	} {
+		b, _ := json.Marshal(v.request)
+		qresp := app.Query(types.RequestQuery{Path: STANDING_ORDER_PAYMENTS_QUERY_PATH, Data: b})
+		assert.Equal(t, CodeTypeOK, qresp.Code)
+		spr := StandingOrderPaymentsResponse{}
+		json.Unmarshal(qresp.Value, &spr)
+		heights := []int64{}
+		for _, sp := range spr.Payments {
+			assert.Equal(t, "rent", sp.ID)
+			assert.Equal(t, 0, sp.Index)
+			assert.Equal(t, "10", tu.tag(types.ResponseDeliverTx{Tags: sp.Tags}, TAG_AMOUNT))
+			heights = append(heights, sp.Height)
+		}
+		assert.Equal(t, v.payments, heights)
+	}
+	b, _ := json.Marshal(StandingOrderPaymentsRequest{FromHeight: 3, ToHeight: 2})
+	qresp := app.Query(types.RequestQuery{Path: STANDING_ORDER_PAYMENTS_QUERY_PATH, Data: b})
+	assert.Equal(t, CodeTypeEncodingError, qresp.Code)
+}
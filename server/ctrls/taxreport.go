@@ -0,0 +1,143 @@
+package ctrls
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/tendermint/abci/types"
+	"go.dedis.ch/kyber/v3"
+)
+
+func (tca *TCApplication) taxReceiverCoins() float64 {
+	if confs.Conf.TaxReceiver == nil {
+		return 0
+	}
+	cj, _ := tca.state.GetCoins(confs.Conf.TaxReceiver)
+	return cj.Coins
+}
+
+// reconcileTax adds the tax of the entry to the block's tax, and the coins that the entry moved to and from
+// the tax receiver to its own transactions. The change of the balance that the node measured is kept apart,
+// so the tax report shows when the balance did not change by the tax and the own transactions.
+func (tca *TCApplication) reconcileTax(entry HistoryEntry, own func(account []byte) float64, before float64) {
+	if confs.Conf.TaxReceiver == nil {
+		return
+	}
+	receiver, _ := confs.Conf.TaxReceiver.Bytes()
+	tb := &tca.taxBlock
+	if entry.Tax > 0 {
+		rate := tca.taxRate()
+		rate.Transfers++
+		rate.Volume += entry.Coins
+		if bytes.Equal(entry.TaxReceiver, receiver) {
+			rate.Tax += entry.Tax
+		}
+	}
+	tb.Own += own(receiver)
+	tb.Measured += tca.taxReceiverCoins() - before
+}
+
+// deliveryCoins returns the public coins that the delivery moved to and from the account, without the tax that the account received.
+func (tca *TCApplication) deliveryCoins(dr DeliveryRequest, entry HistoryEntry) func(account []byte) float64 {
+	return func(account []byte) float64 {
+		from := bytes.Equal(dr.Data.From, account)
+		switch dr.Data.Action {
+		case ADD_ACTION, CONFIDENTIAL_WITHDRAW_ACTION:
+			if from {
+				return dr.Data.Coins
+			}
+		case REMOVE_ACTION, CONFIDENTIAL_DEPOSIT_ACTION, ESCROW_OPEN_ACTION, HTLC_LOCK_ACTION:
+			if from {
+				return -dr.Data.Coins
+			}
+		case SEND_ACTION, SEIZE_ACTION:
+			return paymentCoins(entry, account, false)
+		case ESCROW_RELEASE_ACTION, HTLC_CLAIM_ACTION:
+			return paymentCoins(entry, account, true)
+		case ESCROW_REFUND_ACTION:
+			ej, _ := tca.state.GetEscrow(dr.Data.Escrow.ID)
+			if bytes.Equal(ej.Payer, account) {
+				return ej.Coins
+			}
+		case HTLC_REFUND_ACTION:
+			hj, _ := tca.state.GetHtlc(dr.Data.Htlc.Hashlock)
+			if bytes.Equal(hj.Sender, account) {
+				return hj.Coins
+			}
+		}
+		return 0
+	}
+}
+
+// paymentCoins returns the coins of the payment to and from the account, the payee receives them without the tax.
+// The payer of the locked coins paid them when it locked them.
+func paymentCoins(entry HistoryEntry, account []byte, locked bool) float64 {
+	coins := float64(0)
+	if !locked && bytes.Equal(entry.Payer, account) {
+		coins -= entry.Coins
+	}
+	if bytes.Equal(entry.Payee, account) {
+		coins += entry.Coins - entry.Tax
+	}
+	return coins
+}
+
+// addConfidentialTax counts the confidential send in the block's tax, its tax is the sum of the commitments
+// that only the tax receiver can open with the notes.
+func (tca *TCApplication) addConfidentialTax(tax kyber.Point) {
+	rate := tca.taxRate()
+	rate.ConfidentialTransfers++
+	sum := suite.Point().Null()
+	if len(rate.ConfidentialTax) > 0 {
+		sum, _ = unmarshalCommitment(rate.ConfidentialTax)
+	}
+	rate.ConfidentialTax, _ = suite.Point().Add(sum, tax).MarshalBinary()
+}
+
+// taxRate returns the block's tax of the submitted tax.
+func (tca *TCApplication) taxRate() *TaxRateJson {
+	tb := &tca.taxBlock
+	for i := range tb.Rates {
+		if tb.Rates[i].TaxHash == confs.Conf.IpfsTax {
+			return &tb.Rates[i]
+		}
+	}
+	tb.Rates = append(tb.Rates, TaxRateJson{TaxHash: confs.Conf.IpfsTax, Percentage: confs.Conf.Tax.Percentage})
+	return &tb.Rates[len(tb.Rates)-1]
+}
+
+// saveTaxBlock keeps the block's tax, if the block had tax or changed the tax receiver's balance.
+func (tca *TCApplication) saveTaxBlock() {
+	tb := tca.taxBlock
+	tb.BalanceAfter = tca.taxReceiverCoins()
+	if len(tb.Rates) == 0 && tb.Own == 0 && tb.Measured == 0 && tb.BalanceAfter == tb.BalanceBefore {
+		return
+	}
+	tca.state.SetTaxBlock(tb)
+}
+
+// queryTaxReport returns the tax of the blocks in the range, only for the watchers.
+func (tca *TCApplication) queryTaxReport(qr QueryRequest) types.ResponseQuery {
+	if !confs.Conf.WatcherExists(string(qr.Data.From)) {
+		return types.ResponseQuery{Code: CodeTypeUnauthorized, Log: "You are not a watcher."}
+	}
+	if confs.Conf.TaxReceiver == nil {
+		return types.ResponseQuery{Code: CodeTypeUnauthorized, Log: "The tax is not submitted."}
+	}
+	tr := TaxReportResponse{FromHeight: qr.Data.FromHeight, ToHeight: qr.Data.ToHeight}
+	if tr.ToHeight == 0 {
+		tr.ToHeight = tca.blockHeight
+	}
+	if tr.FromHeight < 0 || tr.FromHeight > tr.ToHeight {
+		return types.ResponseQuery{Code: CodeTypeUnauthorized, Log: "The range of the blocks is not correct."}
+	}
+	tr.Receiver, _ = confs.Conf.TaxReceiver.Bytes()
+	_, err := tca.audit.Append(qr.Data.From, tr.Receiver, TAX_REPORT_QUERY_PATH, qr.Data.Nonce)
+	if err != nil {
+		return types.ResponseQuery{Code: CodeTypeUnauthorized, Log: "The query can not be audited: " + err.Error()}
+	}
+	tr.Blocks = tca.state.GetTaxBlocks(tr.FromHeight, tr.ToHeight)
+	b, _ := json.Marshal(tr)
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
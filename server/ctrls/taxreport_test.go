@@ -0,0 +1,132 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+	"time"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+)
+
+func (tu *testUtils) taxReport(t *testing.T, app *TCApplication, from crypto.PrivKey, fromHeight, toHeight int64) types.ResponseQuery {
+	qr := QueryRequest{}
+	qr.Data.Date = time.Now().UTC()
+	qr.Data.Nonce = tu.nonce()
+	qr.Data.From, _ = from.GetPublic().Bytes()
+	qr.Data.FromHeight = fromHeight
+	qr.Data.ToHeight = toHeight
+	b, _ := json.Marshal(qr.Data)
+	var err error
+	qr.Signature, err = from.Sign(b)
+	assert.Nil(t, err)
+	b, _ = json.Marshal(qr)
+	return app.Query(types.RequestQuery{Path: TAX_REPORT_QUERY_PATH, Data: b})
+}
+
+func TestTaxReportReconcilesTheReceiver(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	watcherPrivk, watcherPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	tu.addWatcher(t, watcherPubk)
+	senderPrivk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxPrivk, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+	tu.addCoins(t, app, senderPrivk, 100)
+
+	for _, coins := range []float64{20, 30} {
+		dr := tu.sendCoins(t, senderPrivk, receiverPubk, taxHash, coins)
+		b, _ := json.Marshal(dr)
+		assert.Equal(t, CodeTypeOK, app.DeliverTx(b).Code)
+	}
+	// the tax receiver's own send pays the tax to itself
+	dr := tu.sendCoins(t, taxPrivk, receiverPubk, taxHash, 2)
+	b, _ := json.Marshal(dr)
+	assert.Equal(t, CodeTypeOK, app.DeliverTx(b).Code)
+	app.EndBlock(types.RequestEndBlock{Height: 1})
+
+	// a change of the balance without a transaction
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 2}})
+	app.state.SetCoins(taxPubk, 100)
+	app.EndBlock(types.RequestEndBlock{Height: 2})
+
+	resp := tu.taxReport(t, app, senderPrivk, 1, 2)
+	assert.Equal(t, CodeTypeUnauthorized, resp.Code)
+	resp = tu.taxReport(t, app, watcherPrivk, 1, 2)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	tr := TaxReportResponse{}
+	json.Unmarshal(resp.Value, &tr)
+	assert.Equal(t, tu.pubBytes(taxPubk), tr.Receiver)
+	assert.Equal(t, 2, len(tr.Blocks))
+
+	tb := tr.Blocks[0]
+	assert.Equal(t, 1, len(tb.Rates))
+	assert.Equal(t, taxHash, tb.Rates[0].TaxHash)
+	assert.Equal(t, 3, tb.Rates[0].Transfers)
+	assert.Equal(t, float64(52), tb.Rates[0].Volume)
+	assert.InDelta(t, 5.2, tb.Rates[0].Tax, 1e-9)
+	assert.InDelta(t, -2, tb.Own, 1e-9)
+	assert.InDelta(t, tb.Measured, tb.Rates[0].Tax+tb.Own, 1e-9)
+	assert.InDelta(t, tb.BalanceAfter-tb.BalanceBefore, tb.Measured, 1e-9)
+
+	tb = tr.Blocks[1]
+	assert.Equal(t, 0, len(tb.Rates))
+	assert.Equal(t, float64(0), tb.Own)
+	assert.Equal(t, float64(0), tb.Measured)
+	assert.NotEqual(t, tb.BalanceAfter-tb.BalanceBefore, tb.Measured)
+}
+
+func TestTaxReportCountsTheLockedCoinsAndTheConfidentialSends(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 1}})
+
+	watcherPrivk, watcherPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	tu.addWatcher(t, watcherPubk)
+	senderPrivk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	_, receiverPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	arbiterPrivk, arbiterPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxPrivk, taxPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	taxHash := tu.submitTax(t, taxPubk)
+
+	// the tax receiver's own coins are added, locked and refunded
+	tu.addCoins(t, app, taxPrivk, 100)
+	arbiterB := tu.pubBytes(arbiterPubk)
+	code := tu.deliver(t, app, taxPrivk, DeliveryData{Action: ESCROW_OPEN_ACTION, To: tu.to(receiverPubk), Coins: 30, TaxHash: &taxHash, Escrow: &Escrow{ID: "order-1", Arbiter: &arbiterB, Timeout: 5}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+	code = tu.deliver(t, app, arbiterPrivk, DeliveryData{Action: ESCROW_REFUND_ACTION, Escrow: &Escrow{ID: "order-1"}}).Code
+	assert.Equal(t, CodeTypeOK, code)
+
+	tu.confidentialDeposit(t, app, senderPrivk, 100)
+	dr, _, rT := tu.confidentialSend(t, senderPrivk, receiverPubk, taxHash, 10000, suite.Scalar().Zero(), 5000, 500)
+	b, _ := json.Marshal(dr)
+	assert.Equal(t, CodeTypeOK, app.DeliverTx(b).Code)
+	app.EndBlock(types.RequestEndBlock{Height: 1})
+
+	resp := tu.taxReport(t, app, watcherPrivk, 1, 1)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	tr := TaxReportResponse{}
+	json.Unmarshal(resp.Value, &tr)
+	assert.Equal(t, 1, len(tr.Blocks))
+	tb := tr.Blocks[0]
+	assert.Equal(t, float64(100), tb.Own)
+	assert.Equal(t, float64(100), tb.Measured)
+	assert.Equal(t, 1, len(tb.Rates))
+	assert.Equal(t, 0, tb.Rates[0].Transfers)
+	assert.Equal(t, 1, tb.Rates[0].ConfidentialTransfers)
+	assert.Equal(t, tu.commitBytes(500, rT), tb.Rates[0].ConfidentialTax)
+}
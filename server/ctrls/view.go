@@ -0,0 +1,83 @@
+package ctrls
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/tendermint/abci/types"
+)
+
+func validViewKind(kind ViewKind) bool {
+	return kind == VIEW_BALANCE || kind == VIEW_HISTORY
+}
+
+// Covers returns true if the grant shows the kind of data at the time.
+func (vg *ViewGrant) Covers(kind ViewKind, now time.Time) bool {
+	if kind == VIEW_HISTORY && vg.Kind != VIEW_HISTORY {
+		return false
+	}
+	return !now.Before(vg.NotBefore) && now.Before(vg.NotAfter)
+}
+
+func (tca *TCApplication) validateViewGrant(dr DeliveryRequest) (uint32, error) {
+	vg := dr.Data.ViewGrant
+	if vg == nil {
+		return CodeTypeUnauthorized, errors.New("The view grant is missing.")
+	}
+	_, err := crypto.UnmarshalPublicKey(vg.Watcher)
+	if err != nil {
+		return CodeTypeEncodingError, errors.New("The watcher's public key is not correct.")
+	}
+	if dr.Data.Action == VIEW_REVOKE_ACTION {
+		return CodeTypeOK, nil
+	}
+	if !validViewKind(vg.Kind) {
+		return CodeTypeUnauthorized, errors.New("The kind of the view grant should be balance or history.")
+	}
+	if !vg.NotBefore.Before(vg.NotAfter) {
+		return CodeTypeUnauthorized, errors.New("The view grant should end after it starts.")
+	}
+	return CodeTypeOK, nil
+}
+
+func (tca *TCApplication) deliverViewGrant(dr DeliveryRequest) {
+	tca.state.SetViewGrant(dr.Data.From, *dr.Data.ViewGrant)
+}
+
+func (tca *TCApplication) deliverViewRevoke(dr DeliveryRequest) error {
+	_, ok := tca.state.GetViewGrant(dr.Data.From, dr.Data.ViewGrant.Watcher)
+	if !ok {
+		return errors.New("The watcher does not have a view grant.")
+	}
+	tca.state.DeleteViewGrant(dr.Data.From, dr.Data.ViewGrant.Watcher)
+	return nil
+}
+
+// validateView checks that the watcher has a grant of the user for the kind of data.
+func (tca *TCApplication) validateView(qr QueryRequest, kind ViewKind) (uint32, error) {
+	if !confs.Conf.WatcherExists(string(qr.Data.From)) {
+		return CodeTypeUnauthorized, errors.New("You are not a watcher.")
+	}
+	vg, ok := tca.state.GetViewGrant(*qr.Data.User, qr.Data.From)
+	if !ok {
+		return CodeTypeUnauthorized, errors.New("The user did not grant you a view.")
+	}
+	if !vg.Covers(kind, time.Now().UTC()) {
+		return CodeTypeUnauthorized, errors.New("The view grant does not cover the " + string(kind) + " for this time.")
+	}
+	return CodeTypeOK, nil
+}
+
+// queryViewGrants returns the grants that the public key in the data issued.
+func (tca *TCApplication) queryViewGrants(pub []byte) types.ResponseQuery {
+	_, err := crypto.UnmarshalPublicKey(pub)
+	if err != nil {
+		return types.ResponseQuery{Code: CodeTypeEncodingError, Log: "The public key is not correct."}
+	}
+	vgr := ViewGrantsResponse{Grants: tca.state.GetViewGrants(pub)}
+	b, _ := json.Marshal(vgr)
+	return types.ResponseQuery{Code: CodeTypeOK, Value: b}
+}
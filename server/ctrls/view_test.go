@@ -0,0 +1,145 @@
+package ctrls
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-ipfs-api"
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/mragiadakos/theftcoin/server/confs"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/abci/types"
+)
+
+func (tu *testUtils) grantView(t *testing.T, app *TCApplication, from crypto.PrivKey, watcher crypto.PubKey, kind ViewKind) {
+	watcherB, _ := watcher.Bytes()
+	dr := tu.inflatorCoins(t, from, VIEW_GRANT_ACTION, 0)
+	dr.Data.ViewGrant = &ViewGrant{
+		Watcher:   watcherB,
+		Kind:      kind,
+		NotBefore: time.Now().UTC().Add(-time.Minute),
+		NotAfter:  time.Now().UTC().Add(time.Hour),
+	}
+	tu.signDelivery(t, &dr, from)
+	b, _ := json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+}
+
+func (tu *testUtils) addWatcher(t *testing.T, watcher crypto.PubKey) {
+	b, _ := watcher.Bytes()
+	watchers := []confs.Watcher{{PublicKeyHex: hex.EncodeToString(b)}}
+	watchB, _ := json.Marshal(watchers)
+	sh := shell.NewShell(confs.Conf.IpfsConnection)
+	watchHash, err := sh.BlockPut(watchB)
+	assert.Nil(t, err)
+	confs.Conf.IpfsWatchers = watchHash
+	err = confs.Conf.SubmitWatchers()
+	assert.Nil(t, err)
+}
+
+func (tu *testUtils) watcherQuery(t *testing.T, app *TCApplication, path string, watcher crypto.PrivKey, user crypto.PubKey) types.ResponseQuery {
+	qr := QueryRequest{}
+	qr.Data.Date = time.Now().UTC()
+	qr.Data.Nonce = tu.nonce()
+	qr.Data.From, _ = watcher.GetPublic().Bytes()
+	userB, _ := user.Bytes()
+	qr.Data.User = &userB
+	b, _ := json.Marshal(qr.Data)
+	var err error
+	qr.Signature, err = watcher.Sign(b)
+	assert.Nil(t, err)
+	b, _ = json.Marshal(qr)
+	return app.Query(types.RequestQuery{Path: path, Data: b})
+}
+
+func TestViewGrantHistorySuccessfully(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	confs.Conf.WaitingRequestTime = 5
+
+	fromPrivk, fromPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	fromPubkB, _ := fromPubk.Bytes()
+	confs.Conf.IpfsInflators = tu.addInflator(t, fromPubkB)
+	confs.Conf.SubmitInflators()
+	watcherPrivk, watcherPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	tu.addWatcher(t, watcherPubk)
+
+	app.BeginBlock(types.RequestBeginBlock{Header: types.Header{Height: 7, Time: 1000}})
+	dr := tu.inflatorCoins(t, fromPrivk, ADD_ACTION, 20)
+	b, _ := json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	// the balance grant does not show the history
+	tu.grantView(t, app, fromPrivk, watcherPubk, VIEW_BALANCE)
+	qresp := tu.watcherQuery(t, app, HISTORY_QUERY_PATH, watcherPrivk, fromPubk)
+	assert.Equal(t, CodeTypeUnauthorized, qresp.Code)
+	qresp = tu.watcherQuery(t, app, "", watcherPrivk, fromPubk)
+	assert.Equal(t, CodeTypeOK, qresp.Code)
+
+	tu.grantView(t, app, fromPrivk, watcherPubk, VIEW_HISTORY)
+	qresp = tu.watcherQuery(t, app, HISTORY_QUERY_PATH, watcherPrivk, fromPubk)
+	assert.Equal(t, CodeTypeOK, qresp.Code)
+	hr := HistoryResponse{}
+	err = json.Unmarshal(qresp.Value, &hr)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(hr.Entries))
+	assert.Equal(t, ADD_ACTION, hr.Entries[0].Action)
+	assert.Equal(t, int64(7), hr.Entries[0].Height)
+	assert.Equal(t, time.Unix(1000, 0).UTC(), hr.Entries[0].Time)
+	assert.Equal(t, txHash(b), hr.Entries[0].Hash)
+	assert.Equal(t, float64(20), hr.Entries[0].Coins)
+
+	// the grants are public
+	qresp = app.Query(types.RequestQuery{Path: VIEW_GRANTS_QUERY_PATH, Data: fromPubkB})
+	assert.Equal(t, CodeTypeOK, qresp.Code)
+	vgr := ViewGrantsResponse{}
+	json.Unmarshal(qresp.Value, &vgr)
+	assert.Equal(t, 1, len(vgr.Grants))
+	assert.Equal(t, VIEW_HISTORY, vgr.Grants[0].Kind)
+
+	// after the revoke the watcher can not see the account
+	watcherB, _ := watcherPubk.Bytes()
+	dr = tu.inflatorCoins(t, fromPrivk, VIEW_REVOKE_ACTION, 0)
+	dr.Data.ViewGrant = &ViewGrant{Watcher: watcherB}
+	tu.signDelivery(t, &dr, fromPrivk)
+	b, _ = json.Marshal(dr)
+	resp = app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	qresp = tu.watcherQuery(t, app, "", watcherPrivk, fromPubk)
+	assert.Equal(t, CodeTypeUnauthorized, qresp.Code)
+}
+
+func TestViewGrantFailExpired(t *testing.T) {
+	tu := testUtils{}
+	app := NewTCApplication()
+	confs.Conf.WaitingRequestTime = 5
+
+	fromPrivk, fromPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	watcherPrivk, watcherPubk, err := crypto.GenerateEd25519Key(rand.Reader)
+	assert.Nil(t, err)
+	tu.addWatcher(t, watcherPubk)
+
+	watcherB, _ := watcherPubk.Bytes()
+	dr := tu.inflatorCoins(t, fromPrivk, VIEW_GRANT_ACTION, 0)
+	dr.Data.ViewGrant = &ViewGrant{
+		Watcher:   watcherB,
+		Kind:      VIEW_HISTORY,
+		NotBefore: time.Now().UTC().Add(-2 * time.Hour),
+		NotAfter:  time.Now().UTC().Add(-time.Hour),
+	}
+	tu.signDelivery(t, &dr, fromPrivk)
+	b, _ := json.Marshal(dr)
+	resp := app.DeliverTx(b)
+	assert.Equal(t, CodeTypeOK, resp.Code)
+
+	qresp := tu.watcherQuery(t, app, "", watcherPrivk, fromPubk)
+	assert.Equal(t, CodeTypeUnauthorized, qresp.Code)
+}
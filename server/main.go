@@ -21,8 +21,15 @@ func main() {
 	node := flag.String("node", "tcp://0.0.0.0:46658", "the TCP URL for the ABCI daemon")
 	ipfsInflatorsHash := flag.String("inflators", "", "the IPFS hash with the JSON list of public keys for inflators")
 	ipfsWatchersHash := flag.String("watchers", "", "the IPFS hash with the JSON list of public keys for watchers")
+	ipfsAuthoritiesHash := flag.String("authorities", "", "the IPFS hash with the JSON list of public keys for the compliance authorities, empty for none")
+	ipfsAttestorsHash := flag.String("attestors", "", "the IPFS hash with the JSON list of public keys for the KYC attestors, empty for none")
+	ipfsKycHash := flag.String("kyc", "", "the IPFS hash with the JSON list of the KYC levels that the sends above their coins need, empty for none")
 	ipfsTaxHash := flag.String("tax", "", "the IPFS hash with the JSON for the tax")
 	waitSec := flag.Int("wait", 5, "the seconds for an acceptable query")
+	skewSec := flag.Int("skew", 2, "the seconds that the clients' clocks can differ from the server's clock in both directions")
+	auditLog := flag.String("audit", "audit.log", "the file of the hash-chained log for the watchers' queries, empty to keep it in memory")
+	ipfsAuditAnchorersHash := flag.String("audit-anchorers", "", "the IPFS hash with the JSON list of public keys and log ids for the nodes' audit anchorers, empty for none")
+	auditLogId := flag.String("audit-log-id", "", "the id of the node's audit log in the list of the audit anchorers, empty to not compare the anchors")
 	createDemoKeys := flag.Bool("create-demo-keys", false, "Create the first demo keys.")
 	flag.Parse()
 
@@ -58,6 +65,42 @@ func main() {
 		return
 	}
 
+	if len(*ipfsAuthoritiesHash) > 0 {
+		confs.Conf.IpfsAuthorities = *ipfsAuthoritiesHash
+		err = confs.Conf.SubmitAuthorities()
+		if err != nil {
+			fmt.Println("Error ", err.Error())
+			return
+		}
+	}
+
+	if len(*ipfsAttestorsHash) > 0 {
+		confs.Conf.IpfsAttestors = *ipfsAttestorsHash
+		err = confs.Conf.SubmitAttestors()
+		if err != nil {
+			fmt.Println("Error ", err.Error())
+			return
+		}
+	}
+
+	if len(*ipfsKycHash) > 0 {
+		confs.Conf.IpfsKyc = *ipfsKycHash
+		err = confs.Conf.SubmitKycLevels()
+		if err != nil {
+			fmt.Println("Error ", err.Error())
+			return
+		}
+	}
+
+	if len(*ipfsAuditAnchorersHash) > 0 {
+		confs.Conf.IpfsAuditAnchorers = *ipfsAuditAnchorersHash
+		err = confs.Conf.SubmitAuditAnchorers()
+		if err != nil {
+			fmt.Println("Error ", err.Error())
+			return
+		}
+	}
+
 	if len(*ipfsTaxHash) == 0 {
 		fmt.Println("Error ", errors.New("The IPFS hash for tax is missing"))
 		return
@@ -74,6 +117,17 @@ func main() {
 	confs.Conf.AbciDaemon = *node
 	confs.Conf.IpfsConnection = *ipfsDaemon
 	confs.Conf.WaitingRequestTime = *waitSec
+	confs.Conf.QuerySkew = *skewSec
+	confs.Conf.AuditLog = *auditLog
+	confs.Conf.AuditLogId = *auditLogId
+
+	// the log is checked before the application panics for it
+	al, err := ctrls.OpenAuditLog(confs.Conf.AuditLog)
+	if err != nil {
+		fmt.Println("Error ", err)
+		return
+	}
+	al.Close()
 
 	app := ctrls.NewTCApplication()
 	srv, err := absrv.NewServer(confs.Conf.AbciDaemon, flagAbci, app)